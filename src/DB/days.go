@@ -0,0 +1,80 @@
+package DB
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// counterDay is one storagePath/YYYY/MM/DD/counter_N directory
+type counterDay struct {
+	path string
+
+	day time.Time // local midnight of the day
+
+	counterID uint16
+}
+
+// listCounterDays returns every counter day directory below storagePath
+func listCounterDays(storagePath string) ([]counterDay, error) {
+
+	dayPaths, err := filepath.Glob(filepath.Join(storagePath, "[0-9][0-9][0-9][0-9]", "[0-9][0-9]", "[0-9][0-9]"))
+
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	var counterDays []counterDay
+
+	for _, dayPath := range dayPaths {
+
+		rel, _ := filepath.Rel(storagePath, dayPath)
+
+		day, err := time.ParseInLocation("2006/01/02", filepath.ToSlash(rel), time.Local)
+
+		if err != nil {
+
+			continue
+
+		}
+
+		counterDirs, err := os.ReadDir(dayPath)
+
+		if err != nil {
+
+			return nil, err
+
+		}
+
+		for _, counterDir := range counterDirs {
+
+			if !counterDir.IsDir() || !strings.HasPrefix(counterDir.Name(), "counter_") {
+				continue
+			}
+
+			counterID, err := strconv.Atoi(strings.TrimPrefix(counterDir.Name(), "counter_"))
+
+			if err != nil {
+				continue
+			}
+
+			counterDays = append(counterDays, counterDay{
+				path:      filepath.Join(dayPath, counterDir.Name()),
+				day:       day,
+				counterID: uint16(counterID),
+			})
+		}
+	}
+
+	return counterDays, nil
+}
+
+// midnight returns the start of the local day of t
+func midnight(t time.Time) time.Time {
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
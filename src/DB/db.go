@@ -5,12 +5,15 @@ import (
 	"os"
 	"packx/models"
 	"packx/reader"
+	"packx/storageEngine"
 	"packx/utils"
 	"packx/writer"
 	"sync"
 )
 
-func InitDB(dataWriteCh <-chan []models.Metric, queryReceiveCh <-chan models.Query, queryResponseCh chan<- models.QueryResponse, globalShutDownWg *sync.WaitGroup) {
+// InitDB runs the writers and readers until dataWriteCh is closed. The
+// metrics written are passed to publish, which may be nil.
+func InitDB(dataWriteCh <-chan []models.Metric, queryReceiveCh <-chan models.Query, queryCancelCh <-chan uint64, queryResponseCh chan<- models.QueryResponse, publish func(metrics []models.Metric), globalShutDownWg *sync.WaitGroup) {
 
 	defer globalShutDownWg.Done()
 
@@ -28,13 +31,25 @@ func InitDB(dataWriteCh <-chan []models.Metric, queryReceiveCh <-chan models.Que
 
 	log.Printf("Storage directory checked/created: %s", storagePath)
 
+	// one engine is shared by the writers and readers so that a single
+	// write-ahead log fronts every mapped data file
+	storageEn, err := storageEngine.NewStorageEngine()
+
+	if err != nil {
+
+		log.Printf("CRITICAL: Error opening storage engine: %v. DB initialization failed.", err)
+
+		return
+
+	}
+
 	var dbInternalWg sync.WaitGroup
 
 	dbInternalWg.Add(2)
 
 	go func() {
 
-		err := writer.StartWriteHandler(&dbInternalWg, dataWriteCh)
+		err := writer.StartWriteHandler(&dbInternalWg, dataWriteCh, storageEn, publish)
 
 		if err != nil {
 
@@ -48,7 +63,18 @@ func InitDB(dataWriteCh <-chan []models.Metric, queryReceiveCh <-chan models.Que
 
 	}()
 
-	go reader.InitQueryEngine(queryReceiveCh, queryResponseCh, &dbInternalWg)
+	go reader.InitQueryEngine(queryReceiveCh, queryCancelCh, queryResponseCh, storageEn, &dbInternalWg)
+
+	// background jobs: retention and rollups
+	var jobsWg sync.WaitGroup
+
+	stopJobs := make(chan struct{})
+
+	jobsWg.Add(2)
+
+	go startRetention(storageEn, stopJobs, &jobsWg)
+
+	go startRollups(storageEn, stopJobs, &jobsWg)
 
 	go func() {
 
@@ -67,4 +93,14 @@ func InitDB(dataWriteCh <-chan []models.Metric, queryReceiveCh <-chan models.Que
 	dbInternalWg.Wait()
 
 	log.Println("DB components (Writer, Query) shut down.")
+
+	close(stopJobs)
+
+	jobsWg.Wait()
+
+	if err := storageEn.Close(); err != nil {
+
+		log.Printf("Error closing storage engine: %v", err)
+
+	}
 }
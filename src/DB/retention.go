@@ -0,0 +1,75 @@
+package DB
+
+import (
+	"log"
+	"packx/storageEngine"
+	"packx/utils"
+	"sync"
+	"time"
+)
+
+// retentionInterval is how often expired days are looked for
+const retentionInterval = time.Hour
+
+// startRetention drops expired counter days right away and then every
+// retentionInterval until stop is closed
+func startRetention(storageEn *storageEngine.StorageEngine, stop <-chan struct{}, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+
+	ticker := time.NewTicker(retentionInterval)
+
+	defer ticker.Stop()
+
+	for {
+
+		enforceRetention(storageEn, utils.GetStoragePath(), time.Now())
+
+		select {
+
+		case <-stop:
+
+			return
+
+		case <-ticker.C:
+
+		}
+	}
+}
+
+// enforceRetention removes every storagePath/YYYY/MM/DD/counter_N directory
+// that is older than the counter's retention. A day is expired once all of it
+// lies before the retention window, which starts at midnight RetentionDays ago.
+func enforceRetention(storageEn *storageEngine.StorageEngine, storagePath string, now time.Time) {
+
+	counterDays, err := listCounterDays(storagePath)
+
+	if err != nil {
+
+		log.Printf("Retention: failed to list day directories: %v", err)
+
+		return
+
+	}
+
+	today := midnight(now)
+
+	for _, counterDay := range counterDays {
+
+		retentionDays := utils.GetCounterRetentionDays(counterDay.counterID)
+
+		if retentionDays <= 0 || !counterDay.day.Before(today.AddDate(0, 0, -retentionDays)) {
+			continue
+		}
+
+		if err := storageEn.DropCounterDay(counterDay.path); err != nil {
+
+			log.Printf("Retention: failed to drop %s: %v", counterDay.path, err)
+
+			continue
+
+		}
+
+		log.Printf("Retention: dropped %s (counter %d keeps %d days)", counterDay.path, counterDay.counterID, retentionDays)
+	}
+}
@@ -0,0 +1,100 @@
+package DB
+
+import (
+	"log"
+	"packx/reader"
+	"packx/storageEngine"
+	"packx/utils"
+	"sync"
+	"time"
+)
+
+// rollupInterval is how often closed days are checked for missing or stale rollups
+const rollupInterval = 10 * time.Minute
+
+// startRollups keeps the rollups of closed days current until stop is closed
+func startRollups(storageEn *storageEngine.StorageEngine, stop <-chan struct{}, wg *sync.WaitGroup) {
+
+	defer wg.Done()
+
+	ticker := time.NewTicker(rollupInterval)
+
+	defer ticker.Stop()
+
+	for {
+
+		buildRollups(storageEn, utils.GetStoragePath(), time.Now(), stop)
+
+		select {
+
+		case <-stop:
+
+			return
+
+		case <-ticker.C:
+
+		}
+	}
+}
+
+// buildRollups (re)builds the rollups of every numeric counter day before
+// today whose rollups are missing or older than its raw data
+func buildRollups(storageEn *storageEngine.StorageEngine, storagePath string, now time.Time, stop <-chan struct{}) {
+
+	counterDays, err := listCounterDays(storagePath)
+
+	if err != nil {
+
+		log.Printf("Rollups: failed to list day directories: %v", err)
+
+		return
+
+	}
+
+	today := midnight(now)
+
+	for _, counterDay := range counterDays {
+
+		select {
+
+		case <-stop:
+
+			return
+
+		default:
+
+		}
+
+		if !counterDay.day.Before(today) {
+			continue
+		}
+
+		if dataType, err := utils.GetCounterType(counterDay.counterID); err != nil || (dataType != utils.TypeInt && dataType != utils.TypeFloat) {
+			continue
+		}
+
+		current, err := reader.RollupsCurrent(storageEn, counterDay.path)
+
+		if err != nil {
+
+			log.Printf("Rollups: failed to check %s: %v", counterDay.path, err)
+
+			continue
+
+		}
+
+		if current {
+			continue
+		}
+
+		if err := reader.BuildRollups(storageEn, counterDay.path, counterDay.counterID); err != nil {
+
+			log.Printf("Rollups: failed to build %s: %v", counterDay.path, err)
+
+			continue
+
+		}
+
+		log.Printf("Rollups: built %s", counterDay.path)
+	}
+}
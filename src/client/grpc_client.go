@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"packx/models"
+	"packx/rpc"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// grpcWriteBatch is the number of metrics sent per message by GRPCClient.Write
+const grpcWriteBatch = 1000
+
+// GRPCClient talks to the gRPC API, converting between the models and the
+// messages of the generated rpc.ReportDBClient. Unlike QueryClient, queries
+// are cancelled and given deadlines through their context.
+type GRPCClient struct {
+	conn *grpc.ClientConn
+
+	rpc rpc.ReportDBClient
+}
+
+// NewGRPCClient connects to the gRPC API at address, e.g. "localhost:9090"
+func NewGRPCClient(address string) (*GRPCClient, error) {
+	conn, err := grpc.NewClient(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %v", address, err)
+	}
+
+	return &GRPCClient{conn: conn, rpc: rpc.NewReportDBClient(conn)}, nil
+}
+
+// Query answers a query with a single response. A query the server rejects
+// returns a *QueryError.
+func (c *GRPCClient) Query(ctx context.Context, query models.Query) (*models.QueryResponse, error) {
+	response, err := c.rpc.Query(ctx, rpc.QueryToProto(query))
+	if err != nil {
+		return nil, grpcQueryError(query.QueryID, err)
+	}
+
+	result := rpc.ResponseFromProto(response)
+	return &result, nil
+}
+
+// QueryStream streams the result of a query, calling handle for each chunk
+// until the last one. An error from handle cancels the query and is returned.
+func (c *GRPCClient) QueryStream(ctx context.Context, query models.Query, handle func(*models.QueryResponse) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := c.rpc.QueryStream(ctx, rpc.QueryToProto(query))
+	if err != nil {
+		return grpcQueryError(query.QueryID, err)
+	}
+
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return grpcQueryError(query.QueryID, err)
+		}
+
+		result := rpc.ResponseFromProto(response)
+		if err := handle(&result); err != nil {
+			return err
+		}
+	}
+}
+
+// Write sends metrics to be written and returns how many the server queued.
+// The server refuses a batch holding a metric of an unknown counter, ending
+// the write; the batches before it are queued.
+func (c *GRPCClient) Write(ctx context.Context, metrics []models.Metric) (uint64, error) {
+	stream, err := c.rpc.Write(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to start write: %v", err)
+	}
+
+	for start := 0; start < len(metrics); start += grpcWriteBatch {
+		end := start + grpcWriteBatch
+		if end > len(metrics) {
+			end = len(metrics)
+		}
+
+		request := &rpc.WriteRequest{Metrics: make([]*rpc.Metric, 0, end-start)}
+		for _, metric := range metrics[start:end] {
+			converted, err := rpc.MetricToProto(metric)
+			if err != nil {
+				return 0, fmt.Errorf("metric of object %d: %v", metric.ObjectID, err)
+			}
+			request.Metrics = append(request.Metrics, converted)
+		}
+
+		// a refused batch ends the stream; its error comes with CloseAndRecv
+		if err := stream.Send(request); err != nil {
+			break
+		}
+	}
+
+	summary, err := stream.CloseAndRecv()
+	if err != nil {
+		return 0, fmt.Errorf("write failed: %v", err)
+	}
+	return summary.GetAccepted(), nil
+}
+
+// Close closes the connection
+func (c *GRPCClient) Close() error {
+	return c.conn.Close()
+}
+
+// grpcQueryError turns the error of a rejected query into a *QueryError
+func grpcQueryError(queryID uint64, err error) error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.GetDomain() == rpc.ErrorDomain {
+			return &QueryError{QueryID: queryID, Code: info.GetReason(), Message: st.Message()}
+		}
+	}
+
+	return fmt.Errorf("query %d failed: %v", queryID, err)
+}
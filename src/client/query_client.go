@@ -5,66 +5,128 @@ import (
 	"fmt"
 	zmq "github.com/pebbe/zmq4"
 	"log"
+	"os"
 	"packx/models"
+	"sync"
 	"time"
 )
 
-// QueryClient represents a client that can send queries and receive results
+// responseTimeout bounds the wait for each response
+const responseTimeout = 10 * time.Second
+
+// responseBacklog bounds the responses of a query received and not yet taken
+// by its caller. The receiver never waits for a caller, so that one slow
+// query does not hold up the others; a query falling further behind is ended
+// with an error.
+const responseBacklog = 64
+
+// QueryError is returned for a query the server rejected or failed to answer
+type QueryError struct {
+	QueryID uint64
+
+	Code string // one of the models.ErrorCode constants
+
+	Message string
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query %d failed (%s): %s", e.QueryID, e.Code, e.Message)
+}
+
+// responseError returns the error a response with the error status reports
+func responseError(response *models.QueryResponse) error {
+	if response.Status != models.StatusError {
+		return nil
+	}
+	return &QueryError{QueryID: response.QueryID, Code: response.ErrorCode, Message: response.Error}
+}
+
+// DefaultQueryEndpoint is the query server QueryClient connects to unless
+// QueryClientOptions or the EnvQueryEndpoint environment variable say otherwise
+const DefaultQueryEndpoint = "tcp://localhost:8008"
+
+// EnvQueryEndpoint names the environment variable overriding
+// DefaultQueryEndpoint
+const EnvQueryEndpoint = "REPORTDB_QUERY_ENDPOINT"
+
+// QueryClientOptions configures a QueryClient
+type QueryClientOptions struct {
+	// Endpoint is the query server to connect to, e.g. "tcp://db1:8008" or
+	// "ipc:///run/reportdb/query.sock"
+	Endpoint string
+}
+
+// endpoint returns the endpoint to connect to
+func (o QueryClientOptions) endpoint() string {
+	if o.Endpoint != "" {
+		return o.Endpoint
+	}
+	if endpoint := os.Getenv(EnvQueryEndpoint); endpoint != "" {
+		return endpoint
+	}
+	return DefaultQueryEndpoint
+}
+
+// QueryClient represents a client that can send queries and receive results.
+// It is safe for concurrent use: any number of queries may be in flight at
+// once, each response being matched to its query by QueryID.
 type QueryClient struct {
-	context     *zmq.Context
-	sendSocket  *zmq.Socket
-	recvSocket  *zmq.Socket
-	responses   chan models.QueryResponse
-	done        chan struct{}
+	context *zmq.Context
+
+	socket *zmq.Socket
+
+	socketLock sync.Mutex // zmq sockets must not be used concurrently
+
+	pendingLock sync.Mutex
+
+	pending map[uint64]chan models.QueryResponse // responses of in-flight queries, by QueryID; closed only with pendingLock held, once removed
+
+	done chan struct{}
+
+	closeOnce sync.Once
 }
 
-// NewQueryClient creates a new query client
-func NewQueryClient() (*QueryClient, error) {
+// NewQueryClient creates a new query client. It takes at most one
+// QueryClientOptions, so that callers without options need not change.
+func NewQueryClient(opts ...QueryClientOptions) (*QueryClient, error) {
+	var options QueryClientOptions
+	if len(opts) > 1 {
+		return nil, fmt.Errorf("expected at most one QueryClientOptions, got %d", len(opts))
+	}
+	if len(opts) == 1 {
+		options = opts[0]
+	}
+	endpoint := options.endpoint()
+
 	log.Println("Initializing query client...")
-	
+
 	context, err := zmq.NewContext()
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ZMQ context: %v", err)
 	}
 
-	// Socket for sending queries
-	sendSocket, err := context.NewSocket(zmq.PUSH)
+	// Queries and their responses share one socket; the server routes each
+	// response back to the client that sent the query
+	socket, err := context.NewSocket(zmq.DEALER)
 	if err != nil {
 		context.Term()
-		return nil, fmt.Errorf("failed to create send socket: %v", err)
+		return nil, fmt.Errorf("failed to create socket: %v", err)
 	}
 
-	log.Println("Connecting to query server on tcp://localhost:8008...")
-	if err := sendSocket.Connect("tcp://localhost:8008"); err != nil {
-		sendSocket.Close()
-		context.Term()
-		return nil, fmt.Errorf("failed to connect send socket: %v", err)
-	}
-
-	// Socket for receiving responses
-	recvSocket, err := context.NewSocket(zmq.PULL)
-	if err != nil {
-		sendSocket.Close()
+	log.Printf("Connecting to query server on %s...", endpoint)
+	if err := socket.Connect(endpoint); err != nil {
+		socket.Close()
 		context.Term()
-		return nil, fmt.Errorf("failed to create receive socket: %v", err)
-	}
-
-	log.Println("Connecting to response server on tcp://localhost:8009...")
-	if err := recvSocket.Connect("tcp://localhost:8009"); err != nil {
-		recvSocket.Close()
-		sendSocket.Close()
-		context.Term()
-		return nil, fmt.Errorf("failed to connect receive socket: %v", err)
+		return nil, fmt.Errorf("failed to connect socket to %s: %v", endpoint, err)
 	}
 
 	log.Println("Query client initialized successfully")
 
 	client := &QueryClient{
-		context:     context,
-		sendSocket:  sendSocket,
-		recvSocket:  recvSocket,
-		responses:   make(chan models.QueryResponse),
-		done:        make(chan struct{}),
+		context: context,
+		socket:  socket,
+		pending: make(map[uint64]chan models.QueryResponse),
+		done:    make(chan struct{}),
 	}
 
 	// Start response receiver
@@ -73,32 +135,244 @@ func NewQueryClient() (*QueryClient, error) {
 	return client, nil
 }
 
-// SendQuery sends a query to the server and waits for response
+// SendQuery sends a query to the server and waits for response. For paged
+// or streamed queries it returns the first response only; use Query to
+// iterate over all of them. A query the server rejects returns a
+// *QueryError; a partial response is returned without error, its warnings
+// saying what could not be read.
 func (c *QueryClient) SendQuery(query models.Query) (*models.QueryResponse, error) {
+	// the server need not keep reading after we stop waiting
+	if query.Deadline == 0 {
+		query.Deadline = time.Now().Add(responseTimeout).UnixMilli()
+	}
+
+	responses, err := c.register(query.QueryID)
+	if err != nil {
+		return nil, err
+	}
+	defer c.unregister(query.QueryID, responses)
+
+	if err := c.send(query); err != nil {
+		return nil, err
+	}
+
+	// Wait for response with timeout
+	log.Printf("Waiting for response to query ID: %d", query.QueryID)
+	response, err := awaitResponse(responses, query.QueryID)
+	if err != nil {
+		if cancelErr := c.CancelQuery(query.QueryID); cancelErr != nil {
+			log.Printf("Error cancelling query ID %d: %v", query.QueryID, cancelErr)
+		}
+		return nil, err
+	}
+
+	log.Printf("Received matching response for query ID: %d with status %s", query.QueryID, response.Status)
+	if query.Stream && !response.Done {
+		// nobody reads the chunks after the first
+		if err := c.CancelQuery(query.QueryID); err != nil {
+			log.Printf("Error cancelling query ID %d: %v", query.QueryID, err)
+		}
+	}
+	if err := responseError(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// CancelQuery asks the server to stop answering the query with queryID. The
+// query responds with what it read so far and the cancelled status.
+func (c *QueryClient) CancelQuery(queryID uint64) error {
+	return c.send(models.Query{QueryID: queryID, Cancel: true})
+}
+
+// register makes the receiver deliver the responses to queryID until it is
+// unregistered. A client can only run one query with a given ID at a time.
+func (c *QueryClient) register(queryID uint64) (<-chan models.QueryResponse, error) {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+
+	if c.pending == nil {
+		return nil, fmt.Errorf("client closed")
+	}
+	if _, exists := c.pending[queryID]; exists {
+		return nil, fmt.Errorf("query ID %d is already in flight", queryID)
+	}
+
+	// one more than the backlog, for the error ending a query that overran it
+	responses := make(chan models.QueryResponse, responseBacklog+1)
+	c.pending[queryID] = responses
+	return responses, nil
+}
+
+// unregister stops the delivery of responses to a query registered with
+// responses. A query already ended by the receiver, whose ID may be in use
+// again, is left alone.
+func (c *QueryClient) unregister(queryID uint64, responses <-chan models.QueryResponse) {
+	c.pendingLock.Lock()
+	defer c.pendingLock.Unlock()
+
+	if pending, exists := c.pending[queryID]; exists && pending == responses {
+		delete(c.pending, queryID)
+		close(pending)
+	}
+}
+
+// send marshals a query and sends it to the server
+func (c *QueryClient) send(query models.Query) error {
 	queryBytes, err := json.Marshal(query)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal query: %v", err)
+		return fmt.Errorf("failed to marshal query: %v", err)
 	}
 
 	log.Printf("Sending query to server: %+v", query)
-	_, err = c.sendSocket.SendBytes(queryBytes, 0)
+	c.socketLock.Lock()
+	_, err = c.socket.SendBytes(queryBytes, 0)
+	c.socketLock.Unlock()
 	if err != nil {
-		return nil, fmt.Errorf("failed to send query: %v", err)
+		return fmt.Errorf("failed to send query: %v", err)
 	}
 	log.Printf("Query sent successfully (ID: %d)", query.QueryID)
+	return nil
+}
 
-	// Wait for response with timeout
-	log.Printf("Waiting for response to query ID: %d", query.QueryID)
+// ResponseIterator walks the responses of a query: the pages of a paged
+// query, requesting each next page with the cursor of the one before, or
+// the chunks of a streamed query as the server sends them.
+//
+//	it := client.Query(query)
+//	defer it.Close()
+//	for it.Next() {
+//		process(it.Response())
+//	}
+//	if err := it.Err(); err != nil { ... }
+type ResponseIterator struct {
+	client *QueryClient
+
+	query models.Query
+
+	responses <-chan models.QueryResponse // nil until the query is first sent
+
+	sequence uint32 // the Sequence of the next chunk of a streamed query
+
+	sent bool // the query for the next response has been sent
+
+	done bool
+
+	response *models.QueryResponse
+
+	err error
+}
+
+// Query sends query lazily, on the first call to Next of the returned iterator
+func (c *QueryClient) Query(query models.Query) *ResponseIterator {
+	return &ResponseIterator{client: c, query: query}
+}
+
+// Next waits for the next response. It returns false once the last response
+// has been returned or an error occurred.
+func (it *ResponseIterator) Next() bool {
+	if it.done || it.err != nil {
+		return false
+	}
+
+	if it.responses == nil {
+		if it.responses, it.err = it.client.register(it.query.QueryID); it.err != nil {
+			return false
+		}
+	}
+
+	if !it.sent {
+		if it.err = it.client.send(it.query); it.err != nil {
+			it.Close()
+			return false
+		}
+		it.sent = true
+	}
+
+	response, err := awaitResponse(it.responses, it.query.QueryID)
+	if err != nil {
+		it.err = err
+		if cancelErr := it.client.CancelQuery(it.query.QueryID); cancelErr != nil {
+			log.Printf("Error cancelling query ID %d: %v", it.query.QueryID, cancelErr)
+		}
+		it.Close()
+		return false
+	}
+	if it.err = responseError(response); it.err != nil {
+		it.Close()
+		return false
+	}
+
+	// a missing chunk would silently leave a hole in the result
+	if it.query.Stream {
+		if response.Sequence != it.sequence {
+			it.err = fmt.Errorf("query %d: expected chunk %d, got chunk %d", it.query.QueryID, it.sequence, response.Sequence)
+			if cancelErr := it.client.CancelQuery(it.query.QueryID); cancelErr != nil {
+				log.Printf("Error cancelling query ID %d: %v", it.query.QueryID, cancelErr)
+			}
+			it.Close()
+			return false
+		}
+		it.sequence++
+	}
+
+	it.response = response
+
+	// a query cut short ends with what it read; Err reports why
+	if response.Status == models.StatusCancelled || response.Status == models.StatusDeadlineExceeded {
+		it.err = fmt.Errorf("query %d ended early: %s", it.query.QueryID, response.Status)
+		it.Close()
+		return true
+	}
+
+	switch {
+	case response.Done, response.Cursor == "":
+		// servers without paging send a single response without a cursor
+		it.done = true
+		it.Close()
+	case !it.query.Stream:
+		it.query.Cursor = response.Cursor
+		it.sent = false
+	}
+	return true
+}
+
+// Close stops the iteration. A streamed query still being answered is
+// cancelled, and its remaining responses are dropped.
+func (it *ResponseIterator) Close() {
+	if it.responses == nil {
+		return
+	}
+	if it.sent && !it.done && it.err == nil {
+		if err := it.client.CancelQuery(it.query.QueryID); err != nil {
+			log.Printf("Error cancelling query ID %d: %v", it.query.QueryID, err)
+		}
+	}
+	it.client.unregister(it.query.QueryID, it.responses)
+	it.responses = nil
+	it.done = true
+}
+
+// Response returns the response Next waited for
+func (it *ResponseIterator) Response() *models.QueryResponse {
+	return it.response
+}
+
+// Err returns the error that ended the iteration, if any
+func (it *ResponseIterator) Err() error {
+	return it.err
+}
+
+// awaitResponse waits for the next response delivered to a pending query
+func awaitResponse(responses <-chan models.QueryResponse, queryID uint64) (*models.QueryResponse, error) {
 	select {
-	case response := <-c.responses:
-		if response.QueryID == query.QueryID {
-			log.Printf("Received matching response for query ID: %d", query.QueryID)
-			return &response, nil
+	case response, ok := <-responses:
+		if !ok {
+			return nil, fmt.Errorf("client closed while waiting for query ID: %d", queryID)
 		}
-		return nil, fmt.Errorf("received response for different query (expected: %d, got: %d)", 
-			query.QueryID, response.QueryID)
-	case <-time.After(10 * time.Second):
-		return nil, fmt.Errorf("timeout waiting for response to query ID: %d", query.QueryID)
+		return &response, nil
+	case <-time.After(responseTimeout):
+		return nil, fmt.Errorf("timeout waiting for response to query ID: %d", queryID)
 	}
 }
 
@@ -111,8 +385,10 @@ func (c *QueryClient) receiveResponses() {
 		case <-c.done:
 			return
 		default:
-			// Try to receive with timeout
-			responseBytes, err := c.recvSocket.RecvBytes(zmq.DONTWAIT)
+			// Try to receive without holding the socket from senders
+			c.socketLock.Lock()
+			responseBytes, err := c.socket.RecvBytes(zmq.DONTWAIT)
+			c.socketLock.Unlock()
 			if err != nil {
 				if err == zmq.ErrorSocketClosed {
 					log.Println("Response socket closed")
@@ -120,7 +396,7 @@ func (c *QueryClient) receiveResponses() {
 				}
 				if zmq.AsErrno(err) == zmq.Errno(11) { // EAGAIN
 					// No message available, sleep briefly
-					time.Sleep(100 * time.Millisecond)
+					time.Sleep(10 * time.Millisecond)
 					continue
 				}
 				log.Printf("Error receiving response: %v", err)
@@ -134,48 +410,82 @@ func (c *QueryClient) receiveResponses() {
 			}
 
 			log.Printf("[Receiver] Raw response received and unmarshalled for QueryID: %d", response.QueryID)
-			log.Printf("[Receiver] Response for query ID: %d contains data for %d object(s)", 
-				response.QueryID, len(response.Data))
-			
-			// Attempt to send to the responses channel. 
-			// This might block if SendQuery isn't ready, but that's expected for this simple sync design.
-			trySendResponse(c.responses, response)
+			log.Printf("[Receiver] Response for query ID: %d contains data for %d object(s), status %s",
+				response.QueryID, len(response.Data), response.Status)
+
+			c.pendingLock.Lock()
+			dropped := c.deliver(response)
+			c.pendingLock.Unlock()
+
+			// the server need not keep answering a query that was ended
+			if dropped && !response.Done {
+				if err := c.CancelQuery(response.QueryID); err != nil {
+					log.Printf("Error cancelling query ID %d: %v", response.QueryID, err)
+				}
+			}
 		}
 	}
 }
 
-// Helper function to safely send to the responses channel
-func trySendResponse(ch chan<- models.QueryResponse, resp models.QueryResponse) {
-	defer func() {
-		if r := recover(); r != nil {
-			log.Printf("[Receiver] Failed to send response to channel (likely closed): %v", r)
-		}
-	}()
-	// Use a select with a short timeout to avoid indefinite blocking if the channel logic has issues
-	select {
-	case ch <- resp:
-		log.Printf("[Receiver] Response for QueryID %d sent to waiting SendQuery (if any)", resp.QueryID)
-	case <-time.After(1 * time.Second):
-		log.Printf("[Receiver] Timeout sending response for QueryID %d to channel. No SendQuery waiting?", resp.QueryID)
+// deliver passes a response to its query without waiting. A query whose
+// caller is responseBacklog responses behind gets an error in place of the
+// response instead, and is removed. It must be called with pendingLock held,
+// which keeps the channel from being closed under it. It returns whether the
+// response was dropped.
+func (c *QueryClient) deliver(response models.QueryResponse) bool {
+	responses, exists := c.pending[response.QueryID]
+	if !exists {
+		log.Printf("[Receiver] Dropping response for query ID %d, which is not in flight", response.QueryID)
+		return false
+	}
+
+	if len(responses) < responseBacklog {
+		responses <- response
+		log.Printf("[Receiver] Response for QueryID %d delivered to its waiting query", response.QueryID)
+		return false
 	}
-	
+
+	log.Printf("[Receiver] Query ID %d has %d responses waiting, ending it", response.QueryID, responseBacklog)
+	responses <- models.QueryResponse{
+		QueryID:   response.QueryID,
+		Done:      true,
+		Status:    models.StatusError,
+		ErrorCode: models.ErrorCodeResponsesDropped,
+		Error:     fmt.Sprintf("response %d dropped, %d responses were not taken", response.Sequence, responseBacklog),
+	}
+	delete(c.pending, response.QueryID)
+	close(responses)
+	return true
 }
 
-// Close closes the client connection
+// Close closes the client connection. Calls after the first do nothing.
 func (c *QueryClient) Close() error {
+	var err error
+	c.closeOnce.Do(func() { err = c.close() })
+	return err
+}
+
+func (c *QueryClient) close() error {
 	log.Println("Closing query client...")
 	close(c.done)
-	
-	if err := c.sendSocket.Close(); err != nil {
-		log.Printf("Error closing send socket: %v", err)
-	}
-	if err := c.recvSocket.Close(); err != nil {
-		log.Printf("Error closing receive socket: %v", err)
+
+	c.socketLock.Lock()
+	if err := c.socket.Close(); err != nil {
+		log.Printf("Error closing socket: %v", err)
 	}
+	c.socketLock.Unlock()
 	if err := c.context.Term(); err != nil {
 		return fmt.Errorf("failed to terminate context: %v", err)
 	}
-	close(c.responses)
+
+	// wake the queries still waiting
+	c.pendingLock.Lock()
+	for queryID, responses := range c.pending {
+		delete(c.pending, queryID)
+		close(responses)
+	}
+	c.pending = nil
+	c.pendingLock.Unlock()
 	log.Println("Query client closed successfully")
 	return nil
 }
@@ -105,4 +105,71 @@ func TestQueryClient(t *testing.T) {
 		// Add delay between tests to avoid overwhelming the server
 		time.Sleep(100 * time.Millisecond)
 	}
-} 
\ No newline at end of file
+}
+
+func TestDeliverEndsQueryThatFallsBehind(t *testing.T) {
+	client := &QueryClient{pending: make(map[uint64]chan models.QueryResponse)}
+
+	responses, err := client.register(7)
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	// the receiver never waits, however far behind the caller is
+	for i := 0; i <= responseBacklog; i++ {
+		client.pendingLock.Lock()
+		dropped := client.deliver(models.QueryResponse{QueryID: 7, Sequence: uint32(i)})
+		client.pendingLock.Unlock()
+		if dropped != (i == responseBacklog) {
+			t.Fatalf("response %d: dropped %v", i, dropped)
+		}
+	}
+
+	for i := 0; i < responseBacklog; i++ {
+		if response := <-responses; response.Sequence != uint32(i) {
+			t.Fatalf("expected response %d, got %d", i, response.Sequence)
+		}
+	}
+	last := <-responses
+	if err := responseError(&last); err == nil || err.(*QueryError).Code != models.ErrorCodeResponsesDropped {
+		t.Fatalf("expected the query ended with %s, got %v", models.ErrorCodeResponsesDropped, err)
+	}
+	if _, open := <-responses; open {
+		t.Fatalf("expected the responses closed")
+	}
+
+	// the ended query is gone, and its ID free again
+	client.unregister(7, responses)
+	if _, err := client.register(7); err != nil {
+		t.Fatalf("register after the query ended: %v", err)
+	}
+}
+
+func TestCloseTwice(t *testing.T) {
+	client, err := NewQueryClient()
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	// a second Close, e.g. a deferred one, must not panic
+	if err := client.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
+
+func TestSubscriberCloseTwice(t *testing.T) {
+	subscriber, err := NewSubscriber("tcp://localhost:5556")
+	if err != nil {
+		t.Fatalf("NewSubscriber: %v", err)
+	}
+
+	if err := subscriber.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := subscriber.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	zmq "github.com/pebbe/zmq4"
+	"log"
+	"packx/models"
+	"sync"
+	"time"
+)
+
+// Subscriber receives the metrics of chosen objects and counters from the
+// live feed as they are written, instead of polling for the latest data.
+// Metrics written while the feed is behind or before a subscription reaches
+// the server are not received; query for those.
+type Subscriber struct {
+	context *zmq.Context
+
+	socket *zmq.Socket
+
+	socketLock sync.Mutex // zmq sockets must not be used concurrently
+
+	metrics chan models.Metric
+
+	done chan struct{}
+
+	closeOnce sync.Once
+}
+
+// NewSubscriber connects to the live feed at endpoint, e.g.
+// "tcp://localhost:5556". Nothing is received until Subscribe is called.
+func NewSubscriber(endpoint string) (*Subscriber, error) {
+	context, err := zmq.NewContext()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ZMQ context: %v", err)
+	}
+
+	socket, err := context.NewSocket(zmq.SUB)
+	if err != nil {
+		context.Term()
+		return nil, fmt.Errorf("failed to create socket: %v", err)
+	}
+
+	if err := socket.Connect(endpoint); err != nil {
+		socket.Close()
+		context.Term()
+		return nil, fmt.Errorf("failed to connect to %s: %v", endpoint, err)
+	}
+
+	s := &Subscriber{
+		context: context,
+		socket:  socket,
+		metrics: make(chan models.Metric, 1000),
+		done:    make(chan struct{}),
+	}
+
+	go s.receiveMetrics()
+
+	return s, nil
+}
+
+// Subscribe receives the metrics of a counter of an object
+func (s *Subscriber) Subscribe(objectID uint32, counterID uint16) error {
+	return s.subscribe(models.LiveTopic(objectID, counterID))
+}
+
+// SubscribeObject receives the metrics of every counter of an object
+func (s *Subscriber) SubscribeObject(objectID uint32) error {
+	return s.subscribe(models.LiveObjectTopic(objectID))
+}
+
+// SubscribeAll receives every metric written
+func (s *Subscriber) SubscribeAll() error {
+	return s.subscribe("")
+}
+
+// Unsubscribe undoes a Subscribe with the same arguments
+func (s *Subscriber) Unsubscribe(objectID uint32, counterID uint16) error {
+	s.socketLock.Lock()
+	defer s.socketLock.Unlock()
+
+	if err := s.socket.SetUnsubscribe(models.LiveTopic(objectID, counterID)); err != nil {
+		return fmt.Errorf("failed to unsubscribe: %v", err)
+	}
+	return nil
+}
+
+func (s *Subscriber) subscribe(topic string) error {
+	s.socketLock.Lock()
+	defer s.socketLock.Unlock()
+
+	if err := s.socket.SetSubscribe(topic); err != nil {
+		return fmt.Errorf("failed to subscribe to %q: %v", topic, err)
+	}
+	return nil
+}
+
+// Metrics returns the channel the subscribed metrics arrive on. It is closed
+// by Close.
+func (s *Subscriber) Metrics() <-chan models.Metric {
+	return s.metrics
+}
+
+func (s *Subscriber) receiveMetrics() {
+	defer close(s.metrics)
+
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		// Try to receive without holding the socket from Subscribe
+		s.socketLock.Lock()
+		frames, err := s.socket.RecvMessageBytes(zmq.DONTWAIT)
+		s.socketLock.Unlock()
+		if err != nil {
+			if err == zmq.ErrorSocketClosed {
+				return
+			}
+			if zmq.AsErrno(err) == zmq.Errno(11) { // EAGAIN
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			log.Printf("Error receiving metric: %v", err)
+			continue
+		}
+		if len(frames) != 2 {
+			log.Printf("Dropping live feed message with %d frames", len(frames))
+			continue
+		}
+
+		var metric models.Metric
+		if err := json.Unmarshal(frames[1], &metric); err != nil {
+			log.Printf("Error unmarshalling metric: %v", err)
+			continue
+		}
+
+		select {
+		case s.metrics <- metric:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// Close stops receiving and closes the connection. Calls after the first do
+// nothing.
+func (s *Subscriber) Close() error {
+	var err error
+	s.closeOnce.Do(func() { err = s.close() })
+	return err
+}
+
+func (s *Subscriber) close() error {
+	close(s.done)
+
+	s.socketLock.Lock()
+	if err := s.socket.Close(); err != nil {
+		log.Printf("Error closing socket: %v", err)
+	}
+	s.socketLock.Unlock()
+	if err := s.context.Term(); err != nil {
+		return fmt.Errorf("failed to terminate context: %v", err)
+	}
+	return nil
+}
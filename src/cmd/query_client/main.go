@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	"packx/client"
@@ -9,9 +10,12 @@ import (
 )
 
 func main() {
+	endpoint := flag.String("endpoint", "", "query server to connect to (default $"+client.EnvQueryEndpoint+", else "+client.DefaultQueryEndpoint+")")
+	flag.Parse()
+
 	log.Println("Starting query client CLI with DEBUG mode...")
 
-	cli, err := client.NewQueryClient()
+	cli, err := client.NewQueryClient(client.QueryClientOptions{Endpoint: *endpoint})
 	if err != nil {
 		log.Fatalf("Failed to create query client: %v", err)
 	}
@@ -50,6 +54,19 @@ func main() {
 	log.Printf("Successfully received response:")
 	log.Printf("  Query ID: %d", response.QueryID)
 	log.Printf("  Total objects in response: %d", len(response.Data))
+	log.Printf("  Status: %s", response.Status)
+	if response.Stats != nil {
+		log.Printf("  Stats: %d blocks scanned, %d points read in %dms",
+			response.Stats.BlocksScanned, response.Stats.PointsRead, response.Stats.DurationMs)
+	}
+	for _, warning := range response.Warnings {
+		log.Printf("  Warning: %s", warning)
+	}
+	for objID, warnings := range response.ObjectWarnings {
+		for _, warning := range warnings {
+			log.Printf("  Warning for object %d: %s", objID, warning)
+		}
+	}
 
 	fmt.Println("\nDETAILED RESPONSE DATA:")
 	fmt.Println("=======================")
@@ -108,6 +125,7 @@ func main() {
 		ObjectIDs:   []uint32{1, 2},
 		CounterId:   1,
 		Aggregation: "avg", // Try average aggregation
+		Interval:    60,    // one point per minute
 	}
 
 	log.Printf("\nSending AGGREGATION query: %+v", aggregationQuery)
@@ -127,7 +145,7 @@ func main() {
 
 				switch v := dp.Value.(type) {
 				case float64:
-					fmt.Printf("  Avg value: %.6f (Time: %s)\n", v, timeStr)
+					fmt.Printf("  Avg value: %.6f (Minute: %s)\n", v, timeStr)
 				default:
 					fmt.Printf("  Value: %v (Type: %T, Time: %s)\n", v, v, timeStr)
 				}
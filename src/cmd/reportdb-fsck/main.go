@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"packx/storageEngine"
+	"packx/utils"
+)
+
+// reportdb-fsck checks a storage directory while the server is stopped. It
+// lists the problems of every partition and, with -repair, rebuilds the block
+// index of partitions whose index no longer matches their data file.
+func main() {
+
+	storagePath := flag.String("storage", "", "storage directory to check (default: storage_path from config.json)")
+
+	repair := flag.Bool("repair", false, "rebuild damaged block indexes from the data files")
+
+	verbose := flag.Bool("v", false, "also list partitions without issues")
+
+	configDir := flag.String("config", "", "directory holding config.json, read without -storage (default $"+utils.EnvConfigDir+", else ./"+utils.DefaultConfigDir+" or "+utils.DefaultConfigDir+" beside the executable)")
+
+	flag.Parse()
+
+	utils.SetConfigDir(*configDir)
+
+	if *storagePath == "" {
+
+		if err := utils.LoadConfig(); err != nil {
+
+			log.Fatalf("No -storage given and config could not be loaded: %v", err)
+
+		}
+
+		*storagePath = utils.GetStoragePath()
+	}
+
+	reports, err := storageEngine.CheckStorage(*storagePath, *repair)
+
+	if err != nil {
+
+		log.Fatalf("Check of %s failed: %v", *storagePath, err)
+
+	}
+
+	blocks, issues, rebuilt := 0, 0, 0
+
+	for _, report := range reports {
+
+		blocks += report.Blocks
+
+		issues += len(report.Issues)
+
+		if report.Rebuilt {
+
+			rebuilt++
+
+			fmt.Printf("%s: index rebuilt\n", report.Path)
+
+		}
+
+		if len(report.Issues) == 0 {
+
+			if *verbose {
+				fmt.Printf("%s: ok (%d blocks)\n", report.Path, report.Blocks)
+			}
+
+			continue
+		}
+
+		fmt.Printf("%s: %d issues (%d blocks)\n", report.Path, len(report.Issues), report.Blocks)
+
+		for _, issue := range report.Issues {
+			fmt.Printf("  %s\n", issue)
+		}
+	}
+
+	fmt.Printf("checked %d partitions, %d blocks: %d issues, %d indexes rebuilt\n", len(reports), blocks, issues, rebuilt)
+
+	if issues > 0 {
+		os.Exit(1)
+	}
+}
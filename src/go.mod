@@ -3,9 +3,15 @@ module packx
 go 1.24.0
 
 require (
-	github.com/pebbe/zmq4 v1.3.0 // indirect
-	go.uber.org/multierr v1.10.0 // indirect
-	go.uber.org/zap v1.27.0 // indirect
-	golang.org/x/crypto v0.37.0 // indirect
-	golang.org/x/sys v0.32.0 // indirect
+	github.com/pebbe/zmq4 v1.3.0
+	golang.org/x/crypto v0.37.0
+	golang.org/x/sys v0.32.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240528184218-531527333157
+	google.golang.org/grpc v1.65.0
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	golang.org/x/net v0.25.0 // indirect
+	golang.org/x/text v0.24.0 // indirect
 )
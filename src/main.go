@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
 	. "packx/DB"
@@ -22,6 +23,25 @@ func main() {
 
 	fmt.Println("Hello world ")
 
+	// flags take precedence over the environment, which takes precedence over config.json
+	configDir := flag.String("config", "", "directory holding config.json and counters.json (default $"+EnvConfigDir+", else ./"+DefaultConfigDir+" or "+DefaultConfigDir+" beside the executable)")
+
+	var addresses Config
+
+	flag.StringVar(&addresses.PullAddress, "pull-address", "", "ZMQ endpoint the PULL server binds (default "+DefaultPullAddress+")")
+
+	flag.StringVar(&addresses.QueryAddress, "query-address", "", "ZMQ endpoint the query server binds (default "+DefaultQueryAddress+")")
+
+	flag.StringVar(&addresses.LiveFeedAddress, "live-feed-address", "", "ZMQ endpoint the live feed publishes on (default "+DefaultLiveFeedAddress+")")
+
+	flag.StringVar(&addresses.HTTPAddress, "http-address", "", "listen address of the HTTP API (default "+DefaultHTTPAddress+")")
+
+	flag.StringVar(&addresses.GRPCAddress, "grpc-address", "", "listen address of the gRPC API (default "+DefaultGRPCAddress+")")
+
+	flag.Parse()
+
+	SetConfigDir(*configDir)
+
 	err := LoadConfig() // loading all the configurations
 
 	if err != nil {
@@ -32,6 +52,14 @@ func main() {
 
 	}
 
+	if err := OverrideAddresses(addresses); err != nil {
+
+		log.Println("Error in address flags:", err)
+
+		return
+
+	}
+
 	//query := Query{
 	//	QueryID: 1,
 	//
@@ -58,9 +86,11 @@ func main() {
 
 	queryResponseCh := make(chan QueryResponse, GetBufferredChanSize())
 
+	queryCancelCh := make(chan uint64, GetBufferredChanSize())
+
 	var globalShutDownWg sync.WaitGroup
 
-	globalShutDownWg.Add(4)
+	globalShutDownWg.Add(6)
 
 	// Start the pull server
 	go server.PullServer(pollData)
@@ -121,13 +151,23 @@ func main() {
 		}
 	}()
 
-	go InitDB(dataWriteCh, queryReceiveCh, queryResponseCh, &globalShutDownWg)
+	// Publish stored metrics to live subscribers
+	liveFeed := server.NewLiveFeed()
+
+	go server.InitLiveFeed(liveFeed, &globalShutDownWg)
+
+	go InitDB(dataWriteCh, queryReceiveCh, queryCancelCh, queryResponseCh, liveFeed.Publish, &globalShutDownWg)
 
 	//go InitPollListener(dataWriteCh, &globalShutDownWg)
 
-	go server.InitQueryListener(queryReceiveCh, &globalShutDownWg)
+	// queries of the ZMQ and HTTP front ends share the reader
+	dispatcher := server.NewQueryDispatcher(queryReceiveCh, queryCancelCh, queryResponseCh)
+
+	go server.InitQueryServer(dispatcher, &globalShutDownWg)
+
+	go server.InitHTTPServer(dataWriteCh, dispatcher, &globalShutDownWg)
 
-	go server.InitQueryResponser(queryResponseCh, &globalShutDownWg)
+	go server.InitGRPCServer(dataWriteCh, dispatcher, &globalShutDownWg)
 
 	//queryReceiveCh <- query
 
@@ -1,5 +1,17 @@
 package models
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Query types
+const (
+	QueryTypeData = "" // data points of the selected objects
+
+	QueryTypeObjects = "objects" // the objects that have data, see QueryResponse.Objects
+)
+
 type DataPoint struct {
 	Timestamp uint32      `json:"timestamp"`
 	Value     interface{} `json:"value"`
@@ -21,15 +33,225 @@ type Query struct {
 
 	To uint32 `json:"to"`
 
-	ObjectIDs []uint32 `json:"Object_id"`
+	ObjectIDs ObjectSelector `json:"Object_id"` // empty or "*" selects every object with data in the range
 
 	CounterId uint16 `json:"counter_id"`
 
+	CounterIDs []uint16 `json:"counter_ids,omitempty"` // queries several counters at once; takes precedence over CounterId
+
 	Aggregation string `json:"aggregation"`
+
+	Interval uint32 `json:"interval,omitempty"` // bucket width in seconds; 0 aggregates the whole range into one point
+
+	Type string `json:"type,omitempty"` // QueryTypeData or QueryTypeObjects
+
+	Rank *Ranking `json:"rank,omitempty"` // returns the highest or lowest objects by Aggregation instead of their data
+
+	Fill string `json:"fill,omitempty"` // how empty interval buckets are filled, one of the Fill constants
+
+	FillValue float64 `json:"fill_value,omitempty"` // the value of FillConstant
+
+	FillLimit uint32 `json:"fill_limit,omitempty"` // FillPrevious and FillLinear leave buckets more than this many seconds after the last value empty; 0 is no limit
+
+	Limit int `json:"limit,omitempty"` // points per response; a larger result is paged, or chunked when streamed
+
+	Cursor string `json:"cursor,omitempty"` // resumes a paged result where the previous response ended
+
+	Stream bool `json:"stream,omitempty"` // sends the whole result as a sequence of chunks instead of pages
+
+	Deadline int64 `json:"deadline,omitempty"` // Unix time in milliseconds after which the server stops reading; 0 is none
+
+	Cancel bool `json:"cancel,omitempty"` // aborts the running query with QueryID instead of starting one
+}
+
+// Statuses of query responses
+const (
+	StatusOK = "ok"
+
+	StatusPartial = "partial" // some data could not be read, see ObjectWarnings and Warnings
+
+	StatusError = "error" // the query was rejected, see ErrorCode and Error
+
+	StatusCancelled = "cancelled" // the response holds what was read until the cancel
+
+	StatusDeadlineExceeded = "deadline_exceeded" // the response holds what was read until the deadline
+)
+
+// Error codes of rejected queries
+const (
+	ErrorCodeInvalidQuery = "invalid_query"
+
+	ErrorCodeUnknownAggregation = "unknown_aggregation"
+
+	ErrorCodeUnsupportedAggregation = "unsupported_aggregation" // e.g. avg of a string counter
+
+	ErrorCodeResponsesDropped = "responses_dropped" // set by a client that fell too far behind the responses of a query
+)
+
+// QueryStats describes the work done to answer a query
+type QueryStats struct {
+	BlocksScanned uint64 `json:"blocks_scanned"`
+
+	PointsRead uint64 `json:"points_read"`
+
+	RollupPointsRead uint64 `json:"rollup_points_read"`
+
+	DurationMs int64 `json:"duration_ms"`
+}
+
+// Fill options for empty buckets of interval queries
+const (
+	FillNone = "none" // leave them out, the same as no fill
+
+	FillNull = "null" // a null value
+
+	FillPrevious = "previous" // the value of the bucket before
+
+	FillLinear = "linear" // interpolated between the buckets around them
+
+	FillConstant = "constant" // FillValue
+)
+
+// Ranking orders
+const (
+	RankTop = "top"
+
+	RankBottom = "bottom"
+)
+
+// Ranking asks for the Limit objects with the highest (RankTop) or lowest
+// (RankBottom) aggregated value
+type Ranking struct {
+	Order string `json:"order"`
+
+	Limit int `json:"limit"`
+}
+
+// RankedObject is one entry of a ranking
+type RankedObject struct {
+	ObjectID uint32 `json:"object_id"`
+
+	Timestamp uint32 `json:"timestamp"`
+
+	Value float64 `json:"value"`
+}
+
+// ObjectSelector lists the objects a query reads. In JSON it is an array of
+// object IDs or the wildcard "*"; both the wildcard and an empty list select
+// every object, as does null.
+type ObjectSelector []uint32
+
+// MarshalJSON writes a selector of every object as the wildcard
+func (s ObjectSelector) MarshalJSON() ([]byte, error) {
+	if s.All() {
+		return []byte(`"*"`), nil
+	}
+	return json.Marshal([]uint32(s))
+}
+
+func (s *ObjectSelector) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*s = nil
+		return nil
+	}
+
+	var wildcard string
+	if err := json.Unmarshal(data, &wildcard); err == nil {
+		if wildcard != "*" {
+			return fmt.Errorf("invalid object selector %q", wildcard)
+		}
+		*s = nil
+		return nil
+	}
+
+	var ids []uint32
+	if err := json.Unmarshal(data, &ids); err != nil {
+		return err
+	}
+	*s = ids
+	return nil
+}
+
+// All reports whether the selector selects every object
+func (s ObjectSelector) All() bool {
+	return len(s) == 0
+}
+
+// ObjectInfo describes an object with data for a counter within a query range
+type ObjectInfo struct {
+	ObjectID uint32 `json:"object_id"`
+
+	FirstTimestamp uint32 `json:"first_timestamp"`
+
+	LastTimestamp uint32 `json:"last_timestamp"`
+}
+
+// CounterInfo describes a configured counter
+type CounterInfo struct {
+	CounterID uint16 `json:"counter_id"`
+
+	Name string `json:"name"`
+
+	Type string `json:"type"`
+
+	RetentionDays int `json:"retention_days"` // 0 keeps data forever
 }
 
 type QueryResponse struct {
 	QueryID uint64 `json:"query_id"`
 
-	Data map[uint32][]DataPoint `json:"data"`
+	Data map[uint32][]DataPoint `json:"data"` // for queries by CounterId
+
+	CounterData map[uint32]map[uint16][]DataPoint `json:"counter_data,omitempty"` // object -> counter -> points, for queries by CounterIDs
+
+	Rankings map[uint16][]RankedObject `json:"rankings,omitempty"` // counter -> ranked objects, for queries with Rank
+
+	Objects map[uint16][]ObjectInfo `json:"objects,omitempty"` // counter -> objects, for QueryTypeObjects queries
+
+	Status string `json:"status,omitempty"` // one of the Status constants
+
+	ErrorCode string `json:"error_code,omitempty"` // one of the ErrorCode constants, set with StatusError
+
+	Error string `json:"error,omitempty"` // set when the query was rejected, e.g. for an unknown aggregation
+
+	Warnings []string `json:"warnings,omitempty"` // about the query as a whole, e.g. a day without a data directory
+
+	ObjectWarnings map[uint32][]string `json:"object_warnings,omitempty"` // data of an object that could not be read, e.g. blocks skipped because they failed their checksum
+
+	Stats *QueryStats `json:"stats,omitempty"` // the work done for the query up to this response
+
+	Sequence uint32 `json:"sequence,omitempty"` // position of the chunk in a streamed result, from 0
+
+	Cursor string `json:"cursor,omitempty"` // set when more of the result follows; send it back to get the next page
+
+	Done bool `json:"done"` // set on the last response of a query
+}
+
+// Counters returns the distinct counters a query reads, in the order requested
+func (q Query) Counters() []uint16 {
+	if len(q.CounterIDs) == 0 {
+		return []uint16{q.CounterId}
+	}
+
+	seen := make(map[uint16]bool, len(q.CounterIDs))
+	var counters []uint16
+	for _, counterID := range q.CounterIDs {
+		if !seen[counterID] {
+			seen[counterID] = true
+			counters = append(counters, counterID)
+		}
+	}
+	return counters
+}
+
+// LiveTopic is the topic metrics of an object and counter are published
+// under on the live feed. Subscriptions match topics by prefix, so the
+// topic ends with a separator: "7/2/" does not match counter 21.
+func LiveTopic(objectID uint32, counterID uint16) string {
+	return fmt.Sprintf("%d/%d/", objectID, counterID)
+}
+
+// LiveObjectTopic is the prefix of the topics of every counter of an object
+func LiveObjectTopic(objectID uint32) string {
+	return fmt.Sprintf("%d/", objectID)
 }
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestObjectSelectorRoundTrip(t *testing.T) {
+	testCases := []struct {
+		name     string
+		selector ObjectSelector
+		json     string
+	}{
+		{
+			name:     "Wildcard",
+			selector: nil,
+			json:     `"*"`,
+		},
+		{
+			name:     "Empty List",
+			selector: ObjectSelector{},
+			json:     `"*"`,
+		},
+		{
+			name:     "Object IDs",
+			selector: ObjectSelector{3, 1, 2},
+			json:     `[3,1,2]`,
+		},
+	}
+
+	for _, tc := range testCases {
+		data, err := json.Marshal(tc.selector)
+		if err != nil {
+			t.Fatalf("%s: Marshal: %v", tc.name, err)
+		}
+		if string(data) != tc.json {
+			t.Fatalf("%s: marshalled to %s, want %s", tc.name, data, tc.json)
+		}
+
+		var selector ObjectSelector
+		if err := json.Unmarshal(data, &selector); err != nil {
+			t.Fatalf("%s: Unmarshal: %v", tc.name, err)
+		}
+		if selector.All() != tc.selector.All() || (!selector.All() && !reflect.DeepEqual(selector, tc.selector)) {
+			t.Fatalf("%s: round trip gave %v, want %v", tc.name, selector, tc.selector)
+		}
+	}
+}
+
+func TestObjectSelectorNull(t *testing.T) {
+	var query Query
+	if err := json.Unmarshal([]byte(`{"query_id": 7, "Object_id": null}`), &query); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if !query.ObjectIDs.All() || query.QueryID != 7 {
+		t.Fatalf("unexpected query %+v", query)
+	}
+
+	// every query a Go client builds parses back
+	for _, sent := range []Query{{QueryID: 1}, {QueryID: 2, ObjectIDs: ObjectSelector{}}, {QueryID: 3, ObjectIDs: ObjectSelector{5}}} {
+		data, err := json.Marshal(sent)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		var received Query
+		if err := json.Unmarshal(data, &received); err != nil {
+			t.Fatalf("query %d did not parse: %v", sent.QueryID, err)
+		}
+		if received.QueryID != sent.QueryID || received.ObjectIDs.All() != sent.ObjectIDs.All() {
+			t.Fatalf("query %d parsed as %+v", sent.QueryID, received)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`"all"`), new(ObjectSelector)); err == nil {
+		t.Fatalf("expected a string other than the wildcard to be refused")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	zmq "github.com/pebbe/zmq4"
 	"log"
 	"packx/models"
+	"packx/utils"
 
 	//. "packx/storageEngine"
 	"sync"
@@ -22,14 +23,14 @@ type data struct {
 
 var (
 	pushSocket *zmq.Socket
-	zmqContext *zmq.Context
 )
 
 func initZmq() error {
 
 	var err error
 
-	zmqContext, err = zmq.NewContext()
+	// the default context, shared with the PULL server of the same process
+	pushSocket, err = zmq.NewSocket(zmq.PUSH)
 
 	if err != nil {
 
@@ -37,22 +38,16 @@ func initZmq() error {
 
 	}
 
-	pushSocket, err = zmqContext.NewSocket(zmq.PUSH)
-
-	if err != nil {
-
-		return err
-
-	}
+	endpoint := utils.ConnectEndpoint(utils.GetPullAddress())
 
 	// Connect to the PULL server
-	if err := pushSocket.Connect("tcp://localhost:5555"); err != nil {
+	if err := pushSocket.Connect(endpoint); err != nil {
 
 		return err
 
 	}
 
-	log.Println("PUSH Client connected to tcp://localhost:5555")
+	log.Println("PUSH Client connected to", endpoint)
 
 	return nil
 
@@ -73,8 +68,6 @@ func PollData(wg *sync.WaitGroup) {
 
 	defer pushSocket.Close()
 
-	defer zmqContext.Term()
-
 	// Create channel for metrics
 	pollData := make(chan models.Metric, 100)
 
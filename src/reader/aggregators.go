@@ -0,0 +1,337 @@
+package reader
+
+import (
+	"fmt"
+	"math"
+	"packx/models"
+	"packx/utils"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Aggregator reduces the points of a range or interval bucket to one value.
+// It reports false when the points hold nothing it can aggregate, in which
+// case no point is returned for them.
+type Aggregator func(points []models.DataPoint, window Window) (interface{}, bool)
+
+// Window is the inclusive time range an aggregation covers: the query range,
+// or the part of it inside an interval bucket
+type Window struct {
+	From uint32
+
+	To uint32
+}
+
+type aggregatorEntry struct {
+	aggregate Aggregator
+
+	numeric bool // only applies to int and float counters
+}
+
+var (
+	aggregatorsLock sync.RWMutex
+
+	aggregators = map[string]aggregatorEntry{
+		"avg":            {numericAggregator(mean), true},
+		"sum":            {numericAggregator(sum), true},
+		"min":            {numericAggregator(minimum), true},
+		"max":            {numericAggregator(maximum), true},
+		"median":         {numericAggregator(percentile(50)), true},
+		"p90":            {numericAggregator(percentile(90)), true},
+		"p95":            {numericAggregator(percentile(95)), true},
+		"p99":            {numericAggregator(percentile(99)), true},
+		"stddev":         {numericAggregator(func(values []float64) float64 { return math.Sqrt(variance(values)) }), true},
+		"variance":       {numericAggregator(variance), true},
+		"rate":           {counterRate, true},
+		"derivative":     {derivative, true},
+		"count":          {countPoints, false},
+		"first":          {firstPoint, false},
+		"last":           {lastPoint, false},
+		"distinct":       {distinctValues, false},
+		"mode":           {modeValue, false},
+		"histogram":      {valueHistogram, false},
+		"state_duration": {stateDurations, false},
+	}
+)
+
+// RegisterAggregator makes an aggregation over values of any type available
+// to queries under name. Registered names cannot be replaced; avg, sum, min,
+// max and count may be answered from rollups without calling their aggregator.
+func RegisterAggregator(name string, aggregator Aggregator) error {
+	return registerAggregator(name, aggregatorEntry{aggregate: aggregator})
+}
+
+// RegisterNumericAggregator is RegisterAggregator for aggregations that only
+// apply to int and float counters. Queries using them on string counters are
+// rejected.
+func RegisterNumericAggregator(name string, aggregator Aggregator) error {
+	return registerAggregator(name, aggregatorEntry{aggregate: aggregator, numeric: true})
+}
+
+func registerAggregator(name string, entry aggregatorEntry) error {
+	aggregatorsLock.Lock()
+	defer aggregatorsLock.Unlock()
+
+	if _, exists := aggregators[name]; exists {
+		return fmt.Errorf("aggregation %q is already registered", name)
+	}
+	aggregators[name] = entry
+	return nil
+}
+
+func lookupAggregator(name string) (aggregatorEntry, bool) {
+	aggregatorsLock.RLock()
+	defer aggregatorsLock.RUnlock()
+
+	entry, exists := aggregators[name]
+	return entry, exists
+}
+
+// checkAggregation returns why a query cannot use aggregation on a counter
+// of dataType, or nil when it can
+func checkAggregation(aggregation string, dataType byte) error {
+	entry, exists := lookupAggregator(aggregation)
+	if !exists {
+		return rejectQuery(models.ErrorCodeUnknownAggregation, "unknown aggregation %q", aggregation)
+	}
+	if entry.numeric && dataType == utils.TypeString {
+		return rejectQuery(models.ErrorCodeUnsupportedAggregation, "aggregation %q does not apply to string counters", aggregation)
+	}
+	return nil
+}
+
+// numericAggregator adapts a function over the numeric values of the points
+func numericAggregator(fn func(values []float64) float64) Aggregator {
+	return func(points []models.DataPoint, window Window) (interface{}, bool) {
+		values := make([]float64, 0, len(points))
+		for _, p := range points {
+			if value, ok := numericValue(p.Value); ok {
+				values = append(values, value)
+			}
+		}
+		if len(values) == 0 {
+			return nil, false
+		}
+		return fn(values), true
+	}
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+func mean(values []float64) float64 {
+	return sum(values) / float64(len(values))
+}
+
+func minimum(values []float64) float64 {
+	min := math.Inf(1)
+	for _, v := range values {
+		min = math.Min(min, v)
+	}
+	return min
+}
+
+func maximum(values []float64) float64 {
+	max := math.Inf(-1)
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	return max
+}
+
+// variance is the population variance of values
+func variance(values []float64) float64 {
+	m := mean(values)
+	total := 0.0
+	for _, v := range values {
+		total += (v - m) * (v - m)
+	}
+	return total / float64(len(values))
+}
+
+// percentile interpolates linearly between the closest ranks
+func percentile(p float64) func(values []float64) float64 {
+	return func(values []float64) float64 {
+		sorted := append([]float64(nil), values...)
+		sort.Float64s(sorted)
+
+		rank := p / 100 * float64(len(sorted)-1)
+		lower := int(math.Floor(rank))
+		upper := int(math.Ceil(rank))
+		return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+	}
+}
+
+func countPoints(points []models.DataPoint, window Window) (interface{}, bool) {
+	return float64(len(points)), len(points) > 0
+}
+
+func firstPoint(points []models.DataPoint, window Window) (interface{}, bool) {
+	if len(points) == 0 {
+		return nil, false
+	}
+	first := points[0]
+	for _, p := range points[1:] {
+		if p.Timestamp < first.Timestamp {
+			first = p
+		}
+	}
+	return first.Value, true
+}
+
+func lastPoint(points []models.DataPoint, window Window) (interface{}, bool) {
+	if len(points) == 0 {
+		return nil, false
+	}
+	last := points[0]
+	for _, p := range points[1:] {
+		if p.Timestamp >= last.Timestamp {
+			last = p
+		}
+	}
+	return last.Value, true
+}
+
+// timedValue is a numeric point, for the aggregations that depend on order
+type timedValue struct {
+	timestamp uint32
+
+	value float64
+}
+
+// timedValues returns the numeric points in time order
+func timedValues(points []models.DataPoint) []timedValue {
+	values := make([]timedValue, 0, len(points))
+	for _, p := range points {
+		if value, ok := numericValue(p.Value); ok {
+			values = append(values, timedValue{timestamp: p.Timestamp, value: value})
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool { return values[i].timestamp < values[j].timestamp })
+	return values
+}
+
+// counterRate is the per second increase of a monotonic counter. A value lower
+// than the one before it is taken as a counter reset, counting from zero.
+func counterRate(points []models.DataPoint, window Window) (interface{}, bool) {
+	values := timedValues(points)
+	if len(values) < 2 {
+		return nil, false
+	}
+
+	elapsed := values[len(values)-1].timestamp - values[0].timestamp
+	if elapsed == 0 {
+		return nil, false
+	}
+
+	increase := 0.0
+	for i := 1; i < len(values); i++ {
+		if values[i].value >= values[i-1].value {
+			increase += values[i].value - values[i-1].value
+		} else {
+			increase += values[i].value
+		}
+	}
+
+	return increase / float64(elapsed), true
+}
+
+// derivative is the per second change between the first and last value
+func derivative(points []models.DataPoint, window Window) (interface{}, bool) {
+	values := timedValues(points)
+	if len(values) < 2 {
+		return nil, false
+	}
+
+	first, last := values[0], values[len(values)-1]
+	if last.timestamp == first.timestamp {
+		return nil, false
+	}
+
+	return (last.value - first.value) / float64(last.timestamp-first.timestamp), true
+}
+
+// valueKey is the form a value takes as a histogram or state duration key
+func valueKey(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+func distinctValues(points []models.DataPoint, window Window) (interface{}, bool) {
+	seen := make(map[string]struct{})
+	for _, p := range points {
+		seen[valueKey(p.Value)] = struct{}{}
+	}
+	return float64(len(seen)), len(points) > 0
+}
+
+// modeValue is the most frequent value; ties go to the lowest key
+func modeValue(points []models.DataPoint, window Window) (interface{}, bool) {
+	if len(points) == 0 {
+		return nil, false
+	}
+
+	counts := make(map[string]int)
+	values := make(map[string]interface{})
+	for _, p := range points {
+		key := valueKey(p.Value)
+		counts[key]++
+		values[key] = p.Value
+	}
+
+	mode, modeCount := "", 0
+	for key, count := range counts {
+		if count > modeCount || (count == modeCount && key < mode) {
+			mode, modeCount = key, count
+		}
+	}
+	return values[mode], true
+}
+
+// valueHistogram counts the points holding each value
+func valueHistogram(points []models.DataPoint, window Window) (interface{}, bool) {
+	histogram := make(map[string]uint64)
+	for _, p := range points {
+		histogram[valueKey(p.Value)]++
+	}
+	return histogram, len(points) > 0
+}
+
+// stateDurations is the number of seconds each value was held within the
+// window. A value is held from its point until the next one; the latest is
+// held until the end of the window, or until now when the window is still open.
+func stateDurations(points []models.DataPoint, window Window) (interface{}, bool) {
+	if len(points) == 0 {
+		return nil, false
+	}
+
+	sorted := append([]models.DataPoint(nil), points...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Timestamp < sorted[j].Timestamp })
+
+	end := uint64(window.To) + 1
+	if now := uint64(time.Now().Unix()); now < end {
+		end = now
+	}
+
+	durations := make(map[string]uint64)
+	for i, p := range sorted {
+		until := end
+		if i+1 < len(sorted) {
+			until = uint64(sorted[i+1].Timestamp)
+		}
+		held := uint64(0)
+		if until > uint64(p.Timestamp) {
+			held = until - uint64(p.Timestamp)
+		}
+		durations[valueKey(p.Value)] += held
+	}
+	return durations, true
+}
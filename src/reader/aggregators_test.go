@@ -0,0 +1,203 @@
+package reader
+
+import (
+	"math"
+	"packx/models"
+	"packx/utils"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// series returns points one second apart holding values
+func series(values ...float64) []models.DataPoint {
+	points := make([]models.DataPoint, len(values))
+	for i, value := range values {
+		points[i] = models.DataPoint{Timestamp: uint32(i), Value: value}
+	}
+	return points
+}
+
+func TestCounterRate(t *testing.T) {
+	// 0 -> 40 -> 100 over 20 seconds
+	rate, ok := counterRate([]models.DataPoint{{Timestamp: 0, Value: 0.0}, {Timestamp: 10, Value: 40.0}, {Timestamp: 20, Value: int64(100)}}, Window{})
+	if !ok || rate != 5.0 {
+		t.Fatalf("expected a rate of 5, got %v (%v)", rate, ok)
+	}
+
+	// a drop is a reset, after which the counter counts from zero:
+	// 50 before the reset and 20 + 30 after it
+	reset := []models.DataPoint{{Timestamp: 0, Value: 100.0}, {Timestamp: 10, Value: 150.0}, {Timestamp: 20, Value: 20.0}, {Timestamp: 30, Value: 50.0}}
+	rate, ok = counterRate(reset, Window{})
+	if !ok || math.Abs(rate.(float64)-100.0/30) > 1e-9 {
+		t.Fatalf("expected a rate of 100/30 across the reset, got %v (%v)", rate, ok)
+	}
+
+	// points are taken in time order whatever order they arrive in
+	reset[0], reset[3] = reset[3], reset[0]
+	if shuffled, _ := counterRate(reset, Window{}); shuffled != rate {
+		t.Fatalf("expected the same rate for unordered points, got %v", shuffled)
+	}
+
+	// a reset to zero adds nothing
+	rate, ok = counterRate([]models.DataPoint{{Timestamp: 0, Value: int64(100)}, {Timestamp: 10, Value: int64(0)}, {Timestamp: 20, Value: int64(10)}}, Window{})
+	if !ok || rate != 0.5 {
+		t.Fatalf("expected a rate of 0.5 after a reset to zero, got %v (%v)", rate, ok)
+	}
+
+	// a rate needs two points apart in time
+	if _, ok := counterRate(nil, Window{}); ok {
+		t.Fatalf("expected no rate without points")
+	}
+	if _, ok := counterRate(series(5), Window{}); ok {
+		t.Fatalf("expected no rate from a single point")
+	}
+	if _, ok := counterRate([]models.DataPoint{{Timestamp: 10, Value: 5.0}, {Timestamp: 10, Value: 8.0}}, Window{}); ok {
+		t.Fatalf("expected no rate when no time elapsed")
+	}
+}
+
+func TestPercentilesOfSmallSamples(t *testing.T) {
+	testCases := []struct {
+		aggregation string
+		points      []models.DataPoint
+		want        float64
+	}{
+		{"median", series(5), 5},
+		{"p99", series(5), 5},
+		{"median", series(20, 10), 15},
+		{"p90", series(20, 10), 19},
+		{"p99", series(10, 20), 19.9},
+		{"p95", series(4, 1, 3, 2, 5), 4.8},
+	}
+
+	for _, tc := range testCases {
+		entry, _ := lookupAggregator(tc.aggregation)
+		value, ok := entry.aggregate(tc.points, Window{})
+		if !ok || math.Abs(value.(float64)-tc.want) > 1e-9 {
+			t.Fatalf("%s of %v: got %v (%v), want %v", tc.aggregation, tc.points, value, ok, tc.want)
+		}
+	}
+
+	// strings are not ranked, so a percentile of them has no value
+	median, _ := lookupAggregator("median")
+	if _, ok := median.aggregate([]models.DataPoint{{Timestamp: 1, Value: "up"}}, Window{}); ok {
+		t.Fatalf("expected no median of strings")
+	}
+}
+
+func TestRegisterAggregator(t *testing.T) {
+	if err := RegisterAggregator("avg", countPoints); err == nil {
+		t.Fatalf("expected avg not to be replaceable")
+	}
+
+	if err := RegisterNumericAggregator("test_spread", numericAggregator(func(values []float64) float64 {
+		return maximum(values) - minimum(values)
+	})); err != nil {
+		t.Fatalf("RegisterAggregator: %v", err)
+	}
+
+	spread, exists := lookupAggregator("test_spread")
+	if !exists {
+		t.Fatalf("registered aggregation not found")
+	}
+	if value, ok := spread.aggregate(series(3, 9, 4), Window{}); !ok || value != 6.0 {
+		t.Fatalf("expected a spread of 6, got %v (%v)", value, ok)
+	}
+
+	// numeric aggregations are refused for string counters
+	if err := checkAggregation("test_spread", utils.TypeString); err == nil {
+		t.Fatalf("expected test_spread to be refused for a string counter")
+	}
+	if err := checkAggregation("test_spread", utils.TypeInt); err != nil {
+		t.Fatalf("checkAggregation: %v", err)
+	}
+	if err := checkAggregation("no_such_aggregation", utils.TypeInt); err == nil {
+		t.Fatalf("expected an unknown aggregation to be refused")
+	}
+}
+
+// states returns points holding values at the given timestamps
+func states(timestamps []uint32, values ...interface{}) []models.DataPoint {
+	points := make([]models.DataPoint, len(values))
+	for i, value := range values {
+		points[i] = models.DataPoint{Timestamp: timestamps[i], Value: value}
+	}
+	return points
+}
+
+func TestDistinctValues(t *testing.T) {
+	points := states([]uint32{1, 2, 3, 4}, "up", "down", "up", "degraded")
+	if distinct, ok := distinctValues(points, Window{}); !ok || distinct != 3.0 {
+		t.Fatalf("expected 3 distinct strings, got %v (%v)", distinct, ok)
+	}
+
+	// an int and a float of the same value are the same value
+	points = states([]uint32{1, 2, 3}, int64(1), 1.0, 2.5)
+	if distinct, ok := distinctValues(points, Window{}); !ok || distinct != 2.0 {
+		t.Fatalf("expected 2 distinct numbers, got %v (%v)", distinct, ok)
+	}
+
+	if _, ok := distinctValues(nil, Window{}); ok {
+		t.Fatalf("expected no distinct count without points")
+	}
+}
+
+func TestModeValue(t *testing.T) {
+	points := states([]uint32{1, 2, 3, 4, 5}, "down", "up", "up", "degraded", "down")
+
+	// down and up are tied, so the lower of the two wins
+	if mode, ok := modeValue(points, Window{}); !ok || mode != "down" {
+		t.Fatalf("expected down to win the tie, got %v (%v)", mode, ok)
+	}
+
+	points = append(points, models.DataPoint{Timestamp: 6, Value: "up"})
+	if mode, _ := modeValue(points, Window{}); mode != "up" {
+		t.Fatalf("expected up as the most frequent value, got %v", mode)
+	}
+
+	// the mode keeps the type of its value
+	if mode, _ := modeValue(states([]uint32{1, 2, 3}, int64(7), int64(3), int64(7)), Window{}); mode != int64(7) {
+		t.Fatalf("expected int64 7, got %#v", mode)
+	}
+
+	if _, ok := modeValue(nil, Window{}); ok {
+		t.Fatalf("expected no mode without points")
+	}
+}
+
+func TestValueHistogram(t *testing.T) {
+	points := states([]uint32{1, 2, 3, 4}, "up", "down", "up", int64(3))
+
+	histogram, ok := valueHistogram(points, Window{})
+	want := map[string]uint64{"up": 2, "down": 1, "3": 1}
+	if !ok || !reflect.DeepEqual(histogram, want) {
+		t.Fatalf("unexpected histogram %v (%v)", histogram, ok)
+	}
+
+	if _, ok := valueHistogram(nil, Window{}); ok {
+		t.Fatalf("expected no histogram without points")
+	}
+}
+
+func TestStateDurations(t *testing.T) {
+	// unordered; the last state is held until the end of the window
+	points := states([]uint32{130, 100, 160}, "down", "up", "up")
+
+	durations, ok := stateDurations(points, Window{From: 100, To: 199})
+	want := map[string]uint64{"up": 30 + 40, "down": 30}
+	if !ok || !reflect.DeepEqual(durations, want) {
+		t.Fatalf("unexpected durations %v (%v)", durations, ok)
+	}
+
+	// a window still open ends now rather than at its end
+	now := uint32(time.Now().Unix())
+	durations, _ = stateDurations(states([]uint32{now - 10}, "up"), Window{From: now - 60, To: now + 3600})
+	if held := durations.(map[string]uint64)["up"]; held < 10 || held > 12 {
+		t.Fatalf("expected up held for about 10 seconds, got %d", held)
+	}
+
+	if _, ok := stateDurations(nil, Window{From: 100, To: 199}); ok {
+		t.Fatalf("expected no durations without points")
+	}
+}
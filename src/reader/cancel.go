@@ -0,0 +1,126 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"log"
+	"packx/models"
+	"sync"
+	"time"
+)
+
+// errQueryCancelled is the cause of the context of a query cancelled by a client
+var errQueryCancelled = errors.New("query cancelled")
+
+// pendingCancelTTL is how long a cancel for a query that has not started is
+// kept, for queries still waiting in the query channel
+const pendingCancelTTL = time.Minute
+
+// runningQuery is a query a reader is answering
+type runningQuery struct {
+	cancel context.CancelCauseFunc
+}
+
+// queryRegistry tracks the running queries by ID so that they can be cancelled
+type queryRegistry struct {
+	mu sync.Mutex
+
+	running map[uint64][]*runningQuery
+
+	pending map[uint64]time.Time // cancels that arrived before their query started
+}
+
+var runningQueries = &queryRegistry{
+	running: make(map[uint64][]*runningQuery),
+	pending: make(map[uint64]time.Time),
+}
+
+// start registers a query and returns its context, which is done once the
+// query is cancelled or its deadline passes, and the function to call when
+// the query has been answered
+func (r *queryRegistry) start(query models.Query) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	queryCtx, stopDeadline := ctx, context.CancelFunc(func() {})
+	if query.Deadline > 0 {
+		queryCtx, stopDeadline = context.WithDeadline(ctx, time.UnixMilli(query.Deadline))
+	}
+
+	entry := &runningQuery{cancel: cancel}
+
+	r.mu.Lock()
+	if cancelledAt, exists := r.pending[query.QueryID]; exists {
+		delete(r.pending, query.QueryID)
+		if time.Since(cancelledAt) < pendingCancelTTL {
+			cancel(errQueryCancelled)
+		}
+	}
+	r.running[query.QueryID] = append(r.running[query.QueryID], entry)
+	r.mu.Unlock()
+
+	finish := func() {
+		stopDeadline()
+		cancel(nil)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		entries := r.running[query.QueryID]
+		for i, e := range entries {
+			if e == entry {
+				entries = append(entries[:i], entries[i+1:]...)
+				break
+			}
+		}
+		if len(entries) == 0 {
+			delete(r.running, query.QueryID)
+		} else {
+			r.running[query.QueryID] = entries
+		}
+	}
+
+	return queryCtx, finish
+}
+
+// cancel aborts the running queries with queryID. A query that has not
+// started yet is cancelled as soon as it starts.
+func (r *queryRegistry) cancel(queryID uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.running[queryID]
+	if len(entries) == 0 {
+		for id, cancelledAt := range r.pending {
+			if time.Since(cancelledAt) >= pendingCancelTTL {
+				delete(r.pending, id)
+			}
+		}
+		r.pending[queryID] = time.Now()
+		log.Printf("Cancel for query %d received before it started", queryID)
+		return
+	}
+
+	for _, entry := range entries {
+		entry.cancel(errQueryCancelled)
+	}
+	log.Printf("Cancelled query %d", queryID)
+}
+
+// interruptedStatus returns the status of a query whose context is done, or
+// "" while it is not
+func interruptedStatus(ctx context.Context) string {
+	if ctx.Err() == nil {
+		return ""
+	}
+	if errors.Is(context.Cause(ctx), errQueryCancelled) {
+		return models.StatusCancelled
+	}
+	return models.StatusDeadlineExceeded
+}
+
+// CancelQueries cancels the queries whose IDs arrive on cancelCh until it is closed
+func CancelQueries(cancelCh <-chan uint64) {
+	for queryID := range cancelCh {
+		runningQueries.cancel(queryID)
+	}
+}
@@ -0,0 +1,86 @@
+package reader
+
+import (
+	"packx/models"
+	"testing"
+	"time"
+)
+
+func newTestRegistry() *queryRegistry {
+	return &queryRegistry{running: make(map[uint64][]*runningQuery), pending: make(map[uint64]time.Time)}
+}
+
+func TestCancelRunningQuery(t *testing.T) {
+	registry := newTestRegistry()
+
+	ctx, finish := registry.start(models.Query{QueryID: 1})
+	other, finishOther := registry.start(models.Query{QueryID: 2})
+	defer finishOther()
+
+	if interruptedStatus(ctx) != "" {
+		t.Fatalf("expected a running query, got status %q", interruptedStatus(ctx))
+	}
+
+	registry.cancel(1)
+	if status := interruptedStatus(ctx); status != models.StatusCancelled {
+		t.Fatalf("expected status cancelled, got %q", status)
+	}
+	if other.Err() != nil {
+		t.Fatalf("cancelling query 1 cancelled query 2")
+	}
+
+	finish()
+	if _, running := registry.running[1]; running {
+		t.Fatalf("expected query 1 forgotten once answered")
+	}
+}
+
+func TestCancelPendingQuery(t *testing.T) {
+	registry := newTestRegistry()
+
+	// the cancel arrives while the query waits for a reader
+	registry.cancel(1)
+
+	ctx, finish := registry.start(models.Query{QueryID: 1})
+	defer finish()
+	if status := interruptedStatus(ctx); status != models.StatusCancelled {
+		t.Fatalf("expected the query cancelled as it starts, got status %q", status)
+	}
+
+	// the cancel is used up by the query it was for
+	again, finishAgain := registry.start(models.Query{QueryID: 1})
+	defer finishAgain()
+	if again.Err() != nil {
+		t.Fatalf("expected a later query with the same ID to run")
+	}
+
+	// a cancel older than pendingCancelTTL is for a query that never came
+	registry.pending[2] = time.Now().Add(-2 * pendingCancelTTL)
+	late, finishLate := registry.start(models.Query{QueryID: 2})
+	defer finishLate()
+	if late.Err() != nil {
+		t.Fatalf("expected an expired cancel to be ignored")
+	}
+}
+
+func TestQueryDeadline(t *testing.T) {
+	registry := newTestRegistry()
+
+	ctx, finish := registry.start(models.Query{QueryID: 1, Deadline: time.Now().Add(-time.Second).UnixMilli()})
+	defer finish()
+	if status := interruptedStatus(ctx); status != models.StatusDeadlineExceeded {
+		t.Fatalf("expected status deadline_exceeded, got %q", status)
+	}
+
+	ctx, finish = registry.start(models.Query{QueryID: 2, Deadline: time.Now().Add(time.Hour).UnixMilli()})
+	defer finish()
+	if ctx.Err() != nil {
+		t.Fatalf("expected a query with a future deadline to run")
+	}
+
+	// a cancel before the deadline is reported as a cancel
+	registry.cancel(2)
+	if status := interruptedStatus(ctx); status != models.StatusCancelled {
+		t.Fatalf("expected status cancelled, got %q", status)
+	}
+}
@@ -0,0 +1,107 @@
+package reader
+
+import (
+	"fmt"
+	"packx/models"
+)
+
+// maxFilledBuckets bounds the series a filled query returns per object
+const maxFilledBuckets = 100000
+
+// checkFill returns why the fill option of a query cannot be applied, or nil
+func checkFill(query models.Query) error {
+	switch query.Fill {
+	case "", models.FillNone:
+		return nil
+	case models.FillNull, models.FillPrevious, models.FillLinear, models.FillConstant:
+	default:
+		return fmt.Errorf("unknown fill %q", query.Fill)
+	}
+
+	if query.Aggregation == "" || query.Interval == 0 {
+		return fmt.Errorf("fill %q needs an aggregation and an interval", query.Fill)
+	}
+	if query.To < query.From {
+		return nil
+	}
+	if buckets := (query.To-query.From)/query.Interval + 1; buckets > maxFilledBuckets {
+		return fmt.Errorf("fill %q would return %d buckets per object, more than %d", query.Fill, buckets, maxFilledBuckets)
+	}
+	return nil
+}
+
+// fillGaps adds a point for every empty bucket of the query range that the
+// fill option of the query can fill. points are the aggregated buckets in
+// time order, stamped with their bucket start.
+func fillGaps(points []models.DataPoint, query models.Query) []models.DataPoint {
+	if query.Fill == "" || query.Fill == models.FillNone || query.Interval == 0 || query.To < query.From {
+		return points
+	}
+
+	first := bucketStart(query.From, query.Interval)
+	last := bucketStart(query.To, query.Interval)
+
+	filled := make([]models.DataPoint, 0, (last-first)/query.Interval+1)
+	var previous *models.DataPoint
+	next := 0
+
+	for start := first; ; start += query.Interval {
+		for next < len(points) && points[next].Timestamp < start {
+			next++
+		}
+
+		if next < len(points) && points[next].Timestamp == start {
+			filled = append(filled, points[next])
+			previous = &points[next]
+			next++
+		} else {
+			var following *models.DataPoint
+			if next < len(points) {
+				following = &points[next]
+			}
+			if value, ok := fillValue(query, start, previous, following); ok {
+				filled = append(filled, models.DataPoint{Timestamp: start, Value: value})
+			}
+		}
+
+		if start >= last || start+query.Interval < start {
+			break
+		}
+	}
+
+	return filled
+}
+
+// fillValue returns the value of the empty bucket at start, between the
+// buckets previous and following, either of which may be missing
+func fillValue(query models.Query, start uint32, previous *models.DataPoint, following *models.DataPoint) (interface{}, bool) {
+	switch query.Fill {
+	case models.FillNull:
+		return nil, true
+	case models.FillConstant:
+		return query.FillValue, true
+	}
+
+	if previous == nil || (query.FillLimit > 0 && start-previous.Timestamp > query.FillLimit) {
+		return nil, false // nothing recent enough to fill from
+	}
+
+	if query.Fill == models.FillPrevious {
+		return previous.Value, true
+	}
+
+	// FillLinear
+	if following == nil {
+		return nil, false
+	}
+	from, ok := numericValue(previous.Value)
+	if !ok {
+		return nil, false
+	}
+	to, ok := numericValue(following.Value)
+	if !ok {
+		return nil, false
+	}
+	fraction := float64(start-previous.Timestamp) / float64(following.Timestamp-previous.Timestamp)
+	return from + (to-from)*fraction, true
+}
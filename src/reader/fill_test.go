@@ -0,0 +1,143 @@
+package reader
+
+import (
+	"packx/models"
+	"reflect"
+	"testing"
+)
+
+// gappyQuery covers the buckets 0, 60, ..., 300, of which gappyPoints fills
+// 60 and 240
+var gappyQuery = models.Query{From: 0, To: 300, Aggregation: "avg", Interval: 60}
+
+var gappyPoints = []models.DataPoint{{Timestamp: 60, Value: 10.0}, {Timestamp: 240, Value: 40.0}}
+
+func TestFillNullAndConstant(t *testing.T) {
+	query := gappyQuery
+
+	// no fill, or fill none, leaves the gaps
+	for _, fill := range []string{"", models.FillNone} {
+		query.Fill = fill
+		if got := fillGaps(gappyPoints, query); !reflect.DeepEqual(got, gappyPoints) {
+			t.Fatalf("fill %q changed the points: %+v", fill, got)
+		}
+	}
+
+	query.Fill = models.FillNull
+	want := []models.DataPoint{{Timestamp: 0}, {Timestamp: 60, Value: 10.0}, {Timestamp: 120}, {Timestamp: 180}, {Timestamp: 240, Value: 40.0}, {Timestamp: 300}}
+	if got := fillGaps(gappyPoints, query); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected null fill %+v", got)
+	}
+
+	query.Fill, query.FillValue = models.FillConstant, -1
+	want = []models.DataPoint{{Timestamp: 0, Value: -1.0}, {Timestamp: 60, Value: 10.0}, {Timestamp: 120, Value: -1.0}, {Timestamp: 180, Value: -1.0}, {Timestamp: 240, Value: 40.0}, {Timestamp: 300, Value: -1.0}}
+	if got := fillGaps(gappyPoints, query); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected constant fill %+v", got)
+	}
+}
+
+func TestFillPrevious(t *testing.T) {
+	query := gappyQuery
+	query.Fill = models.FillPrevious
+
+	// nothing comes before the first value, so bucket 0 stays empty
+	want := []models.DataPoint{{Timestamp: 60, Value: 10.0}, {Timestamp: 120, Value: 10.0}, {Timestamp: 180, Value: 10.0}, {Timestamp: 240, Value: 40.0}, {Timestamp: 300, Value: 40.0}}
+	if got := fillGaps(gappyPoints, query); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected previous fill %+v", got)
+	}
+
+	// a value is not carried further than the fill limit
+	query.FillLimit = 60
+	want = []models.DataPoint{{Timestamp: 60, Value: 10.0}, {Timestamp: 120, Value: 10.0}, {Timestamp: 240, Value: 40.0}, {Timestamp: 300, Value: 40.0}}
+	if got := fillGaps(gappyPoints, query); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected previous fill within 60s %+v", got)
+	}
+
+	// strings are carried forward as they are
+	query.FillLimit = 0
+	states := []models.DataPoint{{Timestamp: 0, Value: "up"}, {Timestamp: 180, Value: "down"}}
+	want = []models.DataPoint{{Timestamp: 0, Value: "up"}, {Timestamp: 60, Value: "up"}, {Timestamp: 120, Value: "up"}, {Timestamp: 180, Value: "down"}, {Timestamp: 240, Value: "down"}, {Timestamp: 300, Value: "down"}}
+	if got := fillGaps(states, query); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected previous fill of strings %+v", got)
+	}
+}
+
+func TestFillLinear(t *testing.T) {
+	query := gappyQuery
+	query.Fill = models.FillLinear
+
+	// only buckets between two values are interpolated
+	want := []models.DataPoint{{Timestamp: 60, Value: 10.0}, {Timestamp: 120, Value: 20.0}, {Timestamp: 180, Value: 30.0}, {Timestamp: 240, Value: 40.0}}
+	if got := fillGaps(gappyPoints, query); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected linear fill %+v", got)
+	}
+
+	query.FillLimit = 60
+	want = []models.DataPoint{{Timestamp: 60, Value: 10.0}, {Timestamp: 120, Value: 20.0}, {Timestamp: 240, Value: 40.0}}
+	if got := fillGaps(gappyPoints, query); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected linear fill within 60s %+v", got)
+	}
+
+	// strings cannot be interpolated
+	query.FillLimit = 0
+	states := []models.DataPoint{{Timestamp: 0, Value: "up"}, {Timestamp: 180, Value: "down"}}
+	if got := fillGaps(states, query); !reflect.DeepEqual(got, states) {
+		t.Fatalf("expected no interpolation between strings, got %+v", got)
+	}
+}
+
+func TestFillWithoutPoints(t *testing.T) {
+	query := models.Query{From: 30, To: 150, Aggregation: "avg", Interval: 60}
+
+	for _, fill := range []string{models.FillPrevious, models.FillLinear} {
+		query.Fill = fill
+		if got := fillGaps(nil, query); len(got) != 0 {
+			t.Fatalf("fill %s: expected nothing to fill from, got %+v", fill, got)
+		}
+	}
+
+	// the buckets run from the one holding From to the one holding To
+	query.Fill = models.FillNull
+	want := []models.DataPoint{{Timestamp: 0}, {Timestamp: 60}, {Timestamp: 120}}
+	if got := fillGaps(nil, query); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected null fill %+v", got)
+	}
+}
+
+func TestCheckFill(t *testing.T) {
+	testCases := []struct {
+		name        string
+		query       models.Query
+		shouldError bool
+	}{
+		{
+			name:  "No Fill",
+			query: models.Query{From: 0, To: 300},
+		},
+		{
+			name:  "Linear",
+			query: models.Query{From: 0, To: 300, Aggregation: "avg", Interval: 60, Fill: models.FillLinear},
+		},
+		{
+			name:        "Unknown Fill",
+			query:       models.Query{From: 0, To: 300, Aggregation: "avg", Interval: 60, Fill: "zero"},
+			shouldError: true,
+		},
+		{
+			name:        "No Interval",
+			query:       models.Query{From: 0, To: 300, Aggregation: "avg", Fill: models.FillNull},
+			shouldError: true,
+		},
+		{
+			name:        "Too Many Buckets",
+			query:       models.Query{From: 0, To: 1 << 30, Aggregation: "avg", Interval: 1, Fill: models.FillNull},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		if err := checkFill(tc.query); (err != nil) != tc.shouldError {
+			t.Fatalf("%s: checkFill returned %v", tc.name, err)
+		}
+	}
+}
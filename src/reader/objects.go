@@ -0,0 +1,77 @@
+package reader
+
+import (
+	"fmt"
+	"packx/models"
+	"packx/storageEngine"
+	"sort"
+)
+
+// counterObjects returns every object with data for a counter in [from, to],
+// merging what the partition indexes of each day directory hold
+func counterObjects(storage *storageEngine.StorageEngine, counterID uint16, from uint32, to uint32) ([]models.ObjectInfo, error) {
+	objects := make(map[uint32]*models.ObjectInfo)
+
+	for _, day := range queryDays(from, to) {
+		counterPath := counterDayPath(day, counterID)
+
+		ranges, err := storage.DeviceRanges(counterPath, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects of %s: %v", counterPath, err)
+		}
+
+		for _, r := range ranges {
+			object, exists := objects[r.DeviceID]
+			if !exists {
+				objects[r.DeviceID] = &models.ObjectInfo{ObjectID: r.DeviceID, FirstTimestamp: r.FirstTimestamp, LastTimestamp: r.LastTimestamp}
+				continue
+			}
+			if r.FirstTimestamp < object.FirstTimestamp {
+				object.FirstTimestamp = r.FirstTimestamp
+			}
+			if r.LastTimestamp > object.LastTimestamp {
+				object.LastTimestamp = r.LastTimestamp
+			}
+		}
+	}
+
+	result := make([]models.ObjectInfo, 0, len(objects))
+	for _, object := range objects {
+		result = append(result, *object)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ObjectID < result[j].ObjectID })
+	return result, nil
+}
+
+// selectObjects keeps the objects the selector names; the wildcard keeps all
+func selectObjects(objects []models.ObjectInfo, selector models.ObjectSelector) []models.ObjectInfo {
+	if selector.All() {
+		return objects
+	}
+
+	selected := make(map[uint32]bool, len(selector))
+	for _, objectID := range selector {
+		selected[objectID] = true
+	}
+
+	var result []models.ObjectInfo
+	for _, object := range objects {
+		if selected[object.ObjectID] {
+			result = append(result, object)
+		}
+	}
+	return result
+}
+
+// answerObjectsQuery fills in the objects with data for every counter of the query
+func answerObjectsQuery(storage *storageEngine.StorageEngine, query models.Query, response *models.QueryResponse) {
+	response.Objects = make(map[uint16][]models.ObjectInfo)
+
+	for _, counterID := range query.Counters() {
+		objects, err := counterObjects(storage, counterID, query.From, query.To)
+		if err != nil {
+			response.Warnings = append(response.Warnings, fmt.Sprintf("counter %d: %v", counterID, err))
+		}
+		response.Objects[counterID] = selectObjects(objects, query.ObjectIDs)
+	}
+}
@@ -0,0 +1,281 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"packx/models"
+	"packx/storageEngine"
+	"sort"
+)
+
+// defaultChunkPoints is the chunk size of streamed queries without a limit
+const defaultChunkPoints = 10000
+
+// pageBatchBlocks is how many blocks a page views at a time, so that it
+// stops viewing blocks soon after its last point
+const pageBatchBlocks = 16
+
+// resultPosition is where a paged result resumes: the counter at index
+// counter of the query, and its first object from objectID on. Within that
+// object's series it resumes at the point skip: of a raw series, the point
+// skip of the block at offset block, in the day of the point's timestamp; of
+// a series computed whole, e.g. aggregated, the point skip of the series.
+type resultPosition struct {
+	counter int
+
+	objectID uint32
+
+	timestamp uint32
+
+	block int64
+
+	skip int
+}
+
+func (p resultPosition) cursor() string {
+	return fmt.Sprintf("%d:%d:%d:%d:%d", p.counter, p.objectID, p.timestamp, p.block, p.skip)
+}
+
+func parseCursor(cursor string) (resultPosition, error) {
+	var p resultPosition
+	if cursor == "" {
+		return p, nil
+	}
+	if _, err := fmt.Sscanf(cursor, "%d:%d:%d:%d:%d", &p.counter, &p.objectID, &p.timestamp, &p.block, &p.skip); err != nil || p.counter < 0 || p.block < 0 || p.skip < 0 {
+		return p, fmt.Errorf("invalid cursor %q", cursor)
+	}
+	return p, nil
+}
+
+// pagedQuery reports whether the result of a data query is split over
+// several responses
+func pagedQuery(query models.Query) bool {
+	return query.Rank == nil && (query.Limit > 0 || query.Cursor != "" || query.Stream)
+}
+
+// checkPaging returns why the paging options of a query are invalid, or nil
+func checkPaging(query models.Query) error {
+	if query.Limit < 0 {
+		return fmt.Errorf("limit must not be negative, got %d", query.Limit)
+	}
+	_, err := parseCursor(query.Cursor)
+	return err
+}
+
+// answerPaged sends the result of a query in responses of at most
+// query.Limit points. Series are ordered by counter as requested and by
+// ascending object ID, and a series longer than the room left in a response
+// is split across responses. A paged query gets one response, with a cursor
+// when more follows; a streamed query gets every chunk up to the one marked
+// done. Each response only holds the objects read for it, so neither side
+// holds the whole result.
+func answerPaged(ctx context.Context, storage *storageEngine.StorageEngine, query models.Query, counterIDs []uint16, queryResultCh chan<- models.QueryResponse) {
+	start, _ := parseCursor(query.Cursor) // validated by checkPaging
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultChunkPoints
+	}
+
+	var sequence uint32
+	page := newPage(query, sequence)
+	count := 0
+	incomplete := false
+
+	// flush sends the current page; it returns false when the query is answered
+	flush := func(next *resultPosition) bool {
+		if next == nil {
+			page.Done = true
+		} else {
+			page.Cursor = next.cursor()
+		}
+		finishResponse(ctx, &page, incomplete)
+		log.Printf("Sending response %d for QueryID %d with %d points and status %s", page.Sequence, query.QueryID, count, page.Status)
+		queryResultCh <- page
+
+		sequence++
+		page = newPage(query, sequence)
+		count = 0
+		incomplete = false
+		return next != nil && query.Stream
+	}
+
+	for ci := start.counter; ci < len(counterIDs) && ctx.Err() == nil; ci++ {
+		counterID := counterIDs[ci]
+
+		objectIDs, warnings := resolveObjects(storage, query, counterID)
+		page.Warnings = append(page.Warnings, warnings...)
+		objectIDs = sortedObjectIDs(objectIDs)
+
+		if warning, known := checkCounterKnown(counterID); !known {
+			page.Warnings = append(page.Warnings, warning)
+			incomplete = true
+			continue
+		}
+		page.Warnings = append(page.Warnings, missingDayWarnings(query, counterID)...)
+
+		versions := make(rollupVersions)
+
+		for _, objectID := range objectIDs {
+			if ctx.Err() != nil {
+				break
+			}
+
+			position := resultPosition{counter: ci, objectID: objectID}
+			if ci == start.counter {
+				if objectID < start.objectID {
+					continue
+				}
+				if objectID == start.objectID {
+					position = start
+				}
+			}
+
+			for {
+				points, next, warnings := readSeriesPage(ctx, storage, query, counterID, position, limit-count, versions)
+				addObjectWarnings(&page, objectID, warnings)
+				addSeries(&page, query, counterID, objectID, points)
+				count += len(points)
+
+				if next == nil && count < limit {
+					break // the series fit, read the next object
+				}
+				if next == nil {
+					n := nextObject(ci, objectID)
+					next = &n
+				}
+				if !flush(next) {
+					return
+				}
+				if next.objectID != objectID || next.counter != ci {
+					break
+				}
+				position = *next
+			}
+		}
+	}
+
+	flush(nil)
+}
+
+// readSeriesPage reads up to want points of the series of the object at
+// position, from position on. It returns where the series continues, or nil
+// when it ends within them. A raw series is read from the resume point on
+// and no further than the page needs; a series computed whole is computed
+// again for each page.
+func readSeriesPage(ctx context.Context, storage *storageEngine.StorageEngine, query models.Query, counterID uint16, position resultPosition, want int, versions rollupVersions) ([]models.DataPoint, *resultPosition, []string) {
+	if query.Aggregation != "" {
+		points, warnings := readObject(ctx, storage, query, counterID, position.objectID, versions)
+		if position.skip >= len(points) {
+			return nil, nil, warnings
+		}
+		points = points[position.skip:]
+		if len(points) <= want {
+			return points, nil, warnings
+		}
+		next := position
+		next.skip += want
+		return points[:want], &next, warnings
+	}
+
+	var points []models.DataPoint
+	var warnings []string
+	var next *resultPosition
+
+	resumeFrom := query.From
+	if position.timestamp > resumeFrom {
+		resumeFrom = position.timestamp
+	}
+
+	for i, day := range queryDays(resumeFrom, query.To) {
+		if ctx.Err() != nil || next != nil {
+			break
+		}
+
+		dateStr := day.Format("2006/01/02")
+		counterPath := counterDayPath(day, counterID)
+
+		// the day of the resume point resumes at its block; late points
+		// written to later blocks are earlier in time, so the range stays
+		// that of the query
+		startOffset := int64(0)
+		if i == 0 {
+			startOffset = position.block
+		}
+
+		dayWarnings, err := readBlocks(ctx, storage, counterPath, int(position.objectID), query.From, query.To, counterID, startOffset, pageBatchBlocks, func(offset int64, blockPoints []models.DataPoint) bool {
+			first := 0
+			if i == 0 && offset == position.block && position.skip > 0 {
+				first = position.skip
+				if first > len(blockPoints) {
+					first = len(blockPoints)
+				}
+			}
+
+			if end := first + want - len(points); end < len(blockPoints) {
+				points = append(points, blockPoints[first:end]...)
+				next = &resultPosition{counter: position.counter, objectID: position.objectID, timestamp: blockPoints[end].Timestamp, block: offset, skip: end}
+				return false
+			}
+			points = append(points, blockPoints[first:]...)
+			return true
+		})
+		warnings = append(warnings, dayWarnings...)
+		if err != nil {
+			log.Printf("Error reading data for ObjectID %d on %s: %v", position.objectID, dateStr, err)
+			if ctx.Err() == nil {
+				warnings = append(warnings, fmt.Sprintf("data of %s could not be read: %v", dateStr, err))
+			}
+		}
+	}
+
+	return points, next, warnings
+}
+
+// nextObject is the position after the last point of objectID
+func nextObject(counter int, objectID uint32) resultPosition {
+	if objectID == math.MaxUint32 {
+		return resultPosition{counter: counter + 1}
+	}
+	return resultPosition{counter: counter, objectID: objectID + 1}
+}
+
+func newPage(query models.Query, sequence uint32) models.QueryResponse {
+	return models.QueryResponse{
+		QueryID:  query.QueryID,
+		Data:     make(map[uint32][]models.DataPoint),
+		Sequence: sequence,
+	}
+}
+
+// addSeries adds points of one series to a page in the shape of the query
+func addSeries(page *models.QueryResponse, query models.Query, counterID uint16, objectID uint32, points []models.DataPoint) {
+	if len(query.CounterIDs) == 0 {
+		page.Data[objectID] = append(page.Data[objectID], points...)
+		return
+	}
+
+	if page.CounterData == nil {
+		page.CounterData = make(map[uint32]map[uint16][]models.DataPoint)
+	}
+	if page.CounterData[objectID] == nil {
+		page.CounterData[objectID] = make(map[uint16][]models.DataPoint)
+	}
+	page.CounterData[objectID][counterID] = append(page.CounterData[objectID][counterID], points...)
+}
+
+// sortedObjectIDs returns the distinct object IDs in ascending order
+func sortedObjectIDs(objectIDs []uint32) []uint32 {
+	sorted := append([]uint32(nil), objectIDs...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	distinct := sorted[:0]
+	for i, objectID := range sorted {
+		if i == 0 || objectID != sorted[i-1] {
+			distinct = append(distinct, objectID)
+		}
+	}
+	return distinct
+}
@@ -0,0 +1,216 @@
+package reader
+
+import (
+	"context"
+	"math"
+	"packx/models"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCursor(t *testing.T) {
+	tests := []struct {
+		name string
+
+		cursor string
+
+		want resultPosition
+
+		wantErr bool
+	}{
+		{name: "empty starts at the beginning", cursor: ""},
+		{name: "round trip", cursor: resultPosition{counter: 1, objectID: 7, timestamp: 86400, block: 8192, skip: 3}.cursor(), want: resultPosition{counter: 1, objectID: 7, timestamp: 86400, block: 8192, skip: 3}},
+		{name: "object only", cursor: resultPosition{objectID: 9}.cursor(), want: resultPosition{objectID: 9}},
+		{name: "earlier format", cursor: "0:7:3", wantErr: true},
+		{name: "not numbers", cursor: "a:b:c:d:e", wantErr: true},
+		{name: "negative counter", cursor: "-1:7:0:0:0", wantErr: true},
+		{name: "negative block", cursor: "0:7:0:-4096:0", wantErr: true},
+		{name: "negative skip", cursor: "0:7:0:0:-1", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseCursor(tc.cursor)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseCursor(%q): error %v, want error %v", tc.cursor, err, tc.wantErr)
+			}
+			if err == nil && got != tc.want {
+				t.Fatalf("parseCursor(%q) = %+v, want %+v", tc.cursor, got, tc.want)
+			}
+
+			query := models.Query{Cursor: tc.cursor}
+			if err := checkPaging(query); (err != nil) != tc.wantErr {
+				t.Fatalf("checkPaging(%q): error %v, want error %v", tc.cursor, err, tc.wantErr)
+			}
+		})
+	}
+}
+
+// pageThrough requests the pages of query one after the other, as a client
+// following cursors does, and returns them
+func pageThrough(t *testing.T, query models.Query, answer func(models.Query) models.QueryResponse) []models.QueryResponse {
+	t.Helper()
+
+	var pages []models.QueryResponse
+	for {
+		page := answer(query)
+		pages = append(pages, page)
+		if page.Cursor == "" {
+			return pages
+		}
+		if len(pages) > 10000 {
+			t.Fatalf("paging does not end, last cursor %q", page.Cursor)
+		}
+		query.Cursor = page.Cursor
+	}
+}
+
+func TestAnswerPagedRoundTrip(t *testing.T) {
+	storage := openTestStorage(t)
+
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local)
+	from := uint32(day.Unix())
+
+	// noisy values compress poorly, so object 1 spans many blocks; it has a
+	// late point written after the others and points sharing a second
+	var timestamps []uint32
+	var values []float64
+	for i := 0; i < 3000; i++ {
+		timestamps = append(timestamps, from+uint32(i))
+		values = append(values, math.Sin(float64(i)))
+	}
+	putFloats(t, storage, day, 1, timestamps, values)
+	putFloats(t, storage, day, 1, []uint32{from + 10, from + 3000, from + 3000, from + 3000}, []float64{-1, 1, 2, 3})
+	putFloats(t, storage, day, 2, []uint32{from + 5, from + 6}, []float64{5, 6})
+
+	query := models.Query{QueryID: 1, From: from, To: from + 3600, ObjectIDs: []uint32{1, 2}, CounterId: 2}
+
+	// the whole result, read in one response
+	want := map[uint32][]models.DataPoint{}
+	for _, objectID := range query.ObjectIDs {
+		points, warnings := readObject(context.Background(), storage, query, 2, objectID, make(rollupVersions))
+		if len(warnings) > 0 {
+			t.Fatalf("unexpected warnings for object %d: %v", objectID, warnings)
+		}
+		want[objectID] = points
+	}
+	if len(want[1]) != 3004 || len(want[2]) != 2 {
+		t.Fatalf("unexpected full read: %d and %d points", len(want[1]), len(want[2]))
+	}
+
+	answer := func(query models.Query) models.QueryResponse {
+		responses := make(chan models.QueryResponse, 1)
+		answerPaged(withQueryStats(context.Background()), storage, query, query.Counters(), responses)
+		return <-responses
+	}
+
+	for _, limit := range []int{1, 7, 100, 3004, 3006, 10000} {
+		query.Limit = limit
+		pages := pageThrough(t, query, answer)
+
+		got := map[uint32][]models.DataPoint{}
+		for i, page := range pages {
+			count := 0
+			for objectID, points := range page.Data {
+				got[objectID] = append(got[objectID], points...)
+				count += len(points)
+			}
+			if count > limit {
+				t.Fatalf("limit %d: page %d holds %d points", limit, i, count)
+			}
+			if page.Status != models.StatusOK {
+				t.Fatalf("limit %d: page %d has status %s", limit, i, page.Status)
+			}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("limit %d: paged result differs from the full read", limit)
+		}
+		if !pages[len(pages)-1].Done {
+			t.Fatalf("limit %d: last page not marked done", limit)
+		}
+	}
+}
+
+func TestAnswerPagedResumesAtCursor(t *testing.T) {
+	storage := openTestStorage(t)
+
+	day := time.Date(2025, 3, 12, 0, 0, 0, 0, time.Local)
+	from := uint32(day.Unix())
+
+	var timestamps []uint32
+	var values []float64
+	for i := 0; i < 20000; i++ {
+		timestamps = append(timestamps, from+uint32(i))
+		values = append(values, math.Sin(float64(i)))
+	}
+	putFloats(t, storage, day, 4, timestamps, values)
+
+	query := models.Query{QueryID: 1, From: from, To: from + 86399, ObjectIDs: []uint32{4}, CounterId: 2}
+	answer := func(query models.Query) models.QueryResponse {
+		responses := make(chan models.QueryResponse, 1)
+		answerPaged(withQueryStats(context.Background()), storage, query, query.Counters(), responses)
+		return <-responses
+	}
+
+	query.Limit = len(timestamps)
+	fullScan := answer(query).Stats.BlocksScanned
+	if fullScan <= 2*pageBatchBlocks {
+		t.Fatalf("expected the series to span more than %d blocks, got %d", 2*pageBatchBlocks, fullScan)
+	}
+
+	// each page views the blocks from its cursor on, a batch at a time, and
+	// stops once it holds its points
+	query.Limit = 100
+	points := 0
+	for i, page := range pageThrough(t, query, answer) {
+		if page.Stats.BlocksScanned > 2*pageBatchBlocks {
+			t.Fatalf("page %d scanned %d of %d blocks", i, page.Stats.BlocksScanned, fullScan)
+		}
+		for _, point := range page.Data[4] {
+			if point.Timestamp != timestamps[points] {
+				t.Fatalf("page %d: expected timestamp %d, got %d", i, timestamps[points], point.Timestamp)
+			}
+			points++
+		}
+	}
+	if points != len(timestamps) {
+		t.Fatalf("expected %d points over the pages, got %d", len(timestamps), points)
+	}
+}
+
+func TestAnswerPagedStreamsChunksInSequence(t *testing.T) {
+	storage := openTestStorage(t)
+
+	day := time.Date(2025, 3, 11, 0, 0, 0, 0, time.Local)
+	from := uint32(day.Unix())
+
+	var timestamps []uint32
+	var values []float64
+	for i := 0; i < 250; i++ {
+		timestamps = append(timestamps, from+uint32(i))
+		values = append(values, float64(i))
+	}
+	putFloats(t, storage, day, 3, timestamps, values)
+
+	query := models.Query{QueryID: 1, From: from, To: from + 3600, ObjectIDs: []uint32{3}, CounterId: 2, Limit: 100, Stream: true}
+	responses := make(chan models.QueryResponse, 10)
+	answerPaged(context.Background(), storage, query, query.Counters(), responses)
+	close(responses)
+
+	var points []models.DataPoint
+	var sequence uint32
+	for chunk := range responses {
+		if chunk.Sequence != sequence {
+			t.Fatalf("expected chunk %d, got %d", sequence, chunk.Sequence)
+		}
+		sequence++
+		points = append(points, chunk.Data[3]...)
+		if chunk.Done != (len(points) == 250) {
+			t.Fatalf("chunk %d: done %v after %d points", chunk.Sequence, chunk.Done, len(points))
+		}
+	}
+	if sequence != 3 || len(points) != 250 || points[249].Value != float64(249) {
+		t.Fatalf("expected 250 points in 3 chunks, got %d points in %d", len(points), sequence)
+	}
+}
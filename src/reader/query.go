@@ -2,14 +2,19 @@ package reader
 
 import (
 	"packx/models"
+	"packx/storageEngine"
 	"packx/utils"
 	"sync"
 )
 
-func InitQueryEngine(queryReceiveCh <-chan models.Query, queryResultCh chan<- models.QueryResponse, shutDownWg *sync.WaitGroup) {
+func InitQueryEngine(queryReceiveCh <-chan models.Query, queryCancelCh <-chan uint64, queryResultCh chan<- models.QueryResponse, storage *storageEngine.StorageEngine, shutDownWg *sync.WaitGroup) {
 
 	defer shutDownWg.Done()
 
+	// cancels bypass the query channel, which may be backed up behind the
+	// very queries they abort
+	go CancelQueries(queryCancelCh)
+
 	var readersWaitGroup sync.WaitGroup
 
 	Readers := utils.GetReaders()
@@ -18,7 +23,7 @@ func InitQueryEngine(queryReceiveCh <-chan models.Query, queryResultCh chan<- mo
 
 	for range Readers {
 
-		go Reader(queryReceiveCh, queryResultCh, &readersWaitGroup)
+		go Reader(queryReceiveCh, queryResultCh, storage, &readersWaitGroup)
 
 	}
 
@@ -0,0 +1,70 @@
+package reader
+
+import (
+	"fmt"
+	"math"
+	"packx/models"
+	"sort"
+)
+
+// checkRanking returns why the ranking of a query cannot be computed, or nil
+func checkRanking(query models.Query) error {
+	if query.Rank == nil {
+		return nil
+	}
+	if query.Rank.Order != models.RankTop && query.Rank.Order != models.RankBottom {
+		return fmt.Errorf("unknown rank order %q", query.Rank.Order)
+	}
+	if query.Rank.Limit <= 0 {
+		return fmt.Errorf("rank limit must be positive, got %d", query.Rank.Limit)
+	}
+	if query.Aggregation == "" {
+		return fmt.Errorf("ranking needs an aggregation")
+	}
+	if query.Interval != 0 {
+		return fmt.Errorf("ranking cannot be combined with an interval")
+	}
+	return nil
+}
+
+// rankObjects orders the objects by their aggregated value and keeps the
+// first rank.Limit. Objects whose aggregation is not a number, such as the
+// mode of a string counter, cannot be ranked and are left out with a warning.
+func rankObjects(data map[uint32][]models.DataPoint, rank models.Ranking) ([]models.RankedObject, []string) {
+	var ranked []models.RankedObject
+	var warnings []string
+	unranked := 0
+
+	for objectID, points := range data {
+		if len(points) == 0 {
+			continue // no data in the range
+		}
+
+		value, ok := numericValue(points[0].Value)
+		if !ok || math.IsNaN(value) {
+			unranked++
+			continue
+		}
+
+		ranked = append(ranked, models.RankedObject{ObjectID: objectID, Timestamp: points[0].Timestamp, Value: value})
+	}
+
+	if unranked > 0 {
+		warnings = append(warnings, fmt.Sprintf("%d objects were left out of the ranking, their aggregated value is not a number", unranked))
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Value != ranked[j].Value {
+			if rank.Order == models.RankTop {
+				return ranked[i].Value > ranked[j].Value
+			}
+			return ranked[i].Value < ranked[j].Value
+		}
+		return ranked[i].ObjectID < ranked[j].ObjectID
+	})
+
+	if len(ranked) > rank.Limit {
+		ranked = ranked[:rank.Limit]
+	}
+	return ranked, warnings
+}
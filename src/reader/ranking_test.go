@@ -0,0 +1,138 @@
+package reader
+
+import (
+	"math"
+	"packx/models"
+	"testing"
+)
+
+// rankedIDs returns the object IDs of ranked in order
+func rankedIDs(ranked []models.RankedObject) []uint32 {
+	var ids []uint32
+	for _, object := range ranked {
+		ids = append(ids, object.ObjectID)
+	}
+	return ids
+}
+
+func sameIDs(a []uint32, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRankObjects(t *testing.T) {
+	data := map[uint32][]models.DataPoint{
+		1: {{Timestamp: 100, Value: 5.0}},
+		2: {{Timestamp: 100, Value: 9.0}},
+		3: {{Timestamp: 100, Value: 5.0}},
+		4: {{Timestamp: 90, Value: int64(1)}},
+		5: nil, // no data in the range
+	}
+
+	top, warnings := rankObjects(data, models.Ranking{Order: models.RankTop, Limit: 2})
+	if !sameIDs(rankedIDs(top), []uint32{2, 1}) || len(warnings) != 0 {
+		t.Fatalf("unexpected top 2 %v, warnings %v", rankedIDs(top), warnings)
+	}
+
+	// objects 1 and 3 are tied, the lower ID ranks first either way
+	top, _ = rankObjects(data, models.Ranking{Order: models.RankTop, Limit: 3})
+	if !sameIDs(rankedIDs(top), []uint32{2, 1, 3}) {
+		t.Fatalf("unexpected top 3 %v", rankedIDs(top))
+	}
+	bottom, _ := rankObjects(data, models.Ranking{Order: models.RankBottom, Limit: 3})
+	if !sameIDs(rankedIDs(bottom), []uint32{4, 1, 3}) {
+		t.Fatalf("unexpected bottom 3 %v", rankedIDs(bottom))
+	}
+
+	// a ranked object carries its value and the timestamp of its point
+	if bottom[0] != (models.RankedObject{ObjectID: 4, Timestamp: 90, Value: 1}) {
+		t.Fatalf("unexpected ranked object %+v", bottom[0])
+	}
+
+	// a limit past the objects with data returns all of them
+	all, _ := rankObjects(data, models.Ranking{Order: models.RankTop, Limit: 10})
+	if !sameIDs(rankedIDs(all), []uint32{2, 1, 3, 4}) {
+		t.Fatalf("unexpected ranking of every object %v", rankedIDs(all))
+	}
+
+	// a tie at the limit is cut by object ID
+	tied := map[uint32][]models.DataPoint{
+		8: {{Timestamp: 100, Value: 3.0}},
+		6: {{Timestamp: 100, Value: 3.0}},
+		7: {{Timestamp: 100, Value: 3.0}},
+	}
+	top, _ = rankObjects(tied, models.Ranking{Order: models.RankTop, Limit: 2})
+	if !sameIDs(rankedIDs(top), []uint32{6, 7}) {
+		t.Fatalf("unexpected ranking of tied objects %v", rankedIDs(top))
+	}
+
+	if none, _ := rankObjects(map[uint32][]models.DataPoint{}, models.Ranking{Order: models.RankTop, Limit: 3}); len(none) != 0 {
+		t.Fatalf("expected an empty ranking without objects, got %+v", none)
+	}
+}
+
+func TestRankObjectsLeavesOutValuesThatAreNotNumbers(t *testing.T) {
+	data := map[uint32][]models.DataPoint{
+		1: {{Timestamp: 100, Value: "up"}},
+		2: {{Timestamp: 100, Value: math.NaN()}},
+		3: {{Timestamp: 100, Value: 1.0}},
+	}
+
+	ranked, warnings := rankObjects(data, models.Ranking{Order: models.RankTop, Limit: 3})
+	if !sameIDs(rankedIDs(ranked), []uint32{3}) {
+		t.Fatalf("expected only object 3 to be ranked, got %v", rankedIDs(ranked))
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("expected one warning for the objects left out, got %v", warnings)
+	}
+}
+
+func TestCheckRanking(t *testing.T) {
+	testCases := []struct {
+		name        string
+		query       models.Query
+		shouldError bool
+	}{
+		{
+			name:  "No Ranking",
+			query: models.Query{},
+		},
+		{
+			name:  "Top",
+			query: models.Query{Aggregation: "avg", Rank: &models.Ranking{Order: models.RankTop, Limit: 5}},
+		},
+		{
+			name:        "Unknown Order",
+			query:       models.Query{Aggregation: "avg", Rank: &models.Ranking{Order: "middle", Limit: 5}},
+			shouldError: true,
+		},
+		{
+			name:        "Zero Limit",
+			query:       models.Query{Aggregation: "avg", Rank: &models.Ranking{Order: models.RankBottom}},
+			shouldError: true,
+		},
+		{
+			name:        "No Aggregation",
+			query:       models.Query{Rank: &models.Ranking{Order: models.RankTop, Limit: 5}},
+			shouldError: true,
+		},
+		{
+			name:        "With Interval",
+			query:       models.Query{Aggregation: "avg", Interval: 60, Rank: &models.Ranking{Order: models.RankTop, Limit: 5}},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		if err := checkRanking(tc.query); (err != nil) != tc.shouldError {
+			t.Fatalf("%s: checkRanking returned %v", tc.name, err)
+		}
+	}
+}
@@ -1,146 +1,455 @@
 package reader
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"log"
 	"math"
+	"os"
 	"packx/models"
 	"packx/storageEngine"
 	"packx/utils"
-	"path/filepath"
 	"sync"
 	"time"
 )
 
-func Reader(queryReceiveCh <-chan models.Query, queryResultCh chan<- models.QueryResponse, shutDownWg *sync.WaitGroup) {
+func Reader(queryReceiveCh <-chan models.Query, queryResultCh chan<- models.QueryResponse, storage *storageEngine.StorageEngine, shutDownWg *sync.WaitGroup) {
 	defer shutDownWg.Done()
 
-	storage, err := storageEngine.NewStorageEngine()
+	for query := range queryReceiveCh {
+		if query.Cancel {
+			runningQueries.cancel(query.QueryID)
+			continue
+		}
+
+		ctx, finish := runningQueries.start(query)
+		answerQuery(ctx, storage, query, queryResultCh)
+		finish()
+	}
+}
+
+// answerQuery reads the result of one query and sends it. Once ctx is done the
+// reads stop and the response carries what was read so far, with the status
+// saying why it was cut short.
+func answerQuery(ctx context.Context, storage *storageEngine.StorageEngine, query models.Query, queryResultCh chan<- models.QueryResponse) {
+	log.Printf("Reader processing query: %+v", query)
+
+	ctx = withQueryStats(ctx)
+
+	// Initialize response
+	response := models.QueryResponse{
+		QueryID: query.QueryID,
+		Data:    make(map[uint32][]models.DataPoint),
+		Done:    true,
+	}
+
+	switch query.Type {
+	case models.QueryTypeData:
+	case models.QueryTypeObjects:
+		answerObjectsQuery(storage, query, &response)
+		finishResponse(ctx, &response, len(response.Warnings) > 0)
+		log.Printf("Sending objects of %d counters for QueryID %d", len(response.Objects), query.QueryID)
+		queryResultCh <- response
+		return
+	default:
+		log.Printf("Rejecting query %d: unknown query type %q", query.QueryID, query.Type)
+		rejectResponse(&response, fmt.Errorf("unknown query type %q", query.Type))
+		queryResultCh <- response
+		return
+	}
+
+	counterIDs := query.Counters()
+
+	err := checkCounters(query, counterIDs)
+	if err == nil {
+		err = checkRanking(query)
+	}
+	if err == nil {
+		err = checkFill(query)
+	}
+	if err == nil {
+		err = checkPaging(query)
+	}
 	if err != nil {
-		log.Printf("Failed to create storage engine: %v", err)
+		log.Printf("Rejecting query %d: %v", query.QueryID, err)
+		rejectResponse(&response, err)
+		queryResultCh <- response
 		return
 	}
 
-	for query := range queryReceiveCh {
-		log.Printf("Reader processing query: %+v", query)
-
-		// Initialize response
-		response := models.QueryResponse{
-			QueryID: query.QueryID,
-			Data:    make(map[uint32][]models.DataPoint),
-		}
-
-		// Process each ObjectID in the query
-		for _, objectID := range query.ObjectIDs {
-			log.Printf("Processing ObjectID: %d", objectID)
-			
-			// Calculate time range for the query
-			fromTime := time.Unix(int64(query.From), 0)
-			toTime := time.Unix(int64(query.To), 0)
-			
-			var allDataPoints []models.DataPoint
-
-			// Iterate through each day in the time range
-			for d := fromTime; !d.After(toTime); d = d.AddDate(0, 0, 1) {
-				dateStr := d.Format("2006/01/02")
-				counterPath := filepath.Join(
-					utils.GetStoragePath(),
-					dateStr,
-					fmt.Sprintf("counter_%d", query.CounterId),
-				)
-				
-				// Set storage path for this read operation
-				if err := storage.SetStoragePath(counterPath); err != nil {
-					log.Printf("Error setting storage path for date %s: %v", dateStr, err)
-					continue
-				}
-				
-				// Process data for this object on this day
-				dataPoints, err := readDataForObject(storage, int(objectID), query.From, query.To, query.CounterId)
-				if err != nil {
-					log.Printf("Error reading data for ObjectID %d on %s: %v", objectID, dateStr, err)
-					continue
-				}
-				
-				allDataPoints = append(allDataPoints, dataPoints...)
-				log.Printf("Found %d data points for ObjectID %d on %s", len(dataPoints), objectID, dateStr)
+	if pagedQuery(query) {
+		answerPaged(ctx, storage, query, counterIDs, queryResultCh)
+		return
+	}
+
+	// Read every counter in parallel, each from its own counter_N directories
+	results := make([]counterResult, len(counterIDs))
+	var counterWg sync.WaitGroup
+	for i, counterID := range counterIDs {
+		counterWg.Add(1)
+		go func(i int, counterID uint16) {
+			defer counterWg.Done()
+			results[i] = readCounter(ctx, storage, query, counterID)
+		}(i, counterID)
+	}
+	counterWg.Wait()
+
+	incomplete := false
+	for i, result := range results {
+		response.Warnings = append(response.Warnings, result.warnings...)
+		for objectID, warnings := range result.objectWarnings {
+			addObjectWarnings(&response, objectID, warnings)
+		}
+		incomplete = incomplete || result.incomplete
+
+		// Ranking queries only return the ranked objects
+		if query.Rank != nil {
+			if response.Rankings == nil {
+				response.Rankings = make(map[uint16][]models.RankedObject)
 			}
-			
-			log.Printf("Found total %d data points for ObjectID %d", len(allDataPoints), objectID)
-
-			// Apply aggregation if specified
-			if query.Aggregation != "" && len(allDataPoints) > 0 {
-				aggregatedPoints := aggregateDataPoints(allDataPoints, query.Aggregation)
-				log.Printf("Aggregated %d points to %d points using %s", len(allDataPoints), len(aggregatedPoints), query.Aggregation)
-				response.Data[objectID] = aggregatedPoints
-			} else {
-				response.Data[objectID] = allDataPoints
+			ranked, warnings := rankObjects(result.data, *query.Rank)
+			response.Rankings[counterIDs[i]] = ranked
+			response.Warnings = append(response.Warnings, warnings...)
+			continue
+		}
+
+		// Single counter queries keep the original response shape
+		if len(query.CounterIDs) == 0 {
+			response.Data = result.data
+			continue
+		}
+
+		if response.CounterData == nil {
+			response.CounterData = make(map[uint32]map[uint16][]models.DataPoint)
+		}
+		for objectID, points := range result.data {
+			if response.CounterData[objectID] == nil {
+				response.CounterData[objectID] = make(map[uint16][]models.DataPoint)
 			}
+			response.CounterData[objectID][counterIDs[i]] = points
 		}
+	}
 
-		// Send response
-		log.Printf("Sending response for QueryID %d with %d objects", query.QueryID, len(response.Data))
-		queryResultCh <- response
+	finishResponse(ctx, &response, incomplete)
+
+	// Send response
+	log.Printf("Sending response for QueryID %d over %d counters with status %s", query.QueryID, len(counterIDs), response.Status)
+	queryResultCh <- response
+}
+
+// counterResult holds what one counter of a query read
+type counterResult struct {
+	data map[uint32][]models.DataPoint
+
+	warnings []string // about the counter as a whole
+
+	objectWarnings map[uint32][]string
+
+	incomplete bool // the counter could not be read at all
+}
+
+// checkCounters returns why the aggregation of a query cannot be applied to
+// its counters, or nil. Unknown counters are left to the read, which finds no
+// data for them.
+func checkCounters(query models.Query, counterIDs []uint16) error {
+	if query.Aggregation == "" {
+		return nil
+	}
+
+	if _, exists := lookupAggregator(query.Aggregation); !exists {
+		return rejectQuery(models.ErrorCodeUnknownAggregation, "unknown aggregation %q", query.Aggregation)
 	}
+
+	for _, counterID := range counterIDs {
+		dataType, err := utils.GetCounterType(counterID)
+		if err != nil {
+			continue
+		}
+
+		if err := checkAggregation(query.Aggregation, dataType); err != nil {
+			if len(query.CounterIDs) > 0 {
+				return rejectQuery(err.(*queryError).code, "counter %d: %v", counterID, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCounterKnown returns a warning when counterID is not configured, in
+// which case nothing can be read for it
+func checkCounterKnown(counterID uint16) (string, bool) {
+	if _, err := utils.GetCounterType(counterID); err != nil {
+		return fmt.Sprintf("counter %d: %v", counterID, err), false
+	}
+	return "", true
+}
+
+// missingDayWarnings reports the days of the query, up to today, that hold
+// no data directory for the counter. Past maxMissingDayWarnings the remaining
+// days are only counted.
+func missingDayWarnings(query models.Query, counterID uint16) []string {
+	var warnings []string
+	missing := 0
+	now := time.Now()
+	for _, day := range queryDays(query.From, query.To) {
+		if day.After(now) {
+			break
+		}
+		if _, err := os.Stat(counterDayPath(day, counterID)); !os.IsNotExist(err) {
+			continue
+		}
+		missing++
+		if missing <= maxMissingDayWarnings {
+			warnings = append(warnings, fmt.Sprintf("counter %d has no data for %s", counterID, day.Format("2006/01/02")))
+		}
+	}
+	if missing > maxMissingDayWarnings {
+		warnings = append(warnings, fmt.Sprintf("counter %d has no data for %d more days", counterID, missing-maxMissingDayWarnings))
+	}
+	return warnings
 }
 
-// readDataForObject reads data for a specific object ID from storage
-func readDataForObject(storage *storageEngine.StorageEngine, objectID int, fromTime uint32, toTime uint32, counterID uint16) ([]models.DataPoint, error) {
+// readCounter answers the query for every object on one counter
+func readCounter(ctx context.Context, storage *storageEngine.StorageEngine, query models.Query, counterID uint16) counterResult {
+	result := counterResult{
+		data:           make(map[uint32][]models.DataPoint),
+		objectWarnings: make(map[uint32][]string),
+	}
+
+	objectIDs, warnings := resolveObjects(storage, query, counterID)
+	result.warnings = append(result.warnings, warnings...)
+
+	if warning, known := checkCounterKnown(counterID); !known {
+		result.warnings = append(result.warnings, warning)
+		result.incomplete = true
+		for _, objectID := range objectIDs {
+			result.data[objectID] = nil
+		}
+		return result
+	}
+	result.warnings = append(result.warnings, missingDayWarnings(query, counterID)...)
+
+	// cached raw data versions of the days read from rollups
+	versions := make(rollupVersions)
+
+	// Process each ObjectID in the query
+	for _, objectID := range objectIDs {
+		if ctx.Err() != nil {
+			break // cut short, the objects read so far are returned
+		}
+
+		points, warnings := readObject(ctx, storage, query, counterID, objectID, versions)
+		if len(warnings) > 0 {
+			result.objectWarnings[objectID] = warnings
+		}
+		result.data[objectID] = points
+	}
+
+	return result
+}
+
+// resolveObjects returns the objects the query reads on one counter; the
+// wildcard reads every object with data for it
+func resolveObjects(storage *storageEngine.StorageEngine, query models.Query, counterID uint16) ([]uint32, []string) {
+	if !query.ObjectIDs.All() {
+		return query.ObjectIDs, nil
+	}
+
+	var warnings []string
+	objects, err := counterObjects(storage, counterID, query.From, query.To)
+	if err != nil {
+		log.Printf("Error listing objects of counter %d: %v", counterID, err)
+		warnings = append(warnings, fmt.Sprintf("counter %d: %v", counterID, err))
+	}
+
+	objectIDs := make([]uint32, 0, len(objects))
+	for _, object := range objects {
+		objectIDs = append(objectIDs, object.ObjectID)
+	}
+	return objectIDs, warnings
+}
+
+// readObject answers the query for one object on one counter
+func readObject(ctx context.Context, storage *storageEngine.StorageEngine, query models.Query, counterID uint16, objectID uint32, versions rollupVersions) ([]models.DataPoint, []string) {
+	query.CounterId = counterID
+
+	log.Printf("Processing ObjectID: %d of counter %d", objectID, counterID)
+
+	// Aggregations rollups can answer read them instead of the raw
+	// points wherever a current rollup exists
+	if rollupAggregation(query.Aggregation) && numericCounter(counterID) {
+		points, warnings := aggregateWithRollups(ctx, storage, query, objectID, versions)
+		return fillGaps(points, query), warnings
+	}
+
+	var allDataPoints []models.DataPoint
+	var allWarnings []string
+
+	// Iterate through each day in the time range
+	for _, day := range queryDays(query.From, query.To) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		dateStr := day.Format("2006/01/02")
+		counterPath := counterDayPath(day, counterID)
+
+		// Process data for this object on this day
+		dataPoints, warnings, err := readDataForObject(ctx, storage, counterPath, int(objectID), query.From, query.To, counterID)
+		allWarnings = append(allWarnings, warnings...)
+		if err != nil {
+			log.Printf("Error reading data for ObjectID %d on %s: %v", objectID, dateStr, err)
+			if ctx.Err() == nil {
+				allWarnings = append(allWarnings, fmt.Sprintf("data of %s could not be read: %v", dateStr, err))
+			}
+			continue
+		}
+
+		allDataPoints = append(allDataPoints, dataPoints...)
+		log.Printf("Found %d data points for ObjectID %d on %s", len(dataPoints), objectID, dateStr)
+	}
+
+	log.Printf("Found total %d data points for ObjectID %d", len(allDataPoints), objectID)
+
+	// Apply aggregation if specified, per interval bucket when one is given
+	if query.Aggregation != "" && query.Interval > 0 {
+		bucketedPoints := bucketDataPoints(allDataPoints, query.Aggregation, query.Interval, Window{From: query.From, To: query.To})
+		log.Printf("Aggregated %d points to %d buckets of %ds using %s", len(allDataPoints), len(bucketedPoints), query.Interval, query.Aggregation)
+		return fillGaps(bucketedPoints, query), allWarnings
+	} else if query.Aggregation != "" && len(allDataPoints) > 0 {
+		aggregatedPoints := aggregateDataPoints(allDataPoints, query.Aggregation, Window{From: query.From, To: query.To})
+		log.Printf("Aggregated %d points to %d points using %s", len(allDataPoints), len(aggregatedPoints), query.Aggregation)
+		return aggregatedPoints, allWarnings
+	}
+
+	return allDataPoints, allWarnings
+}
+
+// readDataForObject reads data for a specific object ID from storage. Blocks
+// that are corrupt or cannot be decoded are skipped and reported as warnings.
+// Once ctx is done it stops, returning the points decoded so far.
+func readDataForObject(ctx context.Context, storage *storageEngine.StorageEngine, counterPath string, objectID int, fromTime uint32, toTime uint32, counterID uint16) ([]models.DataPoint, []string, error) {
 	var dataPoints []models.DataPoint
-	
-	// Get blocks of data for this object ID
-	rawDataBlocks, err := storage.Get(objectID)
+
+	warnings, err := readBlocks(ctx, storage, counterPath, objectID, fromTime, toTime, counterID, 0, 0, func(offset int64, points []models.DataPoint) bool {
+		dataPoints = append(dataPoints, points...)
+		return true
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get data blocks: %v", err)
+		return nil, nil, err
 	}
-	
-	if len(rawDataBlocks) == 0 {
-		return dataPoints, nil // No data for this object ID
+
+	return dataPoints, warnings, nil
+}
+
+// readBlocks decodes the blocks of an object from the block at startOffset
+// on, passing the points of each to visit until it returns false. The blocks
+// are viewed batchBlocks at a time, all at once for 0, so that a read
+// stopping early does not view the blocks it never reaches.
+func readBlocks(ctx context.Context, storage *storageEngine.StorageEngine, counterPath string, objectID int, fromTime uint32, toTime uint32, counterID uint16, startOffset int64, batchBlocks int, visit func(offset int64, points []models.DataPoint) bool) ([]string, error) {
+	var warnings []string
+
+	for {
+		// Get the blocks of this object ID that overlap the query window
+		blockViews, err := storage.GetRangeByPathFrom(ctx, objectID, counterPath, fromTime, toTime, startOffset, batchBlocks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get data blocks: %v", err)
+		}
+
+		if len(blockViews) == 0 {
+			return warnings, nil // No more data for this object ID
+		}
+
+		blockWarnings, more, err := decodeBlocks(ctx, blockViews, counterPath, objectID, fromTime, toTime, counterID, visit)
+		// the decoded points are copies, the blocks need not outlive this read
+		storageEngine.ReleaseViews(blockViews)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, blockWarnings...)
+
+		if !more || batchBlocks == 0 || len(blockViews) < batchBlocks {
+			return warnings, nil
+		}
+		startOffset = blockViews[len(blockViews)-1].Offset + 1
 	}
-	
+}
+
+// decodeBlocks passes the points of each block to visit. Blocks that are
+// corrupt or cannot be decoded are skipped and reported as warnings. It
+// returns false once visit does or ctx is done.
+func decodeBlocks(ctx context.Context, blockViews []storageEngine.BlockView, counterPath string, objectID int, fromTime uint32, toTime uint32, counterID uint16, visit func(offset int64, points []models.DataPoint) bool) ([]string, bool, error) {
+	var warnings []string
+
+	statsFrom(ctx).addBlocks(len(blockViews))
+
 	// Expected data type for this counter
 	expectedType, err := utils.GetCounterType(counterID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get counter type: %v", err)
+		return nil, false, fmt.Errorf("failed to get counter type: %v", err)
 	}
-	
+
 	// Process each block of data
-	for _, blockData := range rawDataBlocks {
-		// Process this block only if it contains data
-		if len(blockData) > 0 {
-			// Deserialize data points from this block
-			points, err := deserializeDataBlock(blockData, fromTime, toTime, expectedType)
-			if err != nil {
-				log.Printf("Error deserializing block for ObjectID %d: %v", objectID, err)
-				continue
-			}
-			
-			dataPoints = append(dataPoints, points...)
+	for _, view := range blockViews {
+		if ctx.Err() != nil {
+			return warnings, false, nil
+		}
+
+		if view.Corrupt {
+			warnings = append(warnings, fmt.Sprintf("block %d of %s failed its checksum and was skipped", view.Offset, counterPath))
+			continue
+		}
+
+		// Deserialize data points from this block
+		points, err := decodeBlockView(view, fromTime, toTime, expectedType)
+		if err != nil {
+			log.Printf("Error deserializing block for ObjectID %d: %v", objectID, err)
+			warnings = append(warnings, fmt.Sprintf("block %d of %s could not be decoded: %v", view.Offset, counterPath, err))
+		}
+		statsFrom(ctx).addPoints(len(points))
+
+		if !visit(view.Offset, points) {
+			return warnings, false, nil
 		}
 	}
-	
-	return dataPoints, nil
+
+	return warnings, true, nil
+}
+
+// decodeBlockView extracts the data points of a block in whichever encoding it uses
+func decodeBlockView(view storageEngine.BlockView, fromTime uint32, toTime uint32, dataType byte) ([]models.DataPoint, error) {
+	switch view.Header.Encoding() {
+	case storageEngine.EncodingGorilla:
+		return deserializeGorillaBlock(view.Data, view.Header.RecordCount, fromTime, toTime, dataType)
+	default:
+		return deserializeDataBlock(view.Data, view.Header.RecordCount, fromTime, toTime, dataType, view.Dictionary)
+	}
 }
 
 // deserializeDataBlock extracts data points from a block of data
-func deserializeDataBlock(blockData []byte, fromTime uint32, toTime uint32, dataType byte) ([]models.DataPoint, error) {
+// Dictionary encoded string blocks are passed with their dictionary; their
+// records hold a 4 byte string ID instead of the length-prefixed string.
+func deserializeDataBlock(blockData []byte, recordCount uint32, fromTime uint32, toTime uint32, dataType byte, dictionary *storageEngine.StringDictionary) ([]models.DataPoint, error) {
 	var dataPoints []models.DataPoint
 	
 	// Process data starting from offset 0 (header is not included in the data)
 	offset := 0
 	
-	// Continue until we reach the end of the data block
-	for offset < len(blockData) {
+	// Only the records counted by the header are complete, a writer may be
+	// appending to the block behind them
+	for record := uint32(0); record < recordCount && offset < len(blockData); record++ {
 		// Need at least 4 bytes for timestamp
 		if offset+4 > len(blockData) {
 			break
 		}
-		
+
 		// Read timestamp (first 4 bytes of each record)
 		timestamp := binary.LittleEndian.Uint32(blockData[offset:offset+4])
 		offset += 4
-		
+
 		// Skip records outside the requested time range
 		if timestamp < fromTime || timestamp > toTime {
 			// Still need to advance the offset based on data type
@@ -150,6 +459,10 @@ func deserializeDataBlock(blockData []byte, fromTime uint32, toTime uint32, data
 			case utils.TypeFloat:
 				offset += 8 // float64 size
 			case utils.TypeString:
+				if dictionary != nil {
+					offset += 4 // string ID
+					break
+				}
 				// String format: 4 bytes length + string data
 				if offset+4 > len(blockData) {
 					return dataPoints, fmt.Errorf("invalid string format: insufficient data for length")
@@ -161,26 +474,26 @@ func deserializeDataBlock(blockData []byte, fromTime uint32, toTime uint32, data
 			}
 			continue
 		}
-		
+
 		// Read the actual value based on data type
 		var value interface{}
 		var valueErr error
-		
+
 		switch dataType {
 		case utils.TypeInt:
 			value, offset, valueErr = readIntValue(blockData, offset)
 		case utils.TypeFloat:
 			value, offset, valueErr = readFloatValue(blockData, offset)
 		case utils.TypeString:
-			value, offset, valueErr = readStringValue(blockData, offset)
+			value, offset, valueErr = readStringValue(blockData, offset, dictionary)
 		default:
 			return dataPoints, fmt.Errorf("unknown data type: %d", dataType)
 		}
-		
+
 		if valueErr != nil {
 			return dataPoints, valueErr
 		}
-		
+
 		// Add the data point
 		dataPoints = append(dataPoints, models.DataPoint{
 			Timestamp: timestamp,
@@ -193,6 +506,46 @@ func deserializeDataBlock(blockData []byte, fromTime uint32, toTime uint32, data
 
 // Helper functions to read different data types
 
+// deserializeGorillaBlock extracts data points from a Gorilla encoded block
+func deserializeGorillaBlock(blockData []byte, recordCount uint32, fromTime uint32, toTime uint32, dataType byte) ([]models.DataPoint, error) {
+	var dataPoints []models.DataPoint
+	
+	decoder := storageEngine.NewGorillaDecoder(blockData, recordCount)
+	
+	for {
+		timestamp, bits, err := decoder.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return dataPoints, err
+		}
+
+		// Skip records outside the requested time range
+		if timestamp < fromTime || timestamp > toTime {
+			continue
+		}
+
+		var value interface{}
+
+		switch dataType {
+		case utils.TypeInt:
+			value = int64(bits)
+		case utils.TypeFloat:
+			value = math.Float64frombits(bits)
+		default:
+			return dataPoints, fmt.Errorf("unexpected data type %d in gorilla block", dataType)
+		}
+
+		dataPoints = append(dataPoints, models.DataPoint{
+			Timestamp: timestamp,
+			Value:     value,
+		})
+	}
+	
+	return dataPoints, nil
+}
+
 func readIntValue(data []byte, offset int) (interface{}, int, error) {
 	if offset+8 > len(data) {
 		return nil, offset, fmt.Errorf("invalid int format: insufficient data")
@@ -211,7 +564,21 @@ func readFloatValue(data []byte, offset int) (interface{}, int, error) {
 	return value, offset + 8, nil
 }
 
-func readStringValue(data []byte, offset int) (interface{}, int, error) {
+func readStringValue(data []byte, offset int, dictionary *storageEngine.StringDictionary) (interface{}, int, error) {
+	if dictionary != nil {
+		// Dictionary format: 4 bytes string ID
+		if offset+4 > len(data) {
+			return nil, offset, fmt.Errorf("invalid string format: insufficient data for string ID")
+		}
+
+		id := binary.LittleEndian.Uint32(data[offset:offset+4])
+		value, found := dictionary.Lookup(id)
+		if !found {
+			return nil, offset, fmt.Errorf("string ID %d is not in the dictionary", id)
+		}
+		return value, offset + 4, nil
+	}
+	
 	// String format: 4 bytes length + string data
 	if offset+4 > len(data) {
 		return nil, offset, fmt.Errorf("invalid string format: insufficient data for length")
@@ -228,84 +595,26 @@ func readStringValue(data []byte, offset int) (interface{}, int, error) {
 	return value, offset + int(strLen), nil
 }
 
-func aggregateDataPoints(points []models.DataPoint, aggregation string) []models.DataPoint {
+// aggregateDataPoints reduces points to one point stamped with the latest
+// timestamp, using the registered aggregator
+func aggregateDataPoints(points []models.DataPoint, aggregation string, window Window) []models.DataPoint {
 	if len(points) == 0 {
 		return points
 	}
 
-	// Use the latest timestamp for the aggregated result
-	timestamp := points[len(points)-1].Timestamp
-
-	switch aggregation {
-	case "avg":
-		sum := 0.0
-		count := 0
-		for _, p := range points {
-			if val, ok := p.Value.(float64); ok {
-				sum += val
-				count++
-			} else if intVal, ok := p.Value.(int64); ok {
-				sum += float64(intVal)
-				count++
-			}
-		}
-		if count > 0 {
-			return []models.DataPoint{{
-				Timestamp: timestamp,
-				Value:     sum / float64(count),
-			}}
-		}
+	entry, exists := lookupAggregator(aggregation)
+	if !exists {
+		return nil
+	}
 
-	case "sum":
-		sum := 0.0
-		for _, p := range points {
-			if val, ok := p.Value.(float64); ok {
-				sum += val
-			} else if intVal, ok := p.Value.(int64); ok {
-				sum += float64(intVal)
-			}
-		}
-		return []models.DataPoint{{
-			Timestamp: timestamp,
-			Value:     sum,
-		}}
-
-	case "max":
-		max := math.Inf(-1)
-		for _, p := range points {
-			var val float64
-			if floatVal, ok := p.Value.(float64); ok {
-				val = floatVal
-			} else if intVal, ok := p.Value.(int64); ok {
-				val = float64(intVal)
-			} else {
-				continue
-			}
-			max = math.Max(max, val)
-		}
-		return []models.DataPoint{{
-			Timestamp: timestamp,
-			Value:     max,
-		}}
-
-	case "min":
-		min := math.Inf(1)
-		for _, p := range points {
-			var val float64
-			if floatVal, ok := p.Value.(float64); ok {
-				val = floatVal
-			} else if intVal, ok := p.Value.(int64); ok {
-				val = float64(intVal)
-			} else {
-				continue
-			}
-			min = math.Min(min, val)
-		}
-		return []models.DataPoint{{
-			Timestamp: timestamp,
-			Value:     min,
-		}}
+	value, ok := entry.aggregate(points, window)
+	if !ok {
+		return nil
 	}
 
-	return points
+	// Use the latest timestamp for the aggregated result
+	return []models.DataPoint{{
+		Timestamp: points[len(points)-1].Timestamp,
+		Value:     value,
+	}}
 }
@@ -0,0 +1,145 @@
+package reader
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"log"
+	"math"
+	"os"
+	"packx/models"
+	"packx/storageEngine"
+	"packx/utils"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestMain loads a config storing under a temporary directory. The config is
+// loaded once per process, so every test shares it.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "reader-test")
+	if err != nil {
+		log.Fatalf("creating config directory: %v", err)
+	}
+
+	configJSON := `{"storage_path": "` + filepath.Join(dir, "storage") + `"}`
+	countersJSON := `{"1": {"name": "requests", "type": "int"}, "2": {"name": "load", "type": "float"}}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(configJSON), 0644); err != nil {
+		log.Fatalf("writing config.json: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "counters.json"), []byte(countersJSON), 0644); err != nil {
+		log.Fatalf("writing counters.json: %v", err)
+	}
+
+	utils.SetConfigDir(dir)
+	if err := utils.LoadConfig(); err != nil {
+		log.Fatalf("LoadConfig: %v", err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// openTestStorage opens the engine on the configured storage path
+func openTestStorage(t *testing.T) *storageEngine.StorageEngine {
+	t.Helper()
+
+	storage, err := storageEngine.NewStorageEngine()
+	if err != nil {
+		t.Fatalf("NewStorageEngine: %v", err)
+	}
+	t.Cleanup(func() { storage.Close() })
+	return storage
+}
+
+func floatRecord(timestamp uint32, value float64) []byte {
+	buf := make([]byte, 12)
+	binary.LittleEndian.PutUint32(buf[0:4], timestamp)
+	binary.LittleEndian.PutUint64(buf[4:12], math.Float64bits(value))
+	return buf
+}
+
+// putFloats writes points of counter 2 of an object to the day of day
+func putFloats(t *testing.T, storage *storageEngine.StorageEngine, day time.Time, objectID int, timestamps []uint32, values []float64) {
+	t.Helper()
+
+	var records [][]byte
+	for i, timestamp := range timestamps {
+		records = append(records, floatRecord(timestamp, values[i]))
+	}
+	if err := storage.PutBatch(counterDayPath(day, 2), objectID, utils.TypeFloat, records); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+}
+
+// putInts writes points of counter 1 of an object to the day of day
+func putInts(t *testing.T, storage *storageEngine.StorageEngine, day time.Time, objectID int, timestamps []uint32, values []int64) {
+	t.Helper()
+
+	var records [][]byte
+	for i, timestamp := range timestamps {
+		buf := make([]byte, 12)
+		binary.LittleEndian.PutUint32(buf[0:4], timestamp)
+		binary.LittleEndian.PutUint64(buf[4:12], uint64(values[i]))
+		records = append(records, buf)
+	}
+	if err := storage.PutBatch(counterDayPath(day, 1), objectID, utils.TypeInt, records); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+}
+
+// answer runs a query to completion and returns its only response
+func answer(storage *storageEngine.StorageEngine, query models.Query) models.QueryResponse {
+	responses := make(chan models.QueryResponse, 1)
+	answerQuery(context.Background(), storage, query, responses)
+	return <-responses
+}
+
+func TestAnswerQueryOfSeveralCounters(t *testing.T) {
+	storage := openTestStorage(t)
+
+	day := time.Date(2025, 3, 14, 0, 0, 0, 0, time.Local)
+	from := uint32(day.Unix())
+	timestamps := []uint32{from, from + 60, from + 120}
+	putFloats(t, storage, day, 5, timestamps, []float64{1.5, 2.5, 3.5})
+	putInts(t, storage, day, 5, timestamps, []int64{10, 20, 30})
+
+	// each counter's points are keyed by object, then counter
+	query := models.Query{QueryID: 1, From: from, To: from + 3600, ObjectIDs: []uint32{5}, CounterIDs: []uint16{2, 1}}
+	response := answer(storage, query)
+	if response.Status != models.StatusOK || len(response.Data) != 0 {
+		t.Fatalf("unexpected response %+v", response)
+	}
+	loads, requests := response.CounterData[5][2], response.CounterData[5][1]
+	if len(loads) != 3 || loads[2].Value != 3.5 || len(requests) != 3 || requests[2].Value != int64(30) {
+		t.Fatalf("unexpected counter data %+v", response.CounterData)
+	}
+
+	// CounterIDs take precedence over CounterId
+	query.CounterId = 1
+	query.CounterIDs = []uint16{2}
+	response = answer(storage, query)
+	if len(response.CounterData[5]) != 1 || len(response.CounterData[5][2]) != 3 {
+		t.Fatalf("expected only counter 2, got %+v", response.CounterData)
+	}
+
+	// a query by CounterId keeps the single counter shape
+	query.CounterIDs = nil
+	response = answer(storage, query)
+	if response.CounterData != nil || len(response.Data[5]) != 3 || response.Data[5][0].Value != int64(10) {
+		t.Fatalf("unexpected single counter response %+v", response)
+	}
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(responseBytes, &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if _, found := fields["counter_data"]; found {
+		t.Fatalf("single counter response carries counter_data: %s", responseBytes)
+	}
+}
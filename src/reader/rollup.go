@@ -0,0 +1,429 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"packx/models"
+	"packx/storageEngine"
+	"packx/utils"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// RollupResolutions are the rollup intervals in seconds, coarsest first
+var RollupResolutions = []uint32{3600, 300, 60}
+
+// aggregateState folds raw points and rollup intervals into the aggregations
+// rollups can answer
+type aggregateState struct {
+	count uint64
+
+	sum float64
+
+	min float64
+
+	max float64
+
+	last uint32
+}
+
+func newAggregateState() *aggregateState {
+	return &aggregateState{min: math.Inf(1), max: math.Inf(-1)}
+}
+
+func (s *aggregateState) addValue(timestamp uint32, value float64) {
+	s.count++
+	s.sum += value
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+	if timestamp > s.last {
+		s.last = timestamp
+	}
+}
+
+func (s *aggregateState) addRollup(point storageEngine.RollupPoint) {
+	if point.Count == 0 {
+		return
+	}
+	s.count += uint64(point.Count)
+	s.sum += point.Sum
+	s.min = math.Min(s.min, point.Min)
+	s.max = math.Max(s.max, point.Max)
+	if point.Last > s.last {
+		s.last = point.Last
+	}
+}
+
+func (s *aggregateState) value(aggregation string) float64 {
+	switch aggregation {
+	case "avg":
+		return s.sum / float64(s.count)
+	case "sum":
+		return s.sum
+	case "min":
+		return s.min
+	case "max":
+		return s.max
+	case "count":
+		return float64(s.count)
+	}
+	return 0
+}
+
+// aggregateBuckets splits the range of a query into interval aligned buckets,
+// each with its own aggregateState. With a zero interval everything falls into
+// a single bucket stamped with the latest timestamp, as before intervals existed.
+type aggregateBuckets struct {
+	interval uint32
+
+	states map[uint32]*aggregateState
+}
+
+func newAggregateBuckets(interval uint32) *aggregateBuckets {
+	return &aggregateBuckets{interval: interval, states: make(map[uint32]*aggregateState)}
+}
+
+// bucketStart returns the start of the interval bucket holding timestamp
+func bucketStart(timestamp uint32, interval uint32) uint32 {
+	if interval == 0 {
+		return 0
+	}
+	return timestamp - timestamp%interval
+}
+
+func (b *aggregateBuckets) bucket(timestamp uint32) *aggregateState {
+	start := bucketStart(timestamp, b.interval)
+	state, exists := b.states[start]
+	if !exists {
+		state = newAggregateState()
+		b.states[start] = state
+	}
+	return state
+}
+
+func (b *aggregateBuckets) addPoints(points []models.DataPoint) {
+	for _, p := range points {
+		if value, ok := numericValue(p.Value); ok {
+			b.bucket(p.Timestamp).addValue(p.Timestamp, value)
+		}
+	}
+}
+
+func (b *aggregateBuckets) addRollup(point storageEngine.RollupPoint) {
+	// the rollup resolution divides the interval, so the whole rollup
+	// interval lies in one bucket
+	b.bucket(point.Start).addRollup(point)
+}
+
+// result returns one point per non-empty bucket in time order
+func (b *aggregateBuckets) result(aggregation string) []models.DataPoint {
+	starts := make([]uint32, 0, len(b.states))
+	for start, state := range b.states {
+		if state.count > 0 {
+			starts = append(starts, start)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	var points []models.DataPoint
+	for _, start := range starts {
+		state := b.states[start]
+		timestamp := start
+		if b.interval == 0 {
+			timestamp = state.last
+		}
+		points = append(points, models.DataPoint{Timestamp: timestamp, Value: state.value(aggregation)})
+	}
+	return points
+}
+
+// bucketDataPoints applies aggregateDataPoints to every interval bucket of
+// points, stamping each aggregated point with the start of its bucket. Each
+// bucket is aggregated over its part of window.
+func bucketDataPoints(points []models.DataPoint, aggregation string, interval uint32, window Window) []models.DataPoint {
+	buckets := make(map[uint32][]models.DataPoint)
+	var starts []uint32
+	for _, p := range points {
+		start := bucketStart(p.Timestamp, interval)
+		if _, exists := buckets[start]; !exists {
+			starts = append(starts, start)
+		}
+		buckets[start] = append(buckets[start], p)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	var result []models.DataPoint
+	for _, start := range starts {
+		bucketWindow := Window{From: start, To: start + interval - 1}
+		if bucketWindow.From < window.From {
+			bucketWindow.From = window.From
+		}
+		if bucketWindow.To < start || bucketWindow.To > window.To {
+			bucketWindow.To = window.To // also when the bucket end overflows
+		}
+
+		aggregated := aggregateDataPoints(buckets[start], aggregation, bucketWindow)
+		if len(aggregated) == 1 {
+			aggregated[0].Timestamp = start
+		}
+		result = append(result, aggregated...)
+	}
+	return result
+}
+
+// rollupAggregation reports whether rollups can answer the aggregation
+func rollupAggregation(aggregation string) bool {
+	switch aggregation {
+	case "avg", "sum", "min", "max", "count":
+		return true
+	}
+	return false
+}
+
+func numericCounter(counterID uint16) bool {
+	dataType, err := utils.GetCounterType(counterID)
+	return err == nil && (dataType == utils.TypeInt || dataType == utils.TypeFloat)
+}
+
+func numericValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// queryDays returns midnight of every day the range [from, to] touches
+func queryDays(from uint32, to uint32) []time.Time {
+	start := time.Unix(int64(from), 0)
+	end := time.Unix(int64(to), 0)
+
+	var days []time.Time
+	for d := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local); !d.After(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, d)
+	}
+	return days
+}
+
+func counterDayPath(day time.Time, counterID uint16) string {
+	return filepath.Join(utils.GetStoragePath(), day.Format("2006/01/02"), fmt.Sprintf("counter_%d", counterID))
+}
+
+// chooseRollupResolution picks the coarsest resolution whose intervals do not
+// straddle the query bounds inside the day [dayStart, dayEnd] and, for bucketed
+// queries, divides the bucket interval
+func chooseRollupResolution(from uint32, to uint32, dayStart uint32, dayEnd uint32, interval uint32) (uint32, bool) {
+	for _, resolution := range RollupResolutions {
+		if interval != 0 && interval%resolution != 0 {
+			continue
+		}
+		fromAligned := from <= dayStart || from%resolution == 0
+		toAligned := to >= dayEnd || (to+1)%resolution == 0
+		if fromAligned && toAligned {
+			return resolution, true
+		}
+	}
+	return 0, false
+}
+
+// rollupVersions caches the raw data version of each counter day for one query
+type rollupVersions map[string]uint64
+
+// addRollupDay folds the rollup of one counter day into buckets. It reports
+// false, leaving buckets untouched, when the day has no current rollup at a
+// resolution that fits the query, so the caller falls back to raw points.
+func addRollupDay(ctx context.Context, storage *storageEngine.StorageEngine, buckets *aggregateBuckets, versions rollupVersions, counterPath string, objectID uint32, from uint32, to uint32, day time.Time) bool {
+	dayStart := uint32(day.Unix())
+	dayEnd := uint32(day.AddDate(0, 0, 1).Unix()) - 1
+
+	resolution, ok := chooseRollupResolution(from, to, dayStart, dayEnd, buckets.interval)
+	if !ok {
+		return false
+	}
+
+	points, sourceVersion, err := storage.ReadRollup(counterPath, resolution, objectID)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading rollup of %s: %v", counterPath, err)
+		}
+		return false
+	}
+
+	current, cached := versions[counterPath]
+	if !cached {
+		if current, err = storage.CounterDayVersion(counterPath); err != nil {
+			log.Printf("Error reading version of %s: %v", counterPath, err)
+			return false
+		}
+		versions[counterPath] = current
+	}
+
+	if sourceVersion != current {
+		return false // raw data changed since the rollup was built
+	}
+
+	statsFrom(ctx).addRollupPoints(len(points))
+
+	for _, point := range points {
+		// intervals at the day's edges only hold that day's records
+		start := point.Start
+		if start < dayStart {
+			start = dayStart
+		}
+		end := point.Start + resolution - 1
+		if end > dayEnd {
+			end = dayEnd
+		}
+
+		if start >= from && end <= to {
+			buckets.addRollup(point)
+		}
+	}
+
+	return true
+}
+
+// aggregateWithRollups answers an avg/sum/min/max/count query for one object,
+// reading rollups for the days that have current ones and raw points otherwise
+func aggregateWithRollups(ctx context.Context, storage *storageEngine.StorageEngine, query models.Query, objectID uint32, versions rollupVersions) ([]models.DataPoint, []string) {
+	buckets := newAggregateBuckets(query.Interval)
+	var allWarnings []string
+
+	for _, day := range queryDays(query.From, query.To) {
+		if ctx.Err() != nil {
+			break
+		}
+
+		counterPath := counterDayPath(day, query.CounterId)
+
+		if addRollupDay(ctx, storage, buckets, versions, counterPath, objectID, query.From, query.To, day) {
+			continue
+		}
+
+		points, warnings, err := readDataForObject(ctx, storage, counterPath, int(objectID), query.From, query.To, query.CounterId)
+		allWarnings = append(allWarnings, warnings...)
+		if err != nil {
+			log.Printf("Error reading data for ObjectID %d on %s: %v", objectID, day.Format("2006/01/02"), err)
+			if ctx.Err() == nil {
+				allWarnings = append(allWarnings, fmt.Sprintf("data of %s could not be read: %v", day.Format("2006/01/02"), err))
+			}
+			continue
+		}
+
+		buckets.addPoints(points)
+	}
+
+	return buckets.result(query.Aggregation), allWarnings
+}
+
+// BuildRollups computes the rollups of one counter day at every resolution.
+// Only numeric counters are rolled up.
+func BuildRollups(storage *storageEngine.StorageEngine, counterPath string, counterID uint16) error {
+	if !numericCounter(counterID) {
+		return fmt.Errorf("counter %d is not numeric", counterID)
+	}
+
+	// taken first, so that records written while building make the rollup stale
+	sourceVersion, err := storage.CounterDayVersion(counterPath)
+	if err != nil {
+		return err
+	}
+
+	deviceIDs, err := storage.DeviceIDs(counterPath)
+	if err != nil {
+		return err
+	}
+
+	rollups := make([]map[uint32][]storageEngine.RollupPoint, len(RollupResolutions))
+	for i := range rollups {
+		rollups[i] = make(map[uint32][]storageEngine.RollupPoint)
+	}
+
+	for _, deviceID := range deviceIDs {
+		points, warnings, err := readDataForObject(context.Background(), storage, counterPath, int(deviceID), 0, math.MaxUint32, counterID)
+		if err != nil {
+			return err
+		}
+		for _, warning := range warnings {
+			log.Printf("Rollup of %s: %s", counterPath, warning)
+		}
+
+		for i, resolution := range RollupResolutions {
+			rollups[i][deviceID] = rollupPoints(points, resolution)
+		}
+	}
+
+	for i, resolution := range RollupResolutions {
+		if err := storage.WriteRollup(counterPath, resolution, sourceVersion, rollups[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RollupsCurrent reports whether every rollup of a counter day matches its raw data
+func RollupsCurrent(storage *storageEngine.StorageEngine, counterPath string) (bool, error) {
+	current, err := storage.CounterDayVersion(counterPath)
+	if err != nil {
+		return false, err
+	}
+
+	for _, resolution := range RollupResolutions {
+		sourceVersion, err := storage.RollupSourceVersion(counterPath, resolution)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if sourceVersion != current {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// rollupPoints summarizes points into intervals of resolution seconds
+func rollupPoints(points []models.DataPoint, resolution uint32) []storageEngine.RollupPoint {
+	intervals := make(map[uint32]*storageEngine.RollupPoint)
+	var starts []uint32
+
+	for _, p := range points {
+		value, ok := numericValue(p.Value)
+		if !ok {
+			continue
+		}
+
+		start := p.Timestamp - p.Timestamp%resolution
+		interval, exists := intervals[start]
+		if !exists {
+			interval = &storageEngine.RollupPoint{Start: start, Min: value, Max: value}
+			intervals[start] = interval
+			starts = append(starts, start)
+		}
+
+		interval.Count++
+		interval.Sum += value
+		interval.Min = math.Min(interval.Min, value)
+		interval.Max = math.Max(interval.Max, value)
+		if p.Timestamp > interval.Last {
+			interval.Last = p.Timestamp
+		}
+	}
+
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	result := make([]storageEngine.RollupPoint, 0, len(starts))
+	for _, start := range starts {
+		result = append(result, *intervals[start])
+	}
+	return result
+}
@@ -0,0 +1,170 @@
+package reader
+
+import (
+	"math"
+	"packx/models"
+	"packx/storageEngine"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestBucketDataPoints(t *testing.T) {
+	// out of order, either side of the 60 and 120 boundaries, and nothing
+	// between 180 and 240
+	points := []models.DataPoint{
+		{Timestamp: 61, Value: 3.0},
+		{Timestamp: 59, Value: 1.0},
+		{Timestamp: 60, Value: 2.0},
+		{Timestamp: 119, Value: int64(4)},
+		{Timestamp: 120, Value: 5.0},
+		{Timestamp: 250, Value: 6.0},
+	}
+
+	sums := bucketDataPoints(points, "sum", 60, Window{From: 0, To: 600})
+	want := []models.DataPoint{{Timestamp: 0, Value: 1.0}, {Timestamp: 60, Value: 9.0}, {Timestamp: 120, Value: 5.0}, {Timestamp: 240, Value: 6.0}}
+	if !reflect.DeepEqual(sums, want) {
+		t.Fatalf("unexpected sums %+v", sums)
+	}
+
+	maxes := bucketDataPoints(points, "max", 120, Window{From: 0, To: 600})
+	want = []models.DataPoint{{Timestamp: 0, Value: 4.0}, {Timestamp: 120, Value: 5.0}, {Timestamp: 240, Value: 6.0}}
+	if !reflect.DeepEqual(maxes, want) {
+		t.Fatalf("unexpected maxes %+v", maxes)
+	}
+
+	if empty := bucketDataPoints(nil, "avg", 60, Window{From: 0, To: 600}); len(empty) != 0 {
+		t.Fatalf("expected no buckets without points, got %+v", empty)
+	}
+
+	// the bucket holding the last timestamp does not wrap around
+	last := bucketDataPoints([]models.DataPoint{{Timestamp: math.MaxUint32, Value: 1.0}}, "sum", 60, Window{From: 0, To: math.MaxUint32})
+	if len(last) != 1 || last[0].Timestamp != math.MaxUint32-math.MaxUint32%60 {
+		t.Fatalf("unexpected last bucket %+v", last)
+	}
+
+	// each bucket aggregates over its part of the window, so the states end
+	// with the bucket or the window, whichever comes first
+	states := []models.DataPoint{{Timestamp: 100, Value: "up"}, {Timestamp: 110, Value: "down"}, {Timestamp: 125, Value: "up"}}
+	durations := bucketDataPoints(states, "state_duration", 60, Window{From: 90, To: 129})
+	want = []models.DataPoint{
+		{Timestamp: 60, Value: map[string]uint64{"up": 10, "down": 10}},
+		{Timestamp: 120, Value: map[string]uint64{"up": 5}},
+	}
+	if !reflect.DeepEqual(durations, want) {
+		t.Fatalf("unexpected state durations %+v", durations)
+	}
+}
+
+func TestAggregateBucketsFoldRollups(t *testing.T) {
+	buckets := newAggregateBuckets(300)
+	buckets.addRollup(storageEngine.RollupPoint{Start: 60, Last: 110, Count: 2, Min: 4, Max: 6, Sum: 10})
+	buckets.addRollup(storageEngine.RollupPoint{Start: 240, Last: 250, Count: 1, Min: 1, Max: 1, Sum: 1})
+	buckets.addPoints([]models.DataPoint{{Timestamp: 310, Value: 7.0}, {Timestamp: 320, Value: "down"}})
+
+	avgs := buckets.result("avg")
+	want := []models.DataPoint{{Timestamp: 0, Value: 11.0 / 3}, {Timestamp: 300, Value: 7.0}}
+	if !reflect.DeepEqual(avgs, want) {
+		t.Fatalf("unexpected averages %+v", avgs)
+	}
+
+	// without an interval there is one point, stamped with the latest timestamp
+	single := newAggregateBuckets(0)
+	single.addRollup(storageEngine.RollupPoint{Start: 60, Last: 110, Count: 2, Min: 4, Max: 6, Sum: 10})
+	single.addPoints([]models.DataPoint{{Timestamp: 310, Value: 7.0}})
+	if got := single.result("max"); !reflect.DeepEqual(got, []models.DataPoint{{Timestamp: 310, Value: 7.0}}) {
+		t.Fatalf("unexpected result without an interval %+v", got)
+	}
+}
+
+func TestChooseRollupResolutionDividesInterval(t *testing.T) {
+	dayStart := uint32(864000)
+	dayEnd := dayStart + 86399
+
+	testCases := []struct {
+		interval   uint32
+		resolution uint32
+		ok         bool
+	}{
+		{interval: 0, resolution: 3600, ok: true},
+		{interval: 86400, resolution: 3600, ok: true},
+		{interval: 600, resolution: 300, ok: true},
+		{interval: 120, resolution: 60, ok: true},
+		{interval: 90, ok: false},
+	}
+
+	for _, tc := range testCases {
+		resolution, ok := chooseRollupResolution(dayStart, dayEnd, dayStart, dayEnd, tc.interval)
+		if resolution != tc.resolution || ok != tc.ok {
+			t.Fatalf("interval %d: got resolution %d (%v), want %d (%v)", tc.interval, resolution, ok, tc.resolution, tc.ok)
+		}
+	}
+}
+
+func TestRollupAnswersMatchRawData(t *testing.T) {
+	storage := openTestStorage(t)
+
+	day := time.Date(2025, 3, 20, 0, 0, 0, 0, time.Local)
+	dayStart := uint32(day.Unix())
+	dayEnd := uint32(day.AddDate(0, 0, 1).Unix()) - 1
+
+	// whole values, so sums do not depend on the order they are added in
+	var timestamps []uint32
+	var values []float64
+	for i := 0; dayStart+uint32(i*37) <= dayEnd; i++ {
+		timestamps = append(timestamps, dayStart+uint32(i*37))
+		values = append(values, float64(i%17))
+	}
+	putFloats(t, storage, day, 6, timestamps, values)
+
+	aligned := models.Query{QueryID: 1, From: dayStart, To: dayEnd, ObjectIDs: []uint32{6}, CounterId: 2, Interval: 3600}
+	misaligned := aligned
+	misaligned.From = dayStart + 30
+
+	aggregations := []string{"sum", "avg", "min", "max", "count"}
+	raw := make(map[string][2]models.QueryResponse)
+	for _, aggregation := range aggregations {
+		aligned.Aggregation, misaligned.Aggregation = aggregation, aggregation
+		raw[aggregation] = [2]models.QueryResponse{answer(storage, aligned), answer(storage, misaligned)}
+	}
+
+	if err := BuildRollups(storage, counterDayPath(day, 2), 2); err != nil {
+		t.Fatalf("BuildRollups: %v", err)
+	}
+
+	for _, aggregation := range aggregations {
+		aligned.Aggregation, misaligned.Aggregation = aggregation, aggregation
+
+		// a window on interval boundaries is answered from the rollups
+		response := answer(storage, aligned)
+		if response.Stats.RollupPointsRead == 0 || response.Stats.PointsRead != 0 {
+			t.Fatalf("%s: expected only rollups read, got %+v", aggregation, response.Stats)
+		}
+		if want := raw[aggregation][0].Data; !reflect.DeepEqual(response.Data, want) {
+			t.Fatalf("%s: rollup answer %+v, raw answer %+v", aggregation, response.Data, want)
+		}
+
+		// one starting inside an interval reads the raw points
+		response = answer(storage, misaligned)
+		if response.Stats.RollupPointsRead != 0 {
+			t.Fatalf("%s: misaligned window read %d rollup points", aggregation, response.Stats.RollupPointsRead)
+		}
+		if want := raw[aggregation][1].Data; !reflect.DeepEqual(response.Data, want) {
+			t.Fatalf("%s: misaligned answer %+v, raw answer %+v", aggregation, response.Data, want)
+		}
+	}
+
+	// a record written after the build changes the day's version, so the
+	// stale rollups are passed over for the raw points
+	putFloats(t, storage, day, 6, []uint32{dayStart + 5}, []float64{100})
+
+	aligned.Aggregation = "sum"
+	response := answer(storage, aligned)
+	if response.Stats.RollupPointsRead != 0 || response.Stats.PointsRead == 0 {
+		t.Fatalf("expected raw points read after the write, got %+v", response.Stats)
+	}
+	before, after := raw["sum"][0].Data[6][0].Value.(float64), response.Data[6][0].Value.(float64)
+	if after != before+100 {
+		t.Fatalf("expected the first hour to sum to %v, got %v", before+100, after)
+	}
+}
@@ -0,0 +1,122 @@
+package reader
+
+import (
+	"context"
+	"fmt"
+	"packx/models"
+	"sync/atomic"
+	"time"
+)
+
+// maxMissingDayWarnings bounds the days listed as missing for one counter
+const maxMissingDayWarnings = 10
+
+// queryError is the reason a query is rejected, with its error code
+type queryError struct {
+	code string
+
+	message string
+}
+
+func (e *queryError) Error() string {
+	return e.message
+}
+
+func rejectQuery(code string, format string, args ...interface{}) error {
+	return &queryError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// rejectResponse marks response as the answer to a rejected query. Errors
+// without a code are reported as invalid queries.
+func rejectResponse(response *models.QueryResponse, err error) {
+	response.Status = models.StatusError
+	response.ErrorCode = models.ErrorCodeInvalidQuery
+	if qe, ok := err.(*queryError); ok {
+		response.ErrorCode = qe.code
+	}
+	response.Error = err.Error()
+}
+
+// queryStats counts the work done for one query. Counters of a query are
+// read in parallel, so every field is updated atomically.
+type queryStats struct {
+	start time.Time
+
+	blocksScanned atomic.Uint64
+
+	pointsRead atomic.Uint64
+
+	rollupPointsRead atomic.Uint64
+}
+
+type queryStatsKey struct{}
+
+func withQueryStats(ctx context.Context) context.Context {
+	return context.WithValue(ctx, queryStatsKey{}, &queryStats{start: time.Now()})
+}
+
+// statsFrom returns the stats of the query ctx belongs to. Reads outside of
+// a query, such as building rollups, get nil, which counts nothing.
+func statsFrom(ctx context.Context) *queryStats {
+	stats, _ := ctx.Value(queryStatsKey{}).(*queryStats)
+	return stats
+}
+
+func (s *queryStats) addBlocks(n int) {
+	if s != nil {
+		s.blocksScanned.Add(uint64(n))
+	}
+}
+
+func (s *queryStats) addPoints(n int) {
+	if s != nil {
+		s.pointsRead.Add(uint64(n))
+	}
+}
+
+func (s *queryStats) addRollupPoints(n int) {
+	if s != nil {
+		s.rollupPointsRead.Add(uint64(n))
+	}
+}
+
+func (s *queryStats) snapshot() *models.QueryStats {
+	if s == nil {
+		return nil
+	}
+	return &models.QueryStats{
+		BlocksScanned:    s.blocksScanned.Load(),
+		PointsRead:       s.pointsRead.Load(),
+		RollupPointsRead: s.rollupPointsRead.Load(),
+		DurationMs:       time.Since(s.start).Milliseconds(),
+	}
+}
+
+// finishResponse sets the status and stats of a response about to be sent.
+// incomplete is set when part of the query could not be read for reasons not
+// tied to an object, such as an unknown counter.
+func finishResponse(ctx context.Context, response *models.QueryResponse, incomplete bool) {
+	response.Stats = statsFrom(ctx).snapshot()
+
+	switch {
+	case response.Error != "":
+		response.Status = models.StatusError
+	case interruptedStatus(ctx) != "":
+		response.Status = interruptedStatus(ctx)
+	case incomplete || len(response.ObjectWarnings) > 0:
+		response.Status = models.StatusPartial
+	default:
+		response.Status = models.StatusOK
+	}
+}
+
+// addObjectWarnings records the warnings about one object in response
+func addObjectWarnings(response *models.QueryResponse, objectID uint32, warnings []string) {
+	if len(warnings) == 0 {
+		return
+	}
+	if response.ObjectWarnings == nil {
+		response.ObjectWarnings = make(map[uint32][]string)
+	}
+	response.ObjectWarnings[objectID] = append(response.ObjectWarnings[objectID], warnings...)
+}
@@ -0,0 +1,91 @@
+package reader
+
+import (
+	"context"
+	"errors"
+	"packx/models"
+	"testing"
+	"time"
+)
+
+func TestFinishResponse(t *testing.T) {
+	ctx := withQueryStats(context.Background())
+	stats := statsFrom(ctx)
+	stats.addBlocks(3)
+	stats.addPoints(120)
+	stats.addRollupPoints(4)
+
+	response := models.QueryResponse{QueryID: 1}
+	finishResponse(ctx, &response, false)
+	if response.Status != models.StatusOK {
+		t.Fatalf("expected status ok, got %q", response.Status)
+	}
+	if response.Stats == nil || response.Stats.BlocksScanned != 3 || response.Stats.PointsRead != 120 || response.Stats.RollupPointsRead != 4 {
+		t.Fatalf("unexpected stats %+v", response.Stats)
+	}
+
+	// a warning about one object makes the answer partial
+	addObjectWarnings(&response, 7, []string{"block 4096 failed its checksum"})
+	addObjectWarnings(&response, 7, nil)
+	finishResponse(ctx, &response, false)
+	if response.Status != models.StatusPartial || len(response.ObjectWarnings[7]) != 1 {
+		t.Fatalf("expected a partial answer with one warning for object 7, got %q %v", response.Status, response.ObjectWarnings)
+	}
+
+	// as does a part of the query that could not be read at all
+	response = models.QueryResponse{QueryID: 2}
+	finishResponse(ctx, &response, true)
+	if response.Status != models.StatusPartial {
+		t.Fatalf("expected an incomplete answer to be partial, got %q", response.Status)
+	}
+
+	// reads outside a query have no stats
+	response = models.QueryResponse{QueryID: 3}
+	finishResponse(context.Background(), &response, false)
+	if response.Stats != nil {
+		t.Fatalf("expected no stats outside a query, got %+v", response.Stats)
+	}
+}
+
+func TestFinishResponseOfInterruptedQuery(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(withQueryStats(context.Background()))
+	cancel(errQueryCancelled)
+
+	// a cancel outranks the warnings of what was read before it
+	response := models.QueryResponse{QueryID: 1}
+	addObjectWarnings(&response, 7, []string{"block 4096 failed its checksum"})
+	finishResponse(ctx, &response, false)
+	if response.Status != models.StatusCancelled {
+		t.Fatalf("expected status cancelled, got %q", response.Status)
+	}
+
+	ctx, stop := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer stop()
+
+	response = models.QueryResponse{QueryID: 2}
+	finishResponse(ctx, &response, false)
+	if response.Status != models.StatusDeadlineExceeded {
+		t.Fatalf("expected status deadline_exceeded, got %q", response.Status)
+	}
+}
+
+func TestRejectResponse(t *testing.T) {
+	response := models.QueryResponse{QueryID: 1}
+	rejectResponse(&response, rejectQuery(models.ErrorCodeUnknownAggregation, "unknown aggregation %q", "p42"))
+	if response.Status != models.StatusError || response.ErrorCode != models.ErrorCodeUnknownAggregation || response.Error != `unknown aggregation "p42"` {
+		t.Fatalf("unexpected rejection %+v", response)
+	}
+
+	// errors without a code are invalid queries
+	response = models.QueryResponse{QueryID: 2}
+	rejectResponse(&response, errors.New("from is after to"))
+	if response.ErrorCode != models.ErrorCodeInvalidQuery {
+		t.Fatalf("expected error code %q, got %q", models.ErrorCodeInvalidQuery, response.ErrorCode)
+	}
+
+	// a rejected query stays rejected when finished
+	finishResponse(context.Background(), &response, false)
+	if response.Status != models.StatusError {
+		t.Fatalf("expected status error after finishing, got %q", response.Status)
+	}
+}
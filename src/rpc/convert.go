@@ -0,0 +1,332 @@
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative reportdb.proto
+
+import (
+	"fmt"
+	"math"
+	"packx/models"
+)
+
+// ErrorDomain is the domain of the ErrorInfo detail of the errors of
+// rejected queries; its reason is the models.ErrorCode
+const ErrorDomain = "reportdb"
+
+// ValueToProto converts a value of a metric or data point. nil, the value
+// of a bucket filled with null, converts to nil.
+func ValueToProto(value interface{}) (*Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case int:
+		return &Value{Kind: &Value_IntValue{IntValue: int64(v)}}, nil
+	case int32:
+		return &Value{Kind: &Value_IntValue{IntValue: int64(v)}}, nil
+	case int64:
+		return &Value{Kind: &Value_IntValue{IntValue: v}}, nil
+	case float32:
+		return &Value{Kind: &Value_FloatValue{FloatValue: float64(v)}}, nil
+	case float64:
+		return &Value{Kind: &Value_FloatValue{FloatValue: v}}, nil
+	case string:
+		return &Value{Kind: &Value_StringValue{StringValue: v}}, nil
+	case map[string]uint64:
+		return &Value{Kind: &Value_Counts{Counts: &Counts{Counts: v}}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported value type %T", value)
+	}
+}
+
+// ValueFromProto is the inverse of ValueToProto
+func ValueFromProto(value *Value) interface{} {
+	switch v := value.GetKind().(type) {
+	case *Value_IntValue:
+		return v.IntValue
+	case *Value_FloatValue:
+		return v.FloatValue
+	case *Value_StringValue:
+		return v.StringValue
+	case *Value_Counts:
+		return v.Counts.GetCounts()
+	default:
+		return nil
+	}
+}
+
+// MetricToProto fails for values of a type the schema has no place for
+func MetricToProto(metric models.Metric) (*Metric, error) {
+	value, err := ValueToProto(metric.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Metric{
+		ObjectId:  metric.ObjectID,
+		CounterId: uint32(metric.CounterId),
+		Value:     value,
+		Timestamp: metric.Timestamp,
+	}, nil
+}
+
+// MetricFromProto fails for a metric without a value or with a counter ID
+// out of range
+func MetricFromProto(metric *Metric) (models.Metric, error) {
+	if metric.GetValue().GetKind() == nil {
+		return models.Metric{}, fmt.Errorf("metric of object %d has no value", metric.GetObjectId())
+	}
+	if metric.GetCounterId() > 0xFFFF {
+		return models.Metric{}, fmt.Errorf("counter ID %d out of range", metric.GetCounterId())
+	}
+	return models.Metric{
+		ObjectID:  metric.GetObjectId(),
+		CounterId: uint16(metric.GetCounterId()),
+		Value:     ValueFromProto(metric.GetValue()),
+		Timestamp: metric.GetTimestamp(),
+	}, nil
+}
+
+// QueryToProto converts a query; Cancel has no counterpart, cancelling the
+// call cancels the query
+func QueryToProto(query models.Query) *QueryRequest {
+	request := &QueryRequest{
+		QueryId:     query.QueryID,
+		From:        query.From,
+		To:          query.To,
+		ObjectIds:   query.ObjectIDs,
+		CounterId:   uint32(query.CounterId),
+		Aggregation: query.Aggregation,
+		Interval:    query.Interval,
+		Type:        query.Type,
+		Fill:        query.Fill,
+		FillValue:   query.FillValue,
+		FillLimit:   query.FillLimit,
+		Limit:       clampInt32(query.Limit),
+		Cursor:      query.Cursor,
+		Stream:      query.Stream,
+		Deadline:    query.Deadline,
+	}
+	for _, counterID := range query.CounterIDs {
+		request.CounterIds = append(request.CounterIds, uint32(counterID))
+	}
+	if query.Rank != nil {
+		request.Rank = &Ranking{Order: query.Rank.Order, Limit: clampInt32(query.Rank.Limit)}
+	}
+	return request
+}
+
+// QueryFromProto fails for counter IDs out of range
+func QueryFromProto(request *QueryRequest) (models.Query, error) {
+	query := models.Query{
+		QueryID:     request.GetQueryId(),
+		From:        request.GetFrom(),
+		To:          request.GetTo(),
+		ObjectIDs:   request.GetObjectIds(),
+		Aggregation: request.GetAggregation(),
+		Interval:    request.GetInterval(),
+		Type:        request.GetType(),
+		Fill:        request.GetFill(),
+		FillValue:   request.GetFillValue(),
+		FillLimit:   request.GetFillLimit(),
+		Limit:       int(request.GetLimit()),
+		Cursor:      request.GetCursor(),
+		Stream:      request.GetStream(),
+		Deadline:    request.GetDeadline(),
+	}
+
+	counterIDs := append([]uint32{request.GetCounterId()}, request.GetCounterIds()...)
+	for i, counterID := range counterIDs {
+		if counterID > 0xFFFF {
+			return query, fmt.Errorf("counter ID %d out of range", counterID)
+		}
+		if i == 0 {
+			query.CounterId = uint16(counterID)
+		} else {
+			query.CounterIDs = append(query.CounterIDs, uint16(counterID))
+		}
+	}
+
+	if rank := request.GetRank(); rank != nil {
+		query.Rank = &models.Ranking{Order: rank.GetOrder(), Limit: int(rank.GetLimit())}
+	}
+	return query, nil
+}
+
+func pointsToProto(points []models.DataPoint) (*DataPoints, error) {
+	result := &DataPoints{Points: make([]*DataPoint, 0, len(points))}
+	for _, p := range points {
+		value, err := ValueToProto(p.Value)
+		if err != nil {
+			return nil, err
+		}
+		result.Points = append(result.Points, &DataPoint{Timestamp: p.Timestamp, Value: value})
+	}
+	return result, nil
+}
+
+func pointsFromProto(points *DataPoints) []models.DataPoint {
+	result := make([]models.DataPoint, 0, len(points.GetPoints()))
+	for _, p := range points.GetPoints() {
+		result = append(result, models.DataPoint{Timestamp: p.GetTimestamp(), Value: ValueFromProto(p.GetValue())})
+	}
+	return result
+}
+
+// ResponseToProto fails for values of a type the schema has no place for
+func ResponseToProto(response models.QueryResponse) (*QueryResponse, error) {
+	result := &QueryResponse{
+		QueryId:   response.QueryID,
+		Status:    response.Status,
+		ErrorCode: response.ErrorCode,
+		Error:     response.Error,
+		Warnings:  response.Warnings,
+		Sequence:  response.Sequence,
+		Cursor:    response.Cursor,
+		Done:      response.Done,
+	}
+
+	if len(response.Data) > 0 {
+		result.Data = make(map[uint32]*DataPoints, len(response.Data))
+		for objectID, points := range response.Data {
+			converted, err := pointsToProto(points)
+			if err != nil {
+				return nil, fmt.Errorf("object %d: %v", objectID, err)
+			}
+			result.Data[objectID] = converted
+		}
+	}
+
+	if len(response.CounterData) > 0 {
+		result.CounterData = make(map[uint32]*CounterSeries, len(response.CounterData))
+		for objectID, counters := range response.CounterData {
+			series := &CounterSeries{Counters: make(map[uint32]*DataPoints, len(counters))}
+			for counterID, points := range counters {
+				converted, err := pointsToProto(points)
+				if err != nil {
+					return nil, fmt.Errorf("object %d, counter %d: %v", objectID, counterID, err)
+				}
+				series.Counters[uint32(counterID)] = converted
+			}
+			result.CounterData[objectID] = series
+		}
+	}
+
+	if len(response.Rankings) > 0 {
+		result.Rankings = make(map[uint32]*RankedObjects, len(response.Rankings))
+		for counterID, ranked := range response.Rankings {
+			objects := &RankedObjects{}
+			for _, r := range ranked {
+				objects.Objects = append(objects.Objects, &RankedObject{ObjectId: r.ObjectID, Timestamp: r.Timestamp, Value: r.Value})
+			}
+			result.Rankings[uint32(counterID)] = objects
+		}
+	}
+
+	if len(response.Objects) > 0 {
+		result.Objects = make(map[uint32]*ObjectInfos, len(response.Objects))
+		for counterID, infos := range response.Objects {
+			objects := &ObjectInfos{}
+			for _, info := range infos {
+				objects.Objects = append(objects.Objects, &ObjectInfo{ObjectId: info.ObjectID, FirstTimestamp: info.FirstTimestamp, LastTimestamp: info.LastTimestamp})
+			}
+			result.Objects[uint32(counterID)] = objects
+		}
+	}
+
+	if len(response.ObjectWarnings) > 0 {
+		result.ObjectWarnings = make(map[uint32]*Warnings, len(response.ObjectWarnings))
+		for objectID, warnings := range response.ObjectWarnings {
+			result.ObjectWarnings[objectID] = &Warnings{Warnings: warnings}
+		}
+	}
+
+	if stats := response.Stats; stats != nil {
+		result.Stats = &QueryStats{
+			BlocksScanned:    stats.BlocksScanned,
+			PointsRead:       stats.PointsRead,
+			RollupPointsRead: stats.RollupPointsRead,
+			DurationMs:       stats.DurationMs,
+		}
+	}
+
+	return result, nil
+}
+
+// ResponseFromProto is the inverse of ResponseToProto
+func ResponseFromProto(response *QueryResponse) models.QueryResponse {
+	result := models.QueryResponse{
+		QueryID:   response.GetQueryId(),
+		Data:      make(map[uint32][]models.DataPoint, len(response.GetData())),
+		Status:    response.GetStatus(),
+		ErrorCode: response.GetErrorCode(),
+		Error:     response.GetError(),
+		Warnings:  response.GetWarnings(),
+		Sequence:  response.GetSequence(),
+		Cursor:    response.GetCursor(),
+		Done:      response.GetDone(),
+	}
+
+	for objectID, points := range response.GetData() {
+		result.Data[objectID] = pointsFromProto(points)
+	}
+
+	for objectID, series := range response.GetCounterData() {
+		if result.CounterData == nil {
+			result.CounterData = make(map[uint32]map[uint16][]models.DataPoint)
+		}
+		result.CounterData[objectID] = make(map[uint16][]models.DataPoint, len(series.GetCounters()))
+		for counterID, points := range series.GetCounters() {
+			result.CounterData[objectID][uint16(counterID)] = pointsFromProto(points)
+		}
+	}
+
+	for counterID, ranked := range response.GetRankings() {
+		if result.Rankings == nil {
+			result.Rankings = make(map[uint16][]models.RankedObject)
+		}
+		objects := make([]models.RankedObject, 0, len(ranked.GetObjects()))
+		for _, r := range ranked.GetObjects() {
+			objects = append(objects, models.RankedObject{ObjectID: r.GetObjectId(), Timestamp: r.GetTimestamp(), Value: r.GetValue()})
+		}
+		result.Rankings[uint16(counterID)] = objects
+	}
+
+	for counterID, infos := range response.GetObjects() {
+		if result.Objects == nil {
+			result.Objects = make(map[uint16][]models.ObjectInfo)
+		}
+		objects := make([]models.ObjectInfo, 0, len(infos.GetObjects()))
+		for _, info := range infos.GetObjects() {
+			objects = append(objects, models.ObjectInfo{ObjectID: info.GetObjectId(), FirstTimestamp: info.GetFirstTimestamp(), LastTimestamp: info.GetLastTimestamp()})
+		}
+		result.Objects[uint16(counterID)] = objects
+	}
+
+	for objectID, warnings := range response.GetObjectWarnings() {
+		if result.ObjectWarnings == nil {
+			result.ObjectWarnings = make(map[uint32][]string)
+		}
+		result.ObjectWarnings[objectID] = warnings.GetWarnings()
+	}
+
+	if stats := response.GetStats(); stats != nil {
+		result.Stats = &models.QueryStats{
+			BlocksScanned:    stats.GetBlocksScanned(),
+			PointsRead:       stats.GetPointsRead(),
+			RollupPointsRead: stats.GetRollupPointsRead(),
+			DurationMs:       stats.GetDurationMs(),
+		}
+	}
+
+	return result
+}
+
+// clampInt32 converts a limit to the int32 of the wire, saturating rather
+// than wrapping, so a limit too large for it does not turn negative
+func clampInt32(n int) int32 {
+	if n > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	if n < math.MinInt32 {
+		return math.MinInt32
+	}
+	return int32(n)
+}
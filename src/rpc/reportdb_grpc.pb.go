@@ -0,0 +1,272 @@
+// gRPC interface of the database. Metric, QueryRequest and QueryResponse mirror
+// models.Metric, models.Query and models.QueryResponse, with typed values in
+// place of the untyped ones of the JSON interfaces.
+//
+// Regenerate the Go code with go generate in this directory, using protoc
+// 27.1 and the plugin versions the generated files name:
+//
+//	go install google.golang.org/protobuf/cmd/protoc-gen-go@v1.34.2
+//	go install google.golang.org/grpc/cmd/protoc-gen-go-grpc@v1.4.0
+//	protoc --go_out=. --go_opt=paths=source_relative \
+//	       --go-grpc_out=. --go-grpc_opt=paths=source_relative reportdb.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             v5.27.1
+// source: reportdb.proto
+
+package rpc
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	ReportDB_Query_FullMethodName       = "/reportdb.ReportDB/Query"
+	ReportDB_QueryStream_FullMethodName = "/reportdb.ReportDB/QueryStream"
+	ReportDB_Write_FullMethodName       = "/reportdb.ReportDB/Write"
+)
+
+// ReportDBClient is the client API for ReportDB service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type ReportDBClient interface {
+	// Query answers a query with a single response. Streamed queries are
+	// rejected; use QueryStream.
+	Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error)
+	// QueryStream answers a query in chunks, the last one marked done.
+	// Cancelling the call cancels the query.
+	QueryStream(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (ReportDB_QueryStreamClient, error)
+	// Write queues every batch sent for writing. A batch holding a metric of
+	// an unknown counter ends the call without being queued.
+	Write(ctx context.Context, opts ...grpc.CallOption) (ReportDB_WriteClient, error)
+}
+
+type reportDBClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReportDBClient(cc grpc.ClientConnInterface) ReportDBClient {
+	return &reportDBClient{cc}
+}
+
+func (c *reportDBClient) Query(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryResponse)
+	err := c.cc.Invoke(ctx, ReportDB_Query_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportDBClient) QueryStream(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (ReportDB_QueryStreamClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ReportDB_ServiceDesc.Streams[0], ReportDB_QueryStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &reportDBQueryStreamClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ReportDB_QueryStreamClient interface {
+	Recv() (*QueryResponse, error)
+	grpc.ClientStream
+}
+
+type reportDBQueryStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *reportDBQueryStreamClient) Recv() (*QueryResponse, error) {
+	m := new(QueryResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *reportDBClient) Write(ctx context.Context, opts ...grpc.CallOption) (ReportDB_WriteClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &ReportDB_ServiceDesc.Streams[1], ReportDB_Write_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &reportDBWriteClient{ClientStream: stream}
+	return x, nil
+}
+
+type ReportDB_WriteClient interface {
+	Send(*WriteRequest) error
+	CloseAndRecv() (*WriteSummary, error)
+	grpc.ClientStream
+}
+
+type reportDBWriteClient struct {
+	grpc.ClientStream
+}
+
+func (x *reportDBWriteClient) Send(m *WriteRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *reportDBWriteClient) CloseAndRecv() (*WriteSummary, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	m := new(WriteSummary)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReportDBServer is the server API for ReportDB service.
+// All implementations must embed UnimplementedReportDBServer
+// for forward compatibility
+type ReportDBServer interface {
+	// Query answers a query with a single response. Streamed queries are
+	// rejected; use QueryStream.
+	Query(context.Context, *QueryRequest) (*QueryResponse, error)
+	// QueryStream answers a query in chunks, the last one marked done.
+	// Cancelling the call cancels the query.
+	QueryStream(*QueryRequest, ReportDB_QueryStreamServer) error
+	// Write queues every batch sent for writing. A batch holding a metric of
+	// an unknown counter ends the call without being queued.
+	Write(ReportDB_WriteServer) error
+	mustEmbedUnimplementedReportDBServer()
+}
+
+// UnimplementedReportDBServer must be embedded to have forward compatible implementations.
+type UnimplementedReportDBServer struct {
+}
+
+func (UnimplementedReportDBServer) Query(context.Context, *QueryRequest) (*QueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Query not implemented")
+}
+func (UnimplementedReportDBServer) QueryStream(*QueryRequest, ReportDB_QueryStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method QueryStream not implemented")
+}
+func (UnimplementedReportDBServer) Write(ReportDB_WriteServer) error {
+	return status.Errorf(codes.Unimplemented, "method Write not implemented")
+}
+func (UnimplementedReportDBServer) mustEmbedUnimplementedReportDBServer() {}
+
+// UnsafeReportDBServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReportDBServer will
+// result in compilation errors.
+type UnsafeReportDBServer interface {
+	mustEmbedUnimplementedReportDBServer()
+}
+
+func RegisterReportDBServer(s grpc.ServiceRegistrar, srv ReportDBServer) {
+	s.RegisterService(&ReportDB_ServiceDesc, srv)
+}
+
+func _ReportDB_Query_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportDBServer).Query(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportDB_Query_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportDBServer).Query(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportDB_QueryStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(QueryRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ReportDBServer).QueryStream(m, &reportDBQueryStreamServer{ServerStream: stream})
+}
+
+type ReportDB_QueryStreamServer interface {
+	Send(*QueryResponse) error
+	grpc.ServerStream
+}
+
+type reportDBQueryStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *reportDBQueryStreamServer) Send(m *QueryResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ReportDB_Write_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(ReportDBServer).Write(&reportDBWriteServer{ServerStream: stream})
+}
+
+type ReportDB_WriteServer interface {
+	SendAndClose(*WriteSummary) error
+	Recv() (*WriteRequest, error)
+	grpc.ServerStream
+}
+
+type reportDBWriteServer struct {
+	grpc.ServerStream
+}
+
+func (x *reportDBWriteServer) SendAndClose(m *WriteSummary) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *reportDBWriteServer) Recv() (*WriteRequest, error) {
+	m := new(WriteRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReportDB_ServiceDesc is the grpc.ServiceDesc for ReportDB service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ReportDB_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "reportdb.ReportDB",
+	HandlerType: (*ReportDBServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Query",
+			Handler:    _ReportDB_Query_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "QueryStream",
+			Handler:       _ReportDB_QueryStream_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "Write",
+			Handler:       _ReportDB_Write_Handler,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "reportdb.proto",
+}
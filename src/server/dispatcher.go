@@ -0,0 +1,94 @@
+package server
+
+import (
+	"log"
+	"packx/models"
+	"sync"
+)
+
+// QueryDispatcher hands the queries of every front end, ZMQ or HTTP, to the
+// reader and routes each response back to the front end that submitted the
+// query. Queries are renumbered on the way in, so that queries of different
+// clients with the same ID do not collide in the reader.
+type QueryDispatcher struct {
+	queryReceiveChannel chan<- models.Query
+
+	queryCancelChannel chan<- uint64
+
+	lock sync.Mutex
+
+	lastID uint64
+
+	routes map[uint64]queryRoute // by the ID the query runs under in the reader
+}
+
+// queryRoute is where the responses of a submitted query go
+type queryRoute struct {
+	queryID uint64 // the ID the client gave the query
+
+	stream bool // answered by several responses, up to the one marked done
+
+	deliver func(response models.QueryResponse, last bool)
+}
+
+// NewQueryDispatcher starts routing the responses on queryResultChannel
+func NewQueryDispatcher(queryReceiveChannel chan<- models.Query, queryCancelChannel chan<- uint64, queryResultChannel <-chan models.QueryResponse) *QueryDispatcher {
+	dispatcher := &QueryDispatcher{
+		queryReceiveChannel: queryReceiveChannel,
+		queryCancelChannel:  queryCancelChannel,
+		routes:              make(map[uint64]queryRoute),
+	}
+
+	go dispatcher.dispatch(queryResultChannel)
+
+	return dispatcher
+}
+
+// Submit hands a query to the reader, waiting for room in its queue. Each
+// response is passed to deliver with the query's own ID, last being set on
+// the final one. deliver is called from the dispatching goroutine and must
+// not block, or the responses of every other query wait behind it. Submit
+// returns the ID the query runs under, for Cancel.
+func (d *QueryDispatcher) Submit(query models.Query, deliver func(response models.QueryResponse, last bool)) uint64 {
+	d.lock.Lock()
+	d.lastID++
+	readerID := d.lastID
+	d.routes[readerID] = queryRoute{queryID: query.QueryID, stream: query.Stream, deliver: deliver}
+	d.lock.Unlock()
+
+	query.QueryID = readerID
+	d.queryReceiveChannel <- query
+	return readerID
+}
+
+// Cancel aborts the query running under readerID. The query still delivers
+// its final response, carrying the cancelled status.
+func (d *QueryDispatcher) Cancel(readerID uint64) {
+	select {
+	case d.queryCancelChannel <- readerID:
+	default:
+		log.Printf("Cancel channel full, dropping cancel for query %d", readerID)
+	}
+}
+
+func (d *QueryDispatcher) dispatch(queryResultChannel <-chan models.QueryResponse) {
+	for result := range queryResultChannel {
+		d.lock.Lock()
+		route, exists := d.routes[result.QueryID]
+		// only streamed queries are answered by more than one response
+		last := result.Done || !route.stream
+		if exists && last {
+			delete(d.routes, result.QueryID)
+		}
+		d.lock.Unlock()
+
+		if !exists {
+			log.Printf("Dropping response for unknown query %d", result.QueryID)
+			continue
+		}
+
+		result.QueryID = route.queryID
+		route.deliver(result, last)
+	}
+	log.Println("Query result channel closed, dispatcher stopped")
+}
@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"packx/models"
+	"packx/rpc"
+	"packx/utils"
+	"sync"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer implements the ReportDB service. Like the HTTP API it writes to
+// dataWriteCh and queries through the dispatcher.
+type grpcServer struct {
+	rpc.UnimplementedReportDBServer
+
+	dataWriteCh chan<- []models.Metric
+
+	dispatcher *QueryDispatcher
+}
+
+// NewGRPCServer returns a gRPC server with the ReportDB service registered
+func NewGRPCServer(dataWriteCh chan<- []models.Metric, dispatcher *QueryDispatcher) *grpc.Server {
+	server := grpc.NewServer()
+	rpc.RegisterReportDBServer(server, &grpcServer{dataWriteCh: dataWriteCh, dispatcher: dispatcher})
+	return server
+}
+
+// InitGRPCServer serves the gRPC API on the configured address
+func InitGRPCServer(dataWriteCh chan<- []models.Metric, dispatcher *QueryDispatcher, globalShutdownWaitGroup *sync.WaitGroup) {
+	defer globalShutdownWaitGroup.Done()
+
+	address := utils.GetGRPCAddress()
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		log.Printf("Error listening for gRPC on %s: %v", address, err)
+		return
+	}
+
+	log.Printf("gRPC server started on %s", address)
+
+	if err := NewGRPCServer(dataWriteCh, dispatcher).Serve(listener); err != nil {
+		log.Printf("Error serving gRPC API on %s: %v", address, err)
+	}
+}
+
+func (s *grpcServer) Query(ctx context.Context, request *rpc.QueryRequest) (*rpc.QueryResponse, error) {
+	query, err := grpcQuery(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if query.Stream {
+		return nil, status.Error(codes.InvalidArgument, "streamed queries are answered by QueryStream")
+	}
+
+	// a query that is not streamed has a single response
+	responses := make(chan models.QueryResponse, 1)
+
+	readerID := s.dispatcher.Submit(query, func(response models.QueryResponse, last bool) {
+		responses <- response
+	})
+
+	select {
+	case response := <-responses:
+		return grpcResponse(response)
+	case <-ctx.Done():
+		s.dispatcher.Cancel(readerID)
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func (s *grpcServer) QueryStream(request *rpc.QueryRequest, stream rpc.ReportDB_QueryStreamServer) error {
+	ctx := stream.Context()
+
+	query, err := grpcQuery(ctx, request)
+	if err != nil {
+		return err
+	}
+	query.Stream = true
+
+	queue := newResponseQueue(streamBacklog)
+	readerID := s.dispatcher.Submit(query, queue.push)
+
+	for {
+		response, last, err := queue.pop(ctx)
+		if err != nil {
+			s.dispatcher.Cancel(readerID)
+			return err
+		}
+
+		converted, err := grpcResponse(response)
+		if err == nil {
+			err = stream.Send(converted)
+		}
+		if err != nil {
+			if !last {
+				s.dispatcher.Cancel(readerID)
+			}
+			return err
+		}
+
+		if last {
+			return nil
+		}
+	}
+}
+
+func (s *grpcServer) Write(stream rpc.ReportDB_WriteServer) error {
+	var accepted uint64
+
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&rpc.WriteSummary{Accepted: accepted})
+		}
+		if err != nil {
+			return err
+		}
+
+		metrics := make([]models.Metric, 0, len(request.GetMetrics()))
+		for i, m := range request.GetMetrics() {
+			metric, err := rpc.MetricFromProto(m)
+			if err != nil {
+				return status.Errorf(codes.InvalidArgument, "metric %d: %v; %d metrics accepted before", i, err, accepted)
+			}
+			metrics = append(metrics, metric)
+		}
+		if len(metrics) == 0 {
+			continue
+		}
+
+		if err := checkMetrics(metrics); err != nil {
+			return status.Errorf(codes.InvalidArgument, "%v; %d metrics accepted before", err, accepted)
+		}
+
+		select {
+		case s.dataWriteCh <- metrics:
+			accepted += uint64(len(metrics))
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+	}
+}
+
+// grpcQuery converts a query request; a query without a deadline of its own
+// stops at the deadline of the call
+func grpcQuery(ctx context.Context, request *rpc.QueryRequest) (models.Query, error) {
+	query, err := rpc.QueryFromProto(request)
+	if err != nil {
+		return query, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && query.Deadline == 0 {
+		query.Deadline = deadline.UnixMilli()
+	}
+
+	return query, nil
+}
+
+// grpcResponse converts a response, turning a rejected query into an error
+// carrying its error code
+func grpcResponse(response models.QueryResponse) (*rpc.QueryResponse, error) {
+	if response.Status == models.StatusError {
+		st := status.New(codes.InvalidArgument, response.Error)
+		if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: response.ErrorCode, Domain: rpc.ErrorDomain}); err == nil {
+			st = detailed
+		}
+		return nil, st.Err()
+	}
+
+	converted, err := rpc.ResponseToProto(response)
+	if err != nil {
+		return nil, status.Error(codes.Internal, fmt.Sprintf("converting response: %v", err))
+	}
+	return converted, nil
+}
+
+// streamBacklog bounds the chunks of a streamed query waiting to be sent
+const streamBacklog = 64
+
+// responseQueue holds the responses of a streamed query until they are sent,
+// so that a slow receiver does not hold up the dispatcher. A push finding
+// the queue full is dropped with the pushes after it, and the query fails
+// once the responses before it are sent.
+type responseQueue struct {
+	lock sync.Mutex
+
+	responses []queuedResponse
+
+	limit int
+
+	overrun bool
+
+	ready chan struct{} // signalled when a response is pushed or dropped
+}
+
+type queuedResponse struct {
+	response models.QueryResponse
+
+	last bool
+}
+
+func newResponseQueue(limit int) *responseQueue {
+	return &responseQueue{limit: limit, ready: make(chan struct{}, 1)}
+}
+
+func (q *responseQueue) push(response models.QueryResponse, last bool) {
+	q.lock.Lock()
+	switch {
+	case q.overrun:
+	case len(q.responses) == q.limit:
+		log.Printf("%d responses of query ID %d waiting to be sent, failing it", q.limit, response.QueryID)
+		q.overrun = true
+	default:
+		q.responses = append(q.responses, queuedResponse{response: response, last: last})
+	}
+	q.lock.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pop waits for the next response. It fails once ctx is done, or once the
+// responses pushed before the queue overran are taken.
+func (q *responseQueue) pop(ctx context.Context) (models.QueryResponse, bool, error) {
+	for {
+		q.lock.Lock()
+		if len(q.responses) > 0 {
+			next := q.responses[0]
+			q.responses = q.responses[1:]
+			q.lock.Unlock()
+			return next.response, next.last, nil
+		}
+		overrun := q.overrun
+		q.lock.Unlock()
+
+		if overrun {
+			return models.QueryResponse{}, false, status.Errorf(codes.ResourceExhausted, "more than %d responses waiting to be sent, the rest were dropped", q.limit)
+		}
+
+		select {
+		case <-q.ready:
+		case <-ctx.Done():
+			return models.QueryResponse{}, false, status.FromContextError(ctx.Err()).Err()
+		}
+	}
+}
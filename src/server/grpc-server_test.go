@@ -0,0 +1,166 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"packx/models"
+	"packx/rpc"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// startGRPCTest serves the gRPC API in front of startTestReader over an
+// in-memory listener
+func startGRPCTest(t *testing.T, dataWriteCh chan []models.Metric) (rpc.ReportDBClient, <-chan models.Query) {
+	dispatcher, received := startTestReader(t)
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewGRPCServer(dataWriteCh, dispatcher)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("connecting: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return rpc.NewReportDBClient(conn), received
+}
+
+func TestGRPCQuery(t *testing.T) {
+	client, received := startGRPCTest(t, make(chan []models.Metric, 1))
+
+	response, err := client.Query(context.Background(), rpc.QueryToProto(models.Query{QueryID: 42, From: 100, To: 200, ObjectIDs: []uint32{7}, CounterId: 2}))
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+
+	if query := <-received; query.QueryID == 42 {
+		t.Fatalf("expected the query to be renumbered for the reader")
+	}
+
+	result := rpc.ResponseFromProto(response)
+	if result.QueryID != 42 {
+		t.Fatalf("expected query ID 42, got %d", result.QueryID)
+	}
+	points := result.Data[7]
+	if len(points) != 1 || points[0].Timestamp != 100 || points[0].Value != float64(2) {
+		t.Fatalf("unexpected data for object 7: %+v", points)
+	}
+
+	_, err = client.Query(context.Background(), rpc.QueryToProto(models.Query{QueryID: 1, CounterId: 1, Stream: true}))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for a streamed query, got %v", err)
+	}
+}
+
+func TestGRPCQueryStream(t *testing.T) {
+	client, _ := startGRPCTest(t, make(chan []models.Metric, 1))
+
+	stream, err := client.QueryStream(context.Background(), rpc.QueryToProto(models.Query{QueryID: 5, From: 100, To: 200, ObjectIDs: []uint32{1}, CounterId: 1}))
+	if err != nil {
+		t.Fatalf("QueryStream: %v", err)
+	}
+
+	var chunks []models.QueryResponse
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		chunks = append(chunks, rpc.ResponseFromProto(response))
+	}
+
+	if len(chunks) != 3 || !chunks[2].Done {
+		t.Fatalf("expected 3 chunks ending with the last, got %+v", chunks)
+	}
+	for i, chunk := range chunks {
+		if chunk.QueryID != 5 || chunk.Sequence != uint32(i) {
+			t.Fatalf("unexpected chunk %d: %+v", i, chunk)
+		}
+	}
+}
+
+func TestGRPCWrite(t *testing.T) {
+	dataWriteCh := make(chan []models.Metric, 2)
+	client, _ := startGRPCTest(t, dataWriteCh)
+
+	metrics := []models.Metric{
+		{ObjectID: 1, CounterId: 1, Value: int64(10), Timestamp: 100},
+		{ObjectID: 2, CounterId: 2, Value: 1.5, Timestamp: 100},
+	}
+
+	stream, err := client.Write(context.Background())
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	request := &rpc.WriteRequest{}
+	for _, metric := range metrics {
+		converted, err := rpc.MetricToProto(metric)
+		if err != nil {
+			t.Fatalf("converting metric: %v", err)
+		}
+		request.Metrics = append(request.Metrics, converted)
+	}
+	if err := stream.Send(request); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	summary, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("CloseAndRecv: %v", err)
+	}
+
+	if summary.GetAccepted() != 2 {
+		t.Fatalf("expected 2 metrics accepted, got %d", summary.GetAccepted())
+	}
+	if batch := <-dataWriteCh; len(batch) != 2 || batch[0].Value != int64(10) || batch[1].Value != 1.5 {
+		t.Fatalf("unexpected batch queued: %+v", batch)
+	}
+
+	// a batch with an unknown counter is refused whole
+	stream, err = client.Write(context.Background())
+	if err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	unknown, _ := rpc.MetricToProto(models.Metric{ObjectID: 3, CounterId: 100, Value: int64(1), Timestamp: 100})
+	stream.Send(&rpc.WriteRequest{Metrics: append(request.Metrics, unknown)})
+
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+	if len(dataWriteCh) != 0 {
+		t.Fatalf("expected nothing queued for a refused batch")
+	}
+}
+
+func TestResponseQueueOverrun(t *testing.T) {
+	queue := newResponseQueue(2)
+
+	// pushes never wait; the one finding the queue full and those after it are dropped
+	for i := 0; i < 4; i++ {
+		queue.push(models.QueryResponse{QueryID: 5, Sequence: uint32(i)}, i == 3)
+	}
+
+	for i := 0; i < 2; i++ {
+		response, last, err := queue.pop(context.Background())
+		if err != nil || last || response.Sequence != uint32(i) {
+			t.Fatalf("pop %d: unexpected %+v, last %v, error %v", i, response, last, err)
+		}
+	}
+
+	if _, _, err := queue.pop(context.Background()); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted once the queued responses are taken, got %v", err)
+	}
+}
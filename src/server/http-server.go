@@ -0,0 +1,211 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"packx/models"
+	"packx/utils"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRequestBytes bounds the body of a write or query request
+const maxRequestBytes = 16 << 20
+
+// httpError is the body of a failed request
+type httpError struct {
+	Error string `json:"error"`
+}
+
+// writeResult is the body of an accepted write
+type writeResult struct {
+	Accepted int `json:"accepted"`
+}
+
+// NewHTTPHandler serves the HTTP API:
+//
+//	POST /write     a JSON array of metrics, queued for writing
+//	POST /query     a query, answered with its response
+//	GET  /counters  the configured counters
+//	GET  /objects   the objects with data, for counter_id (repeatable), from and to
+//
+// Writes go to dataWriteCh and queries through dispatcher, like those
+// arriving over ZMQ.
+func NewHTTPHandler(dataWriteCh chan<- []models.Metric, dispatcher *QueryDispatcher) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /write", func(w http.ResponseWriter, r *http.Request) {
+		handleWrite(w, r, dataWriteCh)
+	})
+
+	mux.HandleFunc("POST /query", func(w http.ResponseWriter, r *http.Request) {
+		var query models.Query
+		if err := decodeBody(w, r, &query); err != nil {
+			writeJSON(w, http.StatusBadRequest, httpError{Error: err.Error()})
+			return
+		}
+		if query.Cancel || query.Stream {
+			writeJSON(w, http.StatusBadRequest, httpError{Error: "cancel and stream are not supported over HTTP, page with limit and cursor instead"})
+			return
+		}
+		answerHTTPQuery(w, r, dispatcher, query)
+	})
+
+	mux.HandleFunc("GET /counters", handleCounters)
+
+	mux.HandleFunc("GET /objects", func(w http.ResponseWriter, r *http.Request) {
+		query, err := objectsQuery(r)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, httpError{Error: err.Error()})
+			return
+		}
+		answerHTTPQuery(w, r, dispatcher, query)
+	})
+
+	return mux
+}
+
+// InitHTTPServer serves the HTTP API on the configured address
+func InitHTTPServer(dataWriteCh chan<- []models.Metric, dispatcher *QueryDispatcher, globalShutdownWaitGroup *sync.WaitGroup) {
+	defer globalShutdownWaitGroup.Done()
+
+	address := utils.GetHTTPAddress()
+
+	log.Printf("HTTP server started on %s", address)
+
+	if err := http.ListenAndServe(address, NewHTTPHandler(dataWriteCh, dispatcher)); err != nil {
+		log.Printf("Error serving HTTP API on %s: %v", address, err)
+	}
+}
+
+func handleWrite(w http.ResponseWriter, r *http.Request, dataWriteCh chan<- []models.Metric) {
+	var metrics []models.Metric
+	if err := decodeBody(w, r, &metrics); err != nil {
+		writeJSON(w, http.StatusBadRequest, httpError{Error: err.Error()})
+		return
+	}
+	if len(metrics) == 0 {
+		writeJSON(w, http.StatusBadRequest, httpError{Error: "no metrics to write"})
+		return
+	}
+
+	if err := checkMetrics(metrics); err != nil {
+		writeJSON(w, http.StatusBadRequest, httpError{Error: err.Error()})
+		return
+	}
+
+	select {
+	case dataWriteCh <- metrics:
+		log.Printf("Queued %d metrics received over HTTP", len(metrics))
+		writeJSON(w, http.StatusAccepted, writeResult{Accepted: len(metrics)})
+	case <-r.Context().Done():
+		log.Printf("Dropping %d metrics, the request ended while the write queue was full", len(metrics))
+	}
+}
+
+// checkMetrics returns why a batch of metrics cannot be written, or nil. A
+// batch is written whole or not at all.
+func checkMetrics(metrics []models.Metric) error {
+	for i, metric := range metrics {
+		if _, err := utils.GetCounterType(metric.CounterId); err != nil {
+			return fmt.Errorf("metric %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func handleCounters(w http.ResponseWriter, r *http.Request) {
+	counters := utils.GetCounters()
+
+	infos := make([]models.CounterInfo, 0, len(counters))
+	for id, counter := range counters {
+		infos = append(infos, models.CounterInfo{
+			CounterID:     id,
+			Name:          counter.Name,
+			Type:          counter.Type,
+			RetentionDays: utils.GetCounterRetentionDays(id),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].CounterID < infos[j].CounterID })
+
+	writeJSON(w, http.StatusOK, infos)
+}
+
+// objectsQuery builds the objects query of a GET /objects request. The
+// range defaults to everything up to now.
+func objectsQuery(r *http.Request) (models.Query, error) {
+	params := r.URL.Query()
+
+	query := models.Query{
+		Type: models.QueryTypeObjects,
+		To:   uint32(time.Now().Unix()),
+	}
+
+	for _, value := range params["counter_id"] {
+		counterID, err := strconv.ParseUint(value, 10, 16)
+		if err != nil {
+			return query, fmt.Errorf("invalid counter_id %q", value)
+		}
+		query.CounterIDs = append(query.CounterIDs, uint16(counterID))
+	}
+	if len(query.CounterIDs) == 0 {
+		return query, fmt.Errorf("counter_id is required")
+	}
+
+	for name, bound := range map[string]*uint32{"from": &query.From, "to": &query.To} {
+		value := params.Get(name)
+		if value == "" {
+			continue
+		}
+		timestamp, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return query, fmt.Errorf("invalid %s %q", name, value)
+		}
+		*bound = uint32(timestamp)
+	}
+
+	return query, nil
+}
+
+// answerHTTPQuery runs a query and writes its response. A request that ends
+// before the response arrives cancels the query.
+func answerHTTPQuery(w http.ResponseWriter, r *http.Request, dispatcher *QueryDispatcher, query models.Query) {
+	// a query that is not streamed has a single response, so delivering it
+	// never blocks the dispatcher
+	responses := make(chan models.QueryResponse, 1)
+
+	readerID := dispatcher.Submit(query, func(response models.QueryResponse, last bool) {
+		responses <- response
+	})
+
+	select {
+	case response := <-responses:
+		status := http.StatusOK
+		if response.Status == models.StatusError {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, response)
+	case <-r.Context().Done():
+		log.Printf("HTTP request for query %d ended, cancelling it", query.QueryID)
+		dispatcher.Cancel(readerID)
+	}
+}
+
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Error writing HTTP response: %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"packx/models"
+	"testing"
+)
+
+// startTestReader stands in for the reader, answering every query with one
+// point per object, in three chunks when streamed. It returns a dispatcher
+// in front of it and the queries it received.
+func startTestReader(t *testing.T) (*QueryDispatcher, <-chan models.Query) {
+	queryReceiveCh := make(chan models.Query, 10)
+	queryCancelCh := make(chan uint64, 10)
+	queryResultCh := make(chan models.QueryResponse, 10)
+	received := make(chan models.Query, 10)
+
+	go func() {
+		for query := range queryReceiveCh {
+			received <- query
+
+			chunks := uint32(1)
+			if query.Stream {
+				chunks = 3
+			}
+			for sequence := uint32(0); sequence < chunks; sequence++ {
+				response := models.QueryResponse{QueryID: query.QueryID, Data: make(map[uint32][]models.DataPoint), Status: models.StatusOK, Sequence: sequence, Done: sequence == chunks-1}
+				for _, objectID := range query.ObjectIDs {
+					response.Data[objectID] = []models.DataPoint{{Timestamp: query.From + sequence, Value: float64(query.CounterId)}}
+				}
+				queryResultCh <- response
+			}
+		}
+	}()
+
+	t.Cleanup(func() {
+		close(queryReceiveCh)
+		close(queryResultCh)
+	})
+
+	return NewQueryDispatcher(queryReceiveCh, queryCancelCh, queryResultCh), received
+}
+
+// startHTTPTest serves the HTTP API in front of startTestReader
+func startHTTPTest(t *testing.T, dataWriteCh chan []models.Metric) (*httptest.Server, <-chan models.Query) {
+	dispatcher, received := startTestReader(t)
+
+	server := httptest.NewServer(NewHTTPHandler(dataWriteCh, dispatcher))
+	t.Cleanup(server.Close)
+
+	return server, received
+}
+
+func TestHTTPQuery(t *testing.T) {
+	server, received := startHTTPTest(t, make(chan []models.Metric, 1))
+
+	body, _ := json.Marshal(models.Query{QueryID: 42, From: 100, To: 200, ObjectIDs: []uint32{7}, CounterId: 2})
+	resp, err := http.Post(server.URL+"/query", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /query: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	var response models.QueryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+
+	// the reader sees the dispatcher's ID, the client gets its own back
+	if query := <-received; query.QueryID == 42 {
+		t.Fatalf("expected the query to be renumbered for the reader")
+	}
+	if response.QueryID != 42 {
+		t.Fatalf("expected query ID 42, got %d", response.QueryID)
+	}
+	points := response.Data[7]
+	if len(points) != 1 || points[0].Timestamp != 100 || points[0].Value != float64(2) {
+		t.Fatalf("unexpected data for object 7: %+v", points)
+	}
+}
+
+func TestHTTPQueryRejectsStream(t *testing.T) {
+	server, _ := startHTTPTest(t, make(chan []models.Metric, 1))
+
+	body, _ := json.Marshal(models.Query{QueryID: 1, CounterId: 1, Stream: true})
+	resp, err := http.Post(server.URL+"/query", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /query: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestHTTPWrite(t *testing.T) {
+	dataWriteCh := make(chan []models.Metric, 1)
+	server, _ := startHTTPTest(t, dataWriteCh)
+
+	metrics := []models.Metric{
+		{ObjectID: 1, CounterId: 1, Value: 10, Timestamp: 100},
+		{ObjectID: 2, CounterId: 2, Value: 1.5, Timestamp: 100},
+	}
+	body, _ := json.Marshal(metrics)
+	resp, err := http.Post(server.URL+"/write", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /write: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", resp.StatusCode)
+	}
+	if batch := <-dataWriteCh; len(batch) != 2 || batch[1].ObjectID != 2 {
+		t.Fatalf("unexpected batch queued: %+v", batch)
+	}
+
+	// a batch with an unknown counter is refused whole
+	body, _ = json.Marshal(append(metrics, models.Metric{ObjectID: 3, CounterId: 100, Value: 1, Timestamp: 100}))
+	resp, err = http.Post(server.URL+"/write", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /write: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+	if len(dataWriteCh) != 0 {
+		t.Fatalf("expected nothing queued for a refused batch")
+	}
+}
+
+func TestHTTPObjects(t *testing.T) {
+	server, received := startHTTPTest(t, make(chan []models.Metric, 1))
+
+	resp, err := http.Get(server.URL + "/objects")
+	if err != nil {
+		t.Fatalf("GET /objects: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 without counter_id, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(server.URL + "/objects?counter_id=1&counter_id=3&from=50&to=60")
+	if err != nil {
+		t.Fatalf("GET /objects: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	query := <-received
+	if query.Type != models.QueryTypeObjects || len(query.CounterIDs) != 2 || query.CounterIDs[1] != 3 || query.From != 50 || query.To != 60 {
+		t.Fatalf("unexpected objects query: %+v", query)
+	}
+}
+
+func TestHTTPMethodNotAllowed(t *testing.T) {
+	server, _ := startHTTPTest(t, make(chan []models.Metric, 1))
+
+	resp, err := http.Get(server.URL + "/query")
+	if err != nil {
+		t.Fatalf("GET /query: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"encoding/json"
+	zmq "github.com/pebbe/zmq4"
+	"log"
+	"packx/models"
+	"packx/utils"
+	"sync"
+)
+
+// liveFeedBacklog bounds the batches waiting to be published. The feed never
+// holds up writes: batches beyond it are not published.
+const liveFeedBacklog = 1024
+
+// LiveFeed publishes the metrics the writers stored to subscribers on a PUB
+// socket. Each metric is a two frame message: its models.LiveTopic and its
+// JSON. Subscribers pick objects and counters by subscribing to topics;
+// metrics written while nobody subscribes are not kept.
+type LiveFeed struct {
+	batches chan []models.Metric
+}
+
+func NewLiveFeed() *LiveFeed {
+	return &LiveFeed{batches: make(chan []models.Metric, liveFeedBacklog)}
+}
+
+// Publish queues metrics to be published, dropping them when the feed is
+// behind. It is handed to the writers, which call it with the metrics of a
+// batch once stored. The metrics must not be modified afterwards.
+func (f *LiveFeed) Publish(metrics []models.Metric) {
+	select {
+	case f.batches <- metrics:
+	default:
+		log.Printf("Live feed behind, not publishing %d metrics", len(metrics))
+	}
+}
+
+// InitLiveFeed publishes the metrics of feed on the configured endpoint
+func InitLiveFeed(feed *LiveFeed, globalShutdownWaitGroup *sync.WaitGroup) {
+	defer globalShutdownWaitGroup.Done()
+
+	context, err := zmq.NewContext()
+	if err != nil {
+		log.Printf("Error initializing live feed context: %v", err)
+		return
+	}
+	defer context.Term()
+
+	socket, err := context.NewSocket(zmq.PUB)
+	if err != nil {
+		log.Printf("Error initializing live feed socket: %v", err)
+		return
+	}
+	defer socket.Close()
+
+	address := utils.GetLiveFeedAddress()
+	if err = socket.Bind(address); err != nil {
+		log.Printf("Error binding live feed socket to %s: %v", address, err)
+		return
+	}
+
+	log.Printf("Live feed started on %s", address)
+
+	for metrics := range feed.batches {
+		for _, metric := range metrics {
+			metricBytes, err := json.Marshal(metric)
+			if err != nil {
+				log.Printf("Error marshalling metric of object %d for the live feed: %v", metric.ObjectID, err)
+				continue
+			}
+
+			if _, err := socket.SendMessage(models.LiveTopic(metric.ObjectID, metric.CounterId), metricBytes); err != nil {
+				log.Printf("Error publishing metric of object %d: %v", metric.ObjectID, err)
+			}
+		}
+	}
+}
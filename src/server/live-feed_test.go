@@ -0,0 +1,33 @@
+package server
+
+import (
+	"packx/models"
+	"testing"
+)
+
+func TestLiveFeedDropsWhenBehind(t *testing.T) {
+	feed := NewLiveFeed()
+
+	for i := 0; i < liveFeedBacklog+10; i++ {
+		feed.Publish([]models.Metric{{ObjectID: uint32(i)}})
+	}
+
+	if len(feed.batches) != liveFeedBacklog {
+		t.Fatalf("expected %d batches queued, got %d", liveFeedBacklog, len(feed.batches))
+	}
+}
+
+func TestLiveTopic(t *testing.T) {
+	topic := models.LiveTopic(7, 2)
+	if topic != "7/2/" {
+		t.Fatalf("unexpected topic %q", topic)
+	}
+
+	// subscriptions match by prefix
+	if models.LiveTopic(7, 21)[:len(topic)] == topic {
+		t.Fatalf("topic of counter 21 matches a subscription to counter 2")
+	}
+	if models.LiveTopic(7, 21)[:len(models.LiveObjectTopic(7))] != models.LiveObjectTopic(7) {
+		t.Fatalf("object topic does not prefix the topics of its counters")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	zmq "github.com/pebbe/zmq4"
 	"log"
 	"packx/models"
+	"packx/utils"
 	//"packx/storageEngine"
 )
 
@@ -12,17 +13,9 @@ import (
 
 func PullServer(pollData chan<- models.Metric) {
 
-	context, err := zmq.NewContext()
-
-	if err != nil {
-
-		log.Fatal("Fatal error in the Context Loading: ", err)
-
-	}
-
-	defer context.Term()
-
-	socket, err := context.NewSocket(zmq.PULL)
+	// the poller pushes from the same process, so the socket is made in the
+	// default context the poller's socket shares; an inproc endpoint reaches it
+	socket, err := zmq.NewSocket(zmq.PULL)
 
 	if err != nil {
 
@@ -32,12 +25,14 @@ func PullServer(pollData chan<- models.Metric) {
 
 	defer socket.Close()
 
-	if err := socket.Bind("tcp://*:5555"); err != nil {
+	address := utils.GetPullAddress()
+
+	if err := socket.Bind(address); err != nil {
 
 		log.Fatal("Failed to bind PULL socket:", err)
 	}
 
-	log.Println("PULL Server started on tcp://*:5555")
+	log.Println("PULL Server started on", address)
 
 	for {
 
@@ -0,0 +1,277 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	zmq "github.com/pebbe/zmq4"
+	"log"
+	"packx/models"
+	"packx/utils"
+	"sync"
+	"time"
+)
+
+// pollInterval bounds how long a response waits while the socket is idle
+const pollInterval = 10 * time.Millisecond
+
+// outboxSize bounds the responses waiting to be sent. Responses are delivered
+// from the dispatcher, which must not wait, so a query whose response finds
+// the outbox full is cancelled and answered with an error instead.
+const outboxSize = 1024
+
+// clientQuery identifies a query by the client that sent it. Clients pick
+// their query IDs independently, so only the pair is unique.
+type clientQuery struct {
+	identity string
+
+	queryID uint64
+}
+
+// outgoingResponse is a response waiting to be sent to its client
+type outgoingResponse struct {
+	client clientQuery
+
+	last bool
+
+	response models.QueryResponse
+}
+
+// queryServer is the state of the query socket. Only the goroutine running
+// InitQueryServer touches the socket and the running queries; responses reach
+// it through the outbox, and the errors of queries that overran it through
+// failed.
+type queryServer struct {
+	dispatcher *QueryDispatcher
+
+	socket *zmq.Socket
+
+	running map[clientQuery]uint64 // reader IDs of the queries not fully answered
+
+	outbox chan outgoingResponse
+
+	failedLock sync.Mutex
+
+	failed []outgoingResponse
+}
+
+// InitQueryServer answers queries on a ROUTER socket. Each client connects
+// a DEALER socket, sends its queries and cancels on it, and gets the
+// responses to its own queries back on it, however many clients are connected.
+func InitQueryServer(dispatcher *QueryDispatcher, globalShutdownWaitGroup *sync.WaitGroup) {
+	defer globalShutdownWaitGroup.Done()
+
+	context, err := zmq.NewContext()
+	if err != nil {
+		log.Printf("Error initializing query server context: %v", err)
+		return
+	}
+	defer context.Term()
+
+	socket, err := context.NewSocket(zmq.ROUTER)
+	if err != nil {
+		log.Printf("Error initializing query server socket: %v", err)
+		return
+	}
+	defer socket.Close()
+
+	address := utils.GetQueryAddress()
+	if err = socket.Bind(address); err != nil {
+		log.Printf("Error binding query server socket to %s: %v", address, err)
+		return
+	}
+
+	log.Printf("Query server started on %s", address)
+
+	s := &queryServer{
+		dispatcher: dispatcher,
+		socket:     socket,
+		running:    make(map[clientQuery]uint64),
+		outbox:     make(chan outgoingResponse, outboxSize),
+	}
+
+	poller := zmq.NewPoller()
+	poller.Add(socket, zmq.POLLIN)
+
+	for {
+		s.sendResponses()
+
+		polled, err := poller.Poll(pollInterval)
+		if err != nil {
+			if errors.Is(zmq.AsErrno(err), zmq.ETERM) {
+				log.Println("ZMQ context terminated, closing query server")
+				return
+			}
+			log.Printf("Error polling query server socket: %v", err)
+			continue
+		}
+		if len(polled) == 0 {
+			continue
+		}
+
+		frames, err := socket.RecvMessageBytes(0)
+		if err != nil {
+			log.Printf("Error receiving query: %v", err)
+			continue
+		}
+		if len(frames) < 2 {
+			log.Printf("Dropping query message with %d frames", len(frames))
+			continue
+		}
+
+		// the ROUTER socket prefixes the identity of the sending client
+		identity := string(frames[0])
+		s.receiveQuery(identity, frames[len(frames)-1])
+	}
+}
+
+// receiveQuery hands a query of a client to the reader, or forwards its cancel
+func (s *queryServer) receiveQuery(identity string, queryBytes []byte) {
+	var query models.Query
+	if err := json.Unmarshal(queryBytes, &query); err != nil {
+		log.Printf("Error unmarshalling query: %v", err)
+		rejectMalformedQuery(s.socket, identity, queryBytes, err)
+		return
+	}
+
+	client := clientQuery{identity: identity, queryID: query.QueryID}
+	readerID, running := s.running[client]
+
+	// A cancel carries the ID of the query to abort
+	if query.Cancel {
+		if !running {
+			log.Printf("Ignoring cancel for query ID %d, which is not running", query.QueryID)
+			return
+		}
+		log.Printf("Received cancel for query ID: %d", query.QueryID)
+		s.dispatcher.Cancel(readerID)
+		return
+	}
+
+	if running {
+		log.Printf("Rejecting query ID %d, which is already running", query.QueryID)
+		reply(s.socket, identity, models.QueryResponse{
+			QueryID:   query.QueryID,
+			Done:      true,
+			Status:    models.StatusError,
+			ErrorCode: models.ErrorCodeInvalidQuery,
+			Error:     fmt.Sprintf("query ID %d is already running", query.QueryID),
+		})
+		return
+	}
+
+	log.Printf("Received query: %+v", query)
+	s.running[client] = s.dispatcher.Submit(query, s.deliverer(client))
+}
+
+// deliverer returns the function the dispatcher delivers the responses of a
+// query with. Once a response finds the outbox full the query fails: an
+// error is sent in place of that response and the rest are dropped.
+func (s *queryServer) deliverer(client clientQuery) func(response models.QueryResponse, last bool) {
+	overrun := false // only the dispatching goroutine delivers
+
+	return func(response models.QueryResponse, last bool) {
+		if overrun {
+			return
+		}
+
+		select {
+		case s.outbox <- outgoingResponse{client: client, last: last, response: response}:
+			return
+		default:
+		}
+
+		overrun = true
+		log.Printf("Outbox full, failing query ID %d", client.queryID)
+		s.failedLock.Lock()
+		s.failed = append(s.failed, outgoingResponse{client: client, last: last, response: models.QueryResponse{
+			QueryID:   client.queryID,
+			Done:      true,
+			Status:    models.StatusError,
+			ErrorCode: models.ErrorCodeResponsesDropped,
+			Error:     fmt.Sprintf("response %d dropped, the server has %d responses waiting to be sent", response.Sequence, outboxSize),
+		}})
+		s.failedLock.Unlock()
+	}
+}
+
+// sendResponses sends the responses delivered since the last call. The
+// responses a query queued before it failed are sent before its error.
+func (s *queryServer) sendResponses() {
+	s.failedLock.Lock()
+	failed := s.failed
+	s.failed = nil
+	s.failedLock.Unlock()
+
+	for queued := len(s.outbox); queued > 0; queued-- {
+		s.send(<-s.outbox)
+	}
+
+	for _, outgoing := range failed {
+		// the reader need not keep answering a query nobody receives
+		if readerID, running := s.running[outgoing.client]; running && !outgoing.last {
+			s.dispatcher.Cancel(readerID)
+		}
+		outgoing.last = true
+		s.send(outgoing)
+	}
+}
+
+// send sends one response, forgetting its query once fully answered
+func (s *queryServer) send(outgoing outgoingResponse) {
+	if outgoing.last {
+		delete(s.running, outgoing.client)
+	}
+
+	result := outgoing.response
+	log.Printf("Preparing to send response for QueryID: %d with %d objects, status %s",
+		result.QueryID, len(result.Data), result.Status)
+	if result.Error != "" {
+		log.Printf("QueryID %d failed (%s): %s", result.QueryID, result.ErrorCode, result.Error)
+	}
+	if len(result.Warnings) > 0 || len(result.ObjectWarnings) > 0 {
+		log.Printf("QueryID %d carries %d warnings and warnings for %d objects",
+			result.QueryID, len(result.Warnings), len(result.ObjectWarnings))
+	}
+
+	reply(s.socket, outgoing.client.identity, result)
+}
+
+// reply sends a response to one client. A client that has gone away is
+// dropped by the ROUTER socket, so nothing waits on it.
+func reply(socket *zmq.Socket, identity string, response models.QueryResponse) {
+	responseBytes, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Error marshalling query result: %v", err)
+		return
+	}
+
+	if _, err := socket.SendMessage(identity, responseBytes); err != nil {
+		log.Printf("Failed to send response for QueryID %d: %v", response.QueryID, err)
+		return
+	}
+	log.Printf("Successfully sent response for QueryID: %d", response.QueryID)
+}
+
+// rejectMalformedQuery answers a query that could not be decoded with an
+// error, so the client does not wait for it to time out. Without a readable
+// query ID there is no one to answer, and a cancel is not answered, as the
+// query it was for still is.
+func rejectMalformedQuery(socket *zmq.Socket, identity string, queryBytes []byte, err error) {
+	var header struct {
+		QueryID *uint64 `json:"query_id"`
+
+		Cancel bool `json:"cancel"`
+	}
+	if json.Unmarshal(queryBytes, &header) != nil || header.QueryID == nil || header.Cancel {
+		return
+	}
+
+	reply(socket, identity, models.QueryResponse{
+		QueryID:   *header.QueryID,
+		Done:      true,
+		Status:    models.StatusError,
+		ErrorCode: models.ErrorCodeInvalidQuery,
+		Error:     fmt.Sprintf("malformed query: %v", err),
+	})
+}
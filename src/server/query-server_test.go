@@ -0,0 +1,83 @@
+package server
+
+import (
+	"encoding/json"
+	"packx/models"
+	"testing"
+)
+
+func TestQueryServerFailsQueryOverrunningOutbox(t *testing.T) {
+	s := &queryServer{running: make(map[clientQuery]uint64), outbox: make(chan outgoingResponse, outboxSize)}
+	client := clientQuery{identity: "client", queryID: 7}
+	deliver := s.deliverer(client)
+
+	// the dispatcher is never held up, however full the outbox
+	for i := 0; i < outboxSize+10; i++ {
+		deliver(models.QueryResponse{QueryID: 7, Sequence: uint32(i)}, false)
+	}
+
+	if len(s.outbox) != outboxSize {
+		t.Fatalf("expected %d responses queued, got %d", outboxSize, len(s.outbox))
+	}
+	if len(s.failed) != 1 {
+		t.Fatalf("expected the query failed once, got %+v", s.failed)
+	}
+	failure := s.failed[0].response
+	if failure.Status != models.StatusError || failure.ErrorCode != models.ErrorCodeResponsesDropped || !failure.Done {
+		t.Fatalf("unexpected failure response: %+v", failure)
+	}
+
+	// the rest of the query is dropped, even once there is room again
+	<-s.outbox
+	deliver(models.QueryResponse{QueryID: 7, Sequence: outboxSize + 10}, true)
+	if len(s.outbox) != outboxSize-1 {
+		t.Fatalf("expected nothing queued after the failure, got %d responses", len(s.outbox))
+	}
+}
+
+func TestQueryServerCancelsOverTheWire(t *testing.T) {
+	queries := make(chan models.Query, 1)
+	cancels := make(chan uint64, 1)
+	results := make(chan models.QueryResponse)
+	defer close(results)
+
+	s := &queryServer{
+		dispatcher: NewQueryDispatcher(queries, cancels, results),
+		running:    make(map[clientQuery]uint64),
+		outbox:     make(chan outgoingResponse, outboxSize),
+	}
+
+	// a query and its cancel as QueryClient encodes them
+	queryBytes, err := json.Marshal(models.Query{QueryID: 5, From: 0, To: 100, CounterId: 1})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	s.receiveQuery("client", queryBytes)
+
+	var submitted models.Query
+	select {
+	case submitted = <-queries:
+	default:
+		t.Fatalf("query was not passed to the reader")
+	}
+
+	cancelBytes, err := json.Marshal(models.Query{QueryID: 5, Cancel: true})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	s.receiveQuery("client", cancelBytes)
+
+	select {
+	case readerID := <-cancels:
+		if readerID != submitted.QueryID {
+			t.Fatalf("cancelled reader query %d, expected %d", readerID, submitted.QueryID)
+		}
+	default:
+		t.Fatalf("cancel was not passed to the reader")
+	}
+
+	// the cancel is not answered; the query answers with the cancelled status
+	if len(s.outbox) != 0 {
+		t.Fatalf("expected no response to the cancel, got %d", len(s.outbox))
+	}
+}
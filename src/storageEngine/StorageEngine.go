@@ -1,6 +1,7 @@
 package storageEngine
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"log"
@@ -8,6 +9,7 @@ import (
 	. "packx/utils"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 )
 
 type BlockHeader struct {
@@ -19,6 +21,61 @@ type BlockHeader struct {
 	DataType        byte   // 1 byte - indicates value type
 }
 
+// Block encodings, stored in bits 4-6 of BlockHeader.DataType. The low
+// three bits keep the counter's value type, bit 3 marks blocks still being
+// appended to and the top bit marks blocks that end in a checksum.
+const (
+	// EncodingRaw blocks hold records exactly as serialized by the writer
+	EncodingRaw = 0
+
+	// EncodingGorilla blocks hold delta-of-delta timestamps and xor'd values
+	EncodingGorilla = 1
+
+	// EncodingDictionary blocks hold timestamps and string IDs of the partition's dictionary
+	EncodingDictionary = 2
+
+	// BlockFlagChecksum marks blocks whose last BlockChecksumSize bytes hold
+	// a crc32 of everything before them, header included
+	BlockFlagChecksum = 0x80
+
+	// BlockFlagOpen marks checksummed blocks that records are still appended
+	// to. Their checksum is only written when they are sealed, on rollover to
+	// the device's next block or at a checkpoint, so it is not checked.
+	BlockFlagOpen = 0x08
+
+	BlockChecksumSize = 4
+
+	// BlockWalPositionSize is the trailer before the checksum of checksummed
+	// blocks holding the write-ahead log position of their last record
+	BlockWalPositionSize = 12
+
+	// blockRecordsEnd is where the record region of a checksummed block ends
+	blockRecordsEnd = BlockSize - BlockWalPositionSize - BlockChecksumSize
+
+	// blockCapacity is the record region of a checksummed block
+	blockCapacity = blockRecordsEnd - BlockHeaderSize
+)
+
+// ValueType returns the counter type (TypeInt, TypeFloat, TypeString) of the block's records
+func (h BlockHeader) ValueType() byte {
+	return h.DataType & 0x07
+}
+
+// Encoding returns the format the block's records are stored in
+func (h BlockHeader) Encoding() byte {
+	return (h.DataType >> 4) & 0x07
+}
+
+// Checksummed reports whether the block ends in a checksum
+func (h BlockHeader) Checksummed() bool {
+	return h.DataType&BlockFlagChecksum != 0
+}
+
+// Sealed reports whether the block ends in a checksum that covers it
+func (h BlockHeader) Sealed() bool {
+	return h.Checksummed() && h.DataType&BlockFlagOpen == 0
+}
+
 type OffsetTableEntry struct {
 	Timestamp int64 // Timestamp of the record
 
@@ -29,52 +86,139 @@ type OffsetTableEntry struct {
 	Type byte // Type of the record (1=float, 2=string, 3=int)
 }
 
+// BlockView is a read-only window onto one block of a mapped data file, as
+// returned by GetRange. Header is a copy taken when the view was created;
+// Data aliases the mapping (the record region after the header) and must not
+// be modified. Only the first Header.RecordCount records are complete.
+// Corrupt views failed their checksum; their header and data are not to be trusted.
+// A view keeps its mapping alive until it is released with ReleaseViews, and
+// Data must not be used after that.
+type BlockView struct {
+	Offset int64
+
+	Header BlockHeader
+
+	Data []byte
+
+	Corrupt bool
+
+	Dictionary *StringDictionary // resolves string IDs of dictionary encoded blocks
+
+	mapping *mapping
+}
+
+// ReleaseViews releases the views returned by GetRange once their data is
+// no longer used
+func ReleaseViews(views []BlockView) {
+	for i := range views {
+		if views[i].mapping != nil {
+			views[i].mapping.unpin()
+			views[i].mapping = nil
+			views[i].Data = nil
+		}
+	}
+}
+
+// IndexEntry describes one block of a partition's data file
 type IndexEntry struct {
-	DeviceID      int   `json:"device_id"`
-	BlockOffset   int64 `json:"block_offset"`
-	CurrentOffset int64 `json:"current_offset"`
+	DeviceID       uint32 // 4 bytes
+	StartTimestamp uint32 // 4 bytes - mirrors the block header
+	EndTimestamp   uint32 // 4 bytes - mirrors the block header
+	BlockOffset    int64  // 8 bytes
 }
 
+// BlockManager hands out the blocks of every partition's data.bin and tracks
+// the chain of blocks each device owns there. All devices of a partition share
+// one data file, so offsets are allocated per partition, never per device.
 type BlockManager struct {
 	mu sync.Mutex
-	nextOffset map[int]int64
-	
-	// Track how many bytes are used in each block
-	blockUsage map[int]int
-	
-	// Track the current block offset for each device
-	currentBlock map[int]int64
+
+	partitions map[string]*partitionBlocks // partition path -> allocation state
+}
+
+// partitionBlocks is only mutated while the partition's write lock is held
+type partitionBlocks struct {
+	partition int // index into StorageEngine.partitionLocks
+
+	nextOffset int64 // first unallocated block of data.bin
+
+	devices map[uint32]*deviceChain
+}
+
+// deviceChain is the linked list of blocks a device owns in a partition,
+// joined on disk through BlockHeader.NextBlockOffset
+type deviceChain struct {
+	firstBlock int64
+
+	lastBlock int64 // records are appended here until it is full
+
+	usage int // bytes used after the header of lastBlock, -1 until read
+
+	encoding byte // encoding of lastBlock
+
+	checksummed bool // lastBlock ends in a checksum; older blocks are not appended to
+
+	open bool // lastBlock has BlockFlagOpen set and must be sealed before it is checked
+
+	encoder *gorillaEncoder // append state of lastBlock when it is Gorilla encoded
 }
 
 func newBlockManager() *BlockManager {
 	return &BlockManager{
-		nextOffset:    make(map[int]int64),
-		blockUsage:    make(map[int]int),
-		currentBlock:  make(map[int]int64),
+		partitions: make(map[string]*partitionBlocks),
 	}
 }
 
-func (bm *BlockManager) getNextBlockOffset(deviceID int) int64 {
+// partitionState returns the allocation state of a partition, rebuilding it
+// from the partition's block index the first time the partition is used
+func (bm *BlockManager) partitionState(partitionPath string, partition int, index *blockIndex) *partitionBlocks {
 	bm.mu.Lock()
 	defer bm.mu.Unlock()
 
-	if offset, exists := bm.nextOffset[deviceID]; exists {
-		nextOffset := offset + BlockSize
-		bm.nextOffset[deviceID] = nextOffset
-		return offset
+	if state, exists := bm.partitions[partitionPath]; exists {
+		return state
+	}
+
+	state := &partitionBlocks{
+		partition: partition,
+		devices:   make(map[uint32]*deviceChain),
 	}
 
-	// First block for this device - check if we have a persisted offset
-	if offset, exists := bm.currentBlock[deviceID]; exists {
-		nextOffset := offset + BlockSize
-		bm.nextOffset[deviceID] = nextOffset
-		return offset
+	for _, entry := range index.allEntries() {
+		if entry.BlockOffset+BlockSize > state.nextOffset {
+			state.nextOffset = entry.BlockOffset + BlockSize
+		}
+
+		chain, exists := state.devices[entry.DeviceID]
+		if !exists {
+			chain = &deviceChain{firstBlock: entry.BlockOffset, usage: -1}
+			state.devices[entry.DeviceID] = chain
+		}
+		chain.lastBlock = entry.BlockOffset
 	}
 
-	// Truly first block for this device
-	baseOffset := int64(0)
-	bm.nextOffset[deviceID] = baseOffset + BlockSize
-	return baseOffset
+	bm.partitions[partitionPath] = state
+
+	return state
+}
+
+// forget drops the state of every partition whose path matches
+func (bm *BlockManager) forget(match func(partitionPath string) bool) {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+
+	for partitionPath := range bm.partitions {
+		if match(partitionPath) {
+			delete(bm.partitions, partitionPath)
+		}
+	}
+}
+
+// allocateBlock reserves the next free block of the partition
+func (pb *partitionBlocks) allocateBlock() int64 {
+	offset := pb.nextOffset
+	pb.nextOffset += BlockSize
+	return offset
 }
 
 type StorageEngine struct {
@@ -86,6 +230,14 @@ type StorageEngine struct {
 
 	mmapFilesLock sync.Mutex
 
+	indexes map[string]*blockIndex // partition path -> loaded block index
+
+	indexesLock sync.Mutex
+
+	dictionaries map[string]*StringDictionary // partition path -> loaded string dictionary
+
+	dictionariesLock sync.Mutex
+
 	basedir string // base Directory for the strore the all data
 
 	blockManager *BlockManager
@@ -93,18 +245,75 @@ type StorageEngine struct {
 	storagePath string
 
 	pathLock sync.RWMutex
+
+	rootPath string // root of the storage tree, holds the write-ahead log
+
+	wal *writeAheadLog
+
+	walLock sync.RWMutex // shared by PutBatch, exclusive while checkpointing
+
+	checkpointNow chan struct{}
+
+	stopCheckpoint chan struct{}
+
+	checkpointDone chan struct{}
+
+	blocksVerified uint64 // updated atomically
+
+	checksumFailures uint64 // updated atomically
+}
+
+// EngineStats are counters of the engine's activity since it was opened
+type EngineStats struct {
+	BlocksVerified uint64
+
+	ChecksumFailures uint64
+}
+
+// Stats returns the engine's counters
+func (bs *StorageEngine) Stats() EngineStats {
+	return EngineStats{
+		BlocksVerified:   atomic.LoadUint64(&bs.blocksVerified),
+		ChecksumFailures: atomic.LoadUint64(&bs.checksumFailures),
+	}
 }
 
+// NewStorageEngine creates the engine rooted at the configured storage path and
+// replays any batches an unclean shutdown left in the write-ahead log.
 func NewStorageEngine() (*StorageEngine, error) {
+	return openStorageEngine(GetStoragePath())
+}
+
+func openStorageEngine(rootPath string) (*StorageEngine, error) {
 	engine := &StorageEngine{
-		mmapFiles:    make(map[string]*MappedFile),
-		blockManager: newBlockManager(),
+		mmapFiles:      make(map[string]*MappedFile),
+		indexes:        make(map[string]*blockIndex),
+		dictionaries:   make(map[string]*StringDictionary),
+		blockManager:   newBlockManager(),
+		rootPath:       rootPath,
+		checkpointNow:  make(chan struct{}, 1),
+		stopCheckpoint: make(chan struct{}),
+		checkpointDone: make(chan struct{}),
 	}
 	
 	// Initialize block manager with persisted state
 	if err := engine.initializeBlockManagerState(); err != nil {
 		log.Printf("Warning: Failed to initialize block manager state: %v", err)
 	}
+
+	wal, entries, err := openWriteAheadLog(filepath.Join(rootPath, walDirName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open write-ahead log: %v", err)
+	}
+	engine.wal = wal
+
+	if err := engine.replayWal(entries); err != nil {
+		wal.close()
+		engine.closeMappedFiles()
+		return nil, err
+	}
+
+	go engine.checkpointLoop()
 	
 	return engine, nil
 }
@@ -134,6 +343,71 @@ func (bs *StorageEngine) Put(key int, data []byte) error {
 		return fmt.Errorf("storage path not set")
 	}
 
+	return bs.put(basePath, key, determineDataType(data), data, walPosition{})
+}
+
+// PutBatch appends the serialized records of one device to the write-ahead log
+// and then writes them into the partition under counterPath. Unlike Put it does
+// not depend on the engine-wide storage path, so writers may share the engine.
+func (bs *StorageEngine) PutBatch(counterPath string, key int, dataType byte, records [][]byte) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	bs.walLock.RLock()
+	defer bs.walLock.RUnlock()
+
+	entry := &walEntry{
+		path:     bs.relativeToRoot(counterPath),
+		key:      key,
+		dataType: dataType,
+		records:  records,
+	}
+
+	if err := bs.wal.append(entry); err != nil {
+		return fmt.Errorf("failed to append to write-ahead log: %v", err)
+	}
+
+	if bs.wal.getSize() > walMaxSize {
+		select {
+		case bs.checkpointNow <- struct{}{}:
+		default:
+		}
+	}
+
+	return bs.applyBatch(counterPath, key, dataType, entry.lsn, records, false)
+}
+
+// applyBatch writes the records of the write-ahead log entry lsn into the
+// partition. During replay the records up to the position stored with the
+// device's current block are skipped, since they reached the mapped file
+// before the crash.
+func (bs *StorageEngine) applyBatch(counterPath string, key int, dataType byte, lsn uint64, records [][]byte, replay bool) error {
+	var applied walPosition
+
+	if replay {
+		applied = bs.appliedWalPosition(counterPath, key)
+	}
+
+	for i, data := range records {
+		position := walPosition{lsn: lsn, seq: uint32(i)}
+
+		if replay && !applied.before(position) {
+			continue
+		}
+
+		if err := bs.put(counterPath, key, dataType, data, position); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// put appends one record to the device's chain and stores position, the
+// write-ahead log position it was logged at, with the block. Records not
+// logged pass a zero position and leave the stored position as it is.
+func (bs *StorageEngine) put(basePath string, key int, dataType byte, data []byte, position walPosition) error {
 	partition := key % NumPartitions
 	partitionPath := filepath.Join(basePath, fmt.Sprintf("partition_%d", partition))
 
@@ -155,81 +429,182 @@ func (bs *StorageEngine) Put(key int, data []byte) error {
 		return err
 	}
 
-	// Check if we can use existing block
+	index, err := bs.getBlockIndex(partitionPath)
+	if err != nil {
+		log.Printf("failed to load block index: %v", err)
+		return err
+	}
+
+	state := bs.blockManager.partitionState(partitionPath, partition, index)
+
+	chain := state.devices[uint32(key)]
+
+	if chain != nil && chain.usage < 0 {
+		bs.loadChainTail(mmapFile, chain, key, dataType)
+	}
+
+	// Extract timestamp from data
+	var timestamp uint32
+	if len(data) >= 4 {
+		timestamp = binary.LittleEndian.Uint32(data[:4])
+	}
+
+	encoding := blockEncoding(dataType)
+
+	if encoding == EncodingDictionary {
+		dict, err := bs.getDictionary(partitionPath)
+		if err != nil {
+			return fmt.Errorf("failed to load dictionary: %v", err)
+		}
+
+		if data, err = dictionaryEncode(dict, data); err != nil {
+			return err
+		}
+	}
+
+	// Check if we can use existing block: it must be checksummed, in the same
+	// encoding and still have room for the encoded record
 	var offset int64
 	var isNewBlock bool
+	var payload []byte
+	var payloadOffset int
+	var encoder *gorillaEncoder
 
-	if bs.hasSpaceInBlock(key, len(data)) {
+	if chain != nil && chain.checksummed && chain.encoding == encoding {
+		payloadOffset, payload, encoder = encodeRecord(chain.encoder, chain.usage, encoding, data)
 		// Use existing block
-		currentOffset, _ := bs.blockManager.currentBlock[key]
-		offset = currentOffset
-		isNewBlock = false
-	} else {
+		offset = chain.lastBlock
+	}
+
+	if payload == nil || payloadOffset+len(payload) > blockCapacity {
 		// Allocate new block
-		offset = bs.blockManager.getNextBlockOffset(key)
+		offset = state.allocateBlock()
 		isNewBlock = true
+		payloadOffset, payload, encoder = encodeRecord(nil, 0, encoding, data)
 	}
 
 	requiredSize := offset + BlockSize
-	if requiredSize > int64(mmapFile.size) {
-		newSize := ((requiredSize / BlockSize) + 1) * BlockSize
-		if err := mmapFile.grow(int(newSize)); err != nil {
+	if requiredSize > int64(mmapFile.getSize()) {
+		if err := mmapFile.grow(int(requiredSize)); err != nil {
 			return fmt.Errorf("failed to extend mapping: %v", err)
 		}
 	}
 
-	// Extract timestamp from data
-	var timestamp uint32
-	if len(data) >= 4 {
-		timestamp = binary.LittleEndian.Uint32(data[:4])
+	// A sealed block is reopened before its records change, so that a crash
+	// leaves it unchecked rather than failing its checksum
+	if !isNewBlock && !chain.open {
+		if err := bs.reopenBlock(mmapFile, offset); err != nil {
+			return err
+		}
+		chain.open = true
+	}
+
+	// Write the record first so a header never counts a record that is not
+	// there, and the position after the header: a crash in between applies
+	// the record again on replay instead of losing it
+	if _, err := mmapFile.WriteAt(payload, offset+BlockHeaderSize+int64(payloadOffset)); err != nil {
+		return fmt.Errorf("failed to write data: %v", err)
 	}
 
 	if isNewBlock {
 		// Create new header for new block
-		header := bs.initializeBlockHeader(key, determineDataType(data), timestamp)
+		header := bs.initializeBlockHeader(key, dataType|encoding<<4|BlockFlagChecksum|BlockFlagOpen, timestamp)
 		headerBytes := encodeBlockHeader(header)
 		if _, err := mmapFile.WriteAt(headerBytes, offset); err != nil {
 			return fmt.Errorf("failed to write header: %v", err)
 		}
 
-		// Update block manager state
-		bs.blockManager.mu.Lock()
-		bs.blockManager.currentBlock[key] = offset
-		bs.blockManager.blockUsage[key] = 0
-		bs.blockManager.mu.Unlock()
-	} else {
-		// Update existing header
-		if err := bs.updateBlockHeader(mmapFile, offset, data); err != nil {
-			return fmt.Errorf("failed to update header: %v", err)
+		// a new block carries the position forward, so that the device's
+		// current block always holds the last position applied to it
+		if position.lsn == 0 && chain != nil && chain.checksummed {
+			position = readWalPosition(mmapFile, chain.lastBlock)
+		}
+
+		if err := writeWalPosition(mmapFile, offset, position); err != nil {
+			return err
 		}
-	}
 
-	// Calculate write position
-	writeOffset := offset + BlockHeaderSize
-	if !isNewBlock {
-		usage, _ := bs.getBlockUsage(key, offset)
-		writeOffset += int64(usage)
+		if err := index.append(IndexEntry{
+			DeviceID:       uint32(key),
+			StartTimestamp: timestamp,
+			EndTimestamp:   timestamp,
+			BlockOffset:    offset,
+		}); err != nil {
+			return fmt.Errorf("failed to update index: %v", err)
+		}
+
+		// Chain the device's previous block to the new one, sealing it as
+		// nothing is appended to it any more
+		if chain != nil {
+			if err := bs.linkBlock(mmapFile, chain.lastBlock, offset); err != nil {
+				return fmt.Errorf("failed to link block: %v", err)
+			}
+			chain.lastBlock = offset
+		} else {
+			chain = &deviceChain{firstBlock: offset, lastBlock: offset}
+			state.devices[uint32(key)] = chain
+		}
+
+		chain.encoding = encoding
+		chain.checksummed = true
+		chain.open = true
+		chain.encoder = encoder
+		chain.usage = payloadOffset + len(payload)
+
+		return nil
 	}
 
-	// Write data
-	if _, err := mmapFile.WriteAt(data, writeOffset); err != nil {
-		return fmt.Errorf("failed to write data: %v", err)
+	// Update existing header
+	if err := bs.updateBlockHeader(mmapFile, offset, data); err != nil {
+		return fmt.Errorf("failed to update header: %v", err)
 	}
 
-	// Update block usage
-	bs.blockManager.mu.Lock()
-	bs.blockManager.blockUsage[key] += len(data)
-	bs.blockManager.mu.Unlock()
+	if position.lsn != 0 {
+		if err := writeWalPosition(mmapFile, offset, position); err != nil {
+			return err
+		}
+	}
 
-	// Update index
-	indexPath := filepath.Join(partitionPath, "index.json")
-	if err := bs.updateIndex(indexPath, key, offset); err != nil {
+	if err := index.extend(uint32(key), offset, timestamp); err != nil {
 		return fmt.Errorf("failed to update index: %v", err)
 	}
 
+	chain.encoder = encoder
+	chain.usage = payloadOffset + len(payload)
+
 	return nil
 }
 
+// blockEncoding picks the encoding new blocks of a counter type are written in
+func blockEncoding(dataType byte) byte {
+	switch dataType {
+	case TypeInt, TypeFloat:
+		return EncodingGorilla
+	case TypeString:
+		return EncodingDictionary
+	default:
+		return EncodingRaw
+	}
+}
+
+// encodeRecord encodes a serialized record for the block whose record region
+// has usage bytes in use. It returns where the payload goes in the region and,
+// for Gorilla blocks, the encoder state after the record; prev is not modified.
+func encodeRecord(prev *gorillaEncoder, usage int, encoding byte, data []byte) (int, []byte, *gorillaEncoder) {
+	if encoding != EncodingGorilla || len(data) < 12 {
+		return usage, data, nil
+	}
+
+	encoder := &gorillaEncoder{}
+	if prev != nil {
+		*encoder = *prev
+	}
+
+	payloadOffset, payload := encoder.append(binary.LittleEndian.Uint32(data[0:4]), binary.LittleEndian.Uint64(data[4:12]))
+
+	return payloadOffset, payload, encoder
+}
+
 // Helper function to determine data type
 func determineDataType(data []byte) byte {
 	// Skip timestamp (first 4 bytes)
@@ -250,16 +625,11 @@ func determineDataType(data []byte) byte {
 	}
 }
 
-// retrieves data for a any device using the storage path
+// retrieves data for a any device stored under the given counter path
+// without touching the engine-wide storage path
 func (bs *StorageEngine) GetByPath(deviceID int, path string) ([][]byte, error) {
 
-	if err := bs.SetStoragePath(path); err != nil {
-
-		return nil, fmt.Errorf("failed to set storage path: %v", err)
-
-	}
-
-	return bs.Get(deviceID)
+	return bs.get(path, deviceID)
 }
 
 func (bs *StorageEngine) Get(deviceID int) ([][]byte, error) {
@@ -273,6 +643,11 @@ func (bs *StorageEngine) Get(deviceID int) ([][]byte, error) {
 
 	}
 
+	return bs.get(basePath, deviceID)
+}
+
+func (bs *StorageEngine) get(basePath string, deviceID int) ([][]byte, error) {
+
 	// Calculate partition
 	partition := deviceID % NumPartitions
 
@@ -301,9 +676,7 @@ func (bs *StorageEngine) Get(deviceID int) ([][]byte, error) {
 
 	}
 
-	indexPath := filepath.Join(partitionPath, "index.json")
-
-	index, err := bs.readIndex(indexPath)
+	index, err := bs.getBlockIndex(partitionPath)
 
 	if err != nil {
 
@@ -311,37 +684,340 @@ func (bs *StorageEngine) Get(deviceID int) ([][]byte, error) {
 
 	}
 
-	// Find device's data blocks
+	// Every block of the device, following its chain from the first block
 	var results [][]byte
 
-	for _, entry := range index {
+	for _, blockOffset := range bs.chainOffsets(mmapFile, index, uint32(deviceID)) {
+
+		block := make([]byte, BlockSize)
+
+		if _, err := mmapFile.ReadAt(block, blockOffset); err != nil {
+
+			return nil, fmt.Errorf("failed to read block at offset %d: %v", blockOffset, err)
+
+		}
+
+		// Skip the header
+		data := make([]byte, BlockSize-BlockHeaderSize)
+
+		copy(data, block[BlockHeaderSize:])
+
+		results = append(results, data)
+
+	}
+
+	return results, nil
+}
+
+// GetRange returns views of the device's blocks under the engine-wide storage
+// path that may hold records between from and to, inclusive
+func (bs *StorageEngine) GetRange(deviceID int, from uint32, to uint32) ([]BlockView, error) {
+
+	basePath := bs.getStoragePath()
+
+	if basePath == "" {
 
-		if entry.DeviceID == deviceID {
+		return nil, fmt.Errorf("storage path not set")
 
-			block := make([]byte, BlockSize)
+	}
 
-			if _, err := mmapFile.ReadAt(block, entry.BlockOffset); err != nil {
+	return bs.getRange(context.Background(), basePath, deviceID, from, to, 0, 0)
+}
+
+// GetRangeByPath is GetRange for the counter directory at path
+func (bs *StorageEngine) GetRangeByPath(deviceID int, path string, from uint32, to uint32) ([]BlockView, error) {
 
-				return nil, fmt.Errorf("failed to read block at offset %d: %v", entry.BlockOffset, err)
+	return bs.getRange(context.Background(), path, deviceID, from, to, 0, 0)
+}
+
+// GetRangeByPathContext is GetRangeByPath that gives up once ctx is done,
+// returning the error of ctx
+func (bs *StorageEngine) GetRangeByPathContext(ctx context.Context, deviceID int, path string, from uint32, to uint32) ([]BlockView, error) {
+
+	return bs.getRange(ctx, path, deviceID, from, to, 0, 0)
+}
+
+// GetRangeByPathFrom is GetRangeByPathContext returning at most maxBlocks
+// views, 0 being no limit, from the block at startOffset on. A device's
+// blocks are returned in the order they were written, so a read stopped at
+// the view with some Offset resumes there without viewing those before it.
+func (bs *StorageEngine) GetRangeByPathFrom(ctx context.Context, deviceID int, path string, from uint32, to uint32, startOffset int64, maxBlocks int) ([]BlockView, error) {
+
+	return bs.getRange(ctx, path, deviceID, from, to, startOffset, maxBlocks)
+}
+
+// getRange prunes blocks by the time range kept in the index, then confirms
+// ownership and range against the block header before handing out a view.
+// Nothing is copied: the views alias the mapped data file, which they pin
+// until released.
+func (bs *StorageEngine) getRange(ctx context.Context, basePath string, deviceID int, from uint32, to uint32, startOffset int64, maxBlocks int) ([]BlockView, error) {
+
+	if err := ctx.Err(); err != nil {
+
+		return nil, err
+
+	}
+
+	partition := deviceID % NumPartitions
+
+	partitionPath := filepath.Join(basePath, fmt.Sprintf("partition_%d", partition))
+
+	bs.partitionLocks[partition].RLock()
+
+	defer bs.partitionLocks[partition].RUnlock()
+
+	dataFile := filepath.Join(partitionPath, "data.bin")
+
+	if _, err := os.Stat(dataFile); os.IsNotExist(err) {
+
+		return nil, nil
+
+	}
+
+	mmapFile, err := bs.getMappedDataFile(dataFile)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("failed to get mapped file: %v", err)
+
+	}
+
+	index, err := bs.getBlockIndex(partitionPath)
+
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	var views []BlockView
+
+	for _, entry := range index.deviceBlocksFrom(uint32(deviceID), startOffset) {
+
+		if maxBlocks > 0 && len(views) == maxBlocks {
+			break
+		}
+
+		if entry.EndTimestamp < from || entry.StartTimestamp > to {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+
+			ReleaseViews(views)
+
+			return nil, err
+
+		}
+
+		block, region, err := mmapFile.pinnedView(entry.BlockOffset, BlockSize)
+
+		if err != nil {
+
+			ReleaseViews(views)
+
+			return nil, fmt.Errorf("failed to view block at offset %d: %v", entry.BlockOffset, err)
+
+		}
+
+		header := decodeBlockHeader(block[:BlockHeaderSize])
+
+		if header.Sealed() {
+
+			atomic.AddUint64(&bs.blocksVerified, 1)
+
+			if !verifyBlock(block) {
+
+				atomic.AddUint64(&bs.checksumFailures, 1)
+
+				log.Printf("Checksum mismatch in block %d of %s (device %d)", entry.BlockOffset, dataFile, deviceID)
+
+				region.unpin()
+
+				views = append(views, BlockView{Offset: entry.BlockOffset, Header: header, Corrupt: true})
+
+				continue
 
 			}
 
-			// Skip the header
-			data := make([]byte, BlockSize-BlockHeaderSize)
+		}
+
+		if header.DeviceID != uint32(deviceID) || header.RecordCount == 0 || header.EndTimestamp < from || header.StartTimestamp > to {
+
+			region.unpin()
+
+			continue
+
+		}
+
+		view := BlockView{
+			Offset:  entry.BlockOffset,
+			Header:  header,
+			Data:    block[BlockHeaderSize:],
+			mapping: region,
+		}
+
+		if header.Checksummed() {
+			view.Data = block[BlockHeaderSize:blockRecordsEnd]
+		}
+
+		if header.Encoding() == EncodingDictionary {
+
+			if view.Dictionary, err = bs.getDictionary(partitionPath); err != nil {
 
-			copy(data, block[BlockHeaderSize:])
+				region.unpin()
 
-			results = append(results, data)
+				ReleaseViews(views)
+
+				return nil, err
+
+			}
 
 		}
 
+		views = append(views, view)
 	}
 
-	return results, nil
+	return views, nil
 }
 
 func (bs *StorageEngine) Close() error {
 
+	if bs.wal != nil {
+
+		close(bs.stopCheckpoint)
+
+		<-bs.checkpointDone
+
+		if err := bs.Checkpoint(); err != nil {
+
+			log.Printf("Final checkpoint failed, batches stay in the write-ahead log: %v", err)
+
+		}
+
+		if err := bs.wal.close(); err != nil {
+
+			log.Printf("Error closing write-ahead log: %v", err)
+
+		}
+
+		bs.wal = nil
+
+	} else if err := bs.sealOpenBlocks(); err != nil {
+
+		log.Printf("Error sealing open blocks: %v", err)
+
+	}
+
+	bs.indexesLock.Lock()
+
+	for path, index := range bs.indexes {
+
+		if err := index.close(); err != nil {
+
+			log.Printf("Error closing block index %s: %v", path, err)
+
+		}
+
+		delete(bs.indexes, path)
+
+	}
+
+	bs.indexesLock.Unlock()
+
+	bs.dictionariesLock.Lock()
+
+	for path, dict := range bs.dictionaries {
+
+		if err := dict.close(); err != nil {
+
+			log.Printf("Error closing string dictionary %s: %v", path, err)
+
+		}
+
+		delete(bs.dictionaries, path)
+
+	}
+
+	bs.dictionariesLock.Unlock()
+
+	return bs.closeMappedFiles()
+}
+
+// sealOpenBlocks seals the block every device is appending to, so that
+// what a checkpoint or shutdown leaves behind is covered by checksums. The
+// next record of a device reopens its block.
+func (bs *StorageEngine) sealOpenBlocks() error {
+
+	bs.blockManager.mu.Lock()
+
+	partitions := make(map[string]*partitionBlocks, len(bs.blockManager.partitions))
+
+	for partitionPath, state := range bs.blockManager.partitions {
+
+		partitions[partitionPath] = state
+
+	}
+
+	bs.blockManager.mu.Unlock()
+
+	for partitionPath, state := range partitions {
+
+		if err := bs.sealPartition(partitionPath, state); err != nil {
+
+			return err
+
+		}
+
+	}
+
+	return nil
+}
+
+// sealPartition seals the open blocks of one partition's devices
+func (bs *StorageEngine) sealPartition(partitionPath string, state *partitionBlocks) error {
+
+	bs.partitionLocks[state.partition].Lock()
+
+	defer bs.partitionLocks[state.partition].Unlock()
+
+	var mmapFile *MappedFile
+
+	for deviceID, chain := range state.devices {
+
+		if !chain.open {
+
+			continue
+
+		}
+
+		if mmapFile == nil {
+
+			var err error
+
+			if mmapFile, err = bs.getMappedDataFile(filepath.Join(partitionPath, "data.bin")); err != nil {
+
+				return fmt.Errorf("failed to get mapped file: %v", err)
+
+			}
+
+		}
+
+		if err := bs.sealBlock(mmapFile, chain.lastBlock); err != nil {
+
+			return fmt.Errorf("failed to seal block %d of device %d: %v", chain.lastBlock, deviceID, err)
+
+		}
+
+		chain.open = false
+
+	}
+
+	return nil
+}
+
+func (bs *StorageEngine) closeMappedFiles() error {
+
 	bs.mmapFilesLock.Lock()
 
 	defer bs.mmapFilesLock.Unlock()
@@ -376,60 +1052,17 @@ func (bs *StorageEngine) initializeBlockManagerState() error {
 		return nil // No storage path set yet, skip initialization
 	}
 
-	// Scan through all partitions
+	// Load the block index of every partition that already holds data
 	for partition := 0; partition < NumPartitions; partition++ {
 		partitionPath := filepath.Join(basePath, fmt.Sprintf("partition_%d", partition))
-		indexPath := filepath.Join(partitionPath, "index.json")
 
-		// Read index file if it exists
-		index, err := bs.readIndex(indexPath)
-		if err != nil {
-			continue // Skip this partition if there's an error
+		if _, err := os.Stat(filepath.Join(partitionPath, "data.bin")); err != nil {
+			continue
 		}
 
-		// Update block manager state from index entries
-		for _, entry := range index {
-			deviceID := entry.DeviceID
-			bs.blockManager.mu.Lock()
-
-			// Update nextOffset if this block offset is higher than what we have
-			nextOffset := entry.BlockOffset + BlockSize
-			if currentNext, exists := bs.blockManager.nextOffset[deviceID]; !exists || nextOffset > currentNext {
-				bs.blockManager.nextOffset[deviceID] = nextOffset
-			}
-
-			// Set current block and usage if this is the current block
-			if entry.CurrentOffset != 0 {
-				bs.blockManager.currentBlock[deviceID] = entry.BlockOffset
-				// Read the block header to get current usage
-				if usage, err := bs.getBlockUsageFromHeader(entry.BlockOffset, partition); err == nil {
-					bs.blockManager.blockUsage[deviceID] = usage
-				}
-			}
-
-			bs.blockManager.mu.Unlock()
+		if _, err := bs.getBlockIndex(partitionPath); err != nil {
+			log.Printf("Warning: failed to load block index for %s: %v", partitionPath, err)
 		}
 	}
 	return nil
 }
-
-// Add helper function to get block usage from header
-func (bs *StorageEngine) getBlockUsageFromHeader(offset int64, partition int) (int, error) {
-	partitionPath := filepath.Join(bs.getStoragePath(), fmt.Sprintf("partition_%d", partition))
-	dataFile := filepath.Join(partitionPath, "data.bin")
-
-	mmapFile, err := bs.getMappedDataFile(dataFile)
-	if err != nil {
-		return 0, err
-	}
-
-	headerData := make([]byte, BlockHeaderSize)
-	if _, err := mmapFile.ReadAt(headerData, offset); err != nil {
-		return 0, err
-	}
-
-	header := decodeBlockHeader(headerData)
-	// Calculate usage based on record count and data type
-	// This is a simplified calculation - you might need to adjust based on your actual data structure
-	return int(header.RecordCount) * 12, nil // Assuming each record is 12 bytes (timestamp + value)
-}
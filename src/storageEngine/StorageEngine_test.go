@@ -0,0 +1,139 @@
+package storageEngine
+
+import (
+	"context"
+	"math"
+	. "packx/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetRangeSkipsBlocksOutsideWindow(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	defer engine.Close()
+
+	// noisy values compress poorly, so this spans several blocks
+	var records [][]byte
+	for i := 0; i < 1000; i++ {
+		records = append(records, floatRecord(uint32(10000+i), math.Sin(float64(i))))
+	}
+	if err := engine.PutBatch(counterPath, 7, TypeFloat, records); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+
+	all, err := engine.GetRangeByPath(7, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+	if len(all) < 2 {
+		t.Fatalf("expected several blocks for the full range, got %d", len(all))
+	}
+
+	last, err := engine.GetRangeByPath(7, counterPath, 10990, 10999)
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+	if len(last) != 1 || last[0].Offset != all[len(all)-1].Offset {
+		t.Fatalf("expected only the last block, got %+v", last)
+	}
+
+	none, err := engine.GetRangeByPath(7, counterPath, 20000, 30000)
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+	if len(none) != 0 {
+		t.Fatalf("expected no blocks past the data, got %d", len(none))
+	}
+
+	// views alias the mapping, so a record written later shows up without a new read
+	view := last[0]
+	if err := engine.PutBatch(counterPath, 7, TypeFloat, [][]byte{floatRecord(11000, 1)}); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	decoder := NewGorillaDecoder(view.Data, view.Header.RecordCount+1)
+	var timestamp uint32
+	for {
+		ts, _, err := decoder.Next()
+		if err != nil {
+			break
+		}
+		timestamp = ts
+	}
+	if timestamp != 11000 {
+		t.Fatalf("expected the appended record to be visible through the view, last timestamp %d", timestamp)
+	}
+}
+
+func TestGetRangeByPathFromResumesAtBlock(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	defer engine.Close()
+
+	// another device's blocks interleave with those of device 7
+	for batch := 0; batch < 4; batch++ {
+		for _, device := range []int{7, 9} {
+			var records [][]byte
+			for i := 0; i < 500; i++ {
+				records = append(records, floatRecord(uint32(10000+batch*500+i), math.Sin(float64(i*device))))
+			}
+			if err := engine.PutBatch(counterPath, device, TypeFloat, records); err != nil {
+				t.Fatalf("PutBatch: %v", err)
+			}
+		}
+	}
+
+	all, err := engine.GetRangeByPath(7, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+	defer ReleaseViews(all)
+	if len(all) < 4 {
+		t.Fatalf("expected several blocks, got %d", len(all))
+	}
+
+	tests := []struct {
+		name string
+
+		startOffset int64
+
+		maxBlocks int
+
+		want []BlockView
+	}{
+		{name: "from the start", startOffset: 0, maxBlocks: 0, want: all},
+		{name: "at a block", startOffset: all[2].Offset, maxBlocks: 0, want: all[2:]},
+		{name: "past a block", startOffset: all[1].Offset + 1, maxBlocks: 0, want: all[2:]},
+		{name: "batch", startOffset: all[1].Offset, maxBlocks: 2, want: all[1:3]},
+		{name: "past the last block", startOffset: all[len(all)-1].Offset + 1, maxBlocks: 2, want: nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			views, err := engine.GetRangeByPathFrom(context.Background(), 7, counterPath, 0, ^uint32(0), tc.startOffset, tc.maxBlocks)
+			if err != nil {
+				t.Fatalf("GetRangeByPathFrom: %v", err)
+			}
+			defer ReleaseViews(views)
+
+			if len(views) != len(tc.want) {
+				t.Fatalf("expected %d blocks, got %d", len(tc.want), len(views))
+			}
+			for i := range views {
+				if views[i].Offset != tc.want[i].Offset {
+					t.Fatalf("block %d: expected offset %d, got %d", i, tc.want[i].Offset, views[i].Offset)
+				}
+			}
+		})
+	}
+}
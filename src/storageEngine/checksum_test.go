@@ -0,0 +1,151 @@
+package storageEngine
+
+import (
+	"math"
+	. "packx/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestCorruptBlockIsReported(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	defer engine.Close()
+
+	var records [][]byte
+	for i := 0; i < 1000; i++ {
+		records = append(records, floatRecord(uint32(1000+i), math.Sin(float64(i))))
+	}
+	if err := engine.PutBatch(counterPath, 1, TypeFloat, records); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	// the checkpoint seals the block still being appended to
+	if err := engine.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+
+	views, err := engine.GetRangeByPath(1, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+	for _, view := range views {
+		if view.Corrupt || !view.Header.Sealed() {
+			t.Fatalf("expected intact sealed blocks, got %+v", view.Header)
+		}
+	}
+
+	// flip a bit in the records of the first block
+	mmapFile, _ := engine.getMappedDataFile(filepath.Join(counterPath, "partition_1", "data.bin"))
+	corrupted := views[0].Offset
+	b := make([]byte, 1)
+	mmapFile.ReadAt(b, corrupted+BlockHeaderSize+100)
+	b[0] ^= 0x10
+	mmapFile.WriteAt(b, corrupted+BlockHeaderSize+100)
+
+	views, err = engine.GetRangeByPath(1, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+
+	corrupt := 0
+	for _, view := range views {
+		if view.Corrupt {
+			corrupt++
+			if view.Offset != corrupted {
+				t.Fatalf("block %d reported corrupt, expected %d", view.Offset, corrupted)
+			}
+		}
+	}
+	if corrupt != 1 {
+		t.Fatalf("expected one corrupt block, got %d", corrupt)
+	}
+
+	stats := engine.Stats()
+	if stats.ChecksumFailures != 1 || stats.BlocksVerified != uint64(2*len(views)) {
+		t.Fatalf("unexpected stats %+v for %d blocks", stats, len(views))
+	}
+}
+
+func TestOpenBlockIsNotChecked(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	defer engine.Close()
+
+	var records [][]byte
+	for i := 0; i < 1000; i++ {
+		records = append(records, floatRecord(uint32(1000+i), math.Sin(float64(i))))
+	}
+	if err := engine.PutBatch(counterPath, 1, TypeFloat, records); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+
+	// blocks are sealed as the device moves on; the last one is still open
+	views, err := engine.GetRangeByPath(1, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+	if len(views) < 2 {
+		t.Fatalf("expected several blocks, got %d", len(views))
+	}
+	for i, view := range views {
+		if view.Corrupt || !view.Header.Checksummed() || view.Header.Sealed() != (i < len(views)-1) {
+			t.Fatalf("unexpected header of block %d of %d: %+v", i, len(views), view.Header)
+		}
+	}
+
+	// a crash leaves the checksum of the open block stale, which costs nothing
+	mmapFile, _ := engine.getMappedDataFile(filepath.Join(counterPath, "partition_1", "data.bin"))
+	open := views[len(views)-1]
+	mmapFile.WriteAt([]byte{1, 2, 3, 4}, open.Offset+BlockSize-BlockChecksumSize)
+
+	views, err = engine.GetRangeByPath(1, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+	last := views[len(views)-1]
+	if last.Corrupt || last.Header.RecordCount != open.Header.RecordCount {
+		t.Fatalf("expected the open block read in full, got %+v", last)
+	}
+	if stats := engine.Stats(); stats.ChecksumFailures != 0 {
+		t.Fatalf("unexpected checksum failures %+v", stats)
+	}
+
+	// the checkpoint seals it, and the next record reopens it
+	if err := engine.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	views, err = engine.GetRangeByPath(1, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+	for _, view := range views {
+		if view.Corrupt || !view.Header.Sealed() {
+			t.Fatalf("expected sealed blocks after the checkpoint, got %+v", view.Header)
+		}
+	}
+
+	if err := engine.PutBatch(counterPath, 1, TypeFloat, [][]byte{floatRecord(5000, 1)}); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	views, err = engine.GetRangeByPath(1, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+	last = views[len(views)-1]
+	if last.Corrupt || last.Header.Sealed() || last.Header.RecordCount != open.Header.RecordCount+1 {
+		t.Fatalf("expected the last block reopened with one more record, got %+v", last)
+	}
+	if stats := engine.Stats(); stats.ChecksumFailures != 0 {
+		t.Fatalf("unexpected checksum failures %+v", stats)
+	}
+}
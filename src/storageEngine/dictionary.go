@@ -0,0 +1,285 @@
+package storageEngine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// String dictionaries. Every partition keeps a strings.dict next to its
+// data.bin listing the distinct string values written to it that day, in
+// the order they were first seen:
+//
+//	entry := length(4) value(length)
+//
+// A value's ID is its position in the file. Dictionary encoded blocks store
+// fixed-size records of timestamp(4) id(4) instead of the raw strings.
+
+const (
+	dictionaryFileName = "strings.dict"
+
+	// dictionaryRecordSize is the size of a record in a dictionary encoded block
+	dictionaryRecordSize = 8
+)
+
+// StringDictionary maps the string IDs of a partition back to their values
+type StringDictionary struct {
+	mu sync.RWMutex
+
+	file *os.File
+
+	size int64
+
+	values []string
+
+	ids map[string]uint32
+}
+
+// Lookup returns the string stored under id
+func (d *StringDictionary) Lookup(id uint32) (string, bool) {
+
+	d.mu.RLock()
+
+	defer d.mu.RUnlock()
+
+	if int(id) >= len(d.values) {
+
+		return "", false
+
+	}
+
+	return d.values[id], true
+}
+
+// Values returns every distinct string of the partition, in ID order
+func (d *StringDictionary) Values() []string {
+
+	d.mu.RLock()
+
+	defer d.mu.RUnlock()
+
+	values := make([]string, len(d.values))
+
+	copy(values, d.values)
+
+	return values
+}
+
+// id returns the ID of value, adding it to the dictionary if it is new. New
+// values are synced right away; they are rare once a counter's states have
+// all been seen, and a block must never refer to an ID that a crash lost.
+func (d *StringDictionary) id(value string) (uint32, error) {
+
+	d.mu.RLock()
+
+	id, exists := d.ids[value]
+
+	d.mu.RUnlock()
+
+	if exists {
+
+		return id, nil
+
+	}
+
+	d.mu.Lock()
+
+	defer d.mu.Unlock()
+
+	if id, exists := d.ids[value]; exists {
+
+		return id, nil
+
+	}
+
+	entry := make([]byte, 4+len(value))
+
+	binary.LittleEndian.PutUint32(entry[0:4], uint32(len(value)))
+
+	copy(entry[4:], value)
+
+	if _, err := d.file.WriteAt(entry, d.size); err != nil {
+
+		return 0, fmt.Errorf("failed to append dictionary entry: %v", err)
+
+	}
+
+	if err := d.file.Sync(); err != nil {
+
+		return 0, fmt.Errorf("failed to sync dictionary: %v", err)
+
+	}
+
+	id = uint32(len(d.values))
+
+	d.size += int64(len(entry))
+
+	d.values = append(d.values, value)
+
+	d.ids[value] = id
+
+	return id, nil
+}
+
+func (d *StringDictionary) close() error {
+
+	d.mu.Lock()
+
+	defer d.mu.Unlock()
+
+	return d.file.Close()
+}
+
+// getDictionary returns the string dictionary of a partition, loading it from
+// strings.dict on first use
+func (bs *StorageEngine) getDictionary(partitionPath string) (*StringDictionary, error) {
+
+	bs.dictionariesLock.Lock()
+
+	defer bs.dictionariesLock.Unlock()
+
+	if dict, exists := bs.dictionaries[partitionPath]; exists {
+
+		return dict, nil
+
+	}
+
+	if err := os.MkdirAll(partitionPath, 0755); err != nil {
+
+		return nil, fmt.Errorf("failed to create partition directory: %v", err)
+
+	}
+
+	file, err := os.OpenFile(filepath.Join(partitionPath, dictionaryFileName), os.O_RDWR|os.O_CREATE, 0644)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("failed to open dictionary: %v", err)
+
+	}
+
+	dict, err := readDictionary(file)
+
+	if err != nil {
+
+		file.Close()
+
+		return nil, err
+
+	}
+
+	bs.dictionaries[partitionPath] = dict
+
+	return dict, nil
+}
+
+// readDictionary loads every complete entry of a dictionary file. A trailing
+// partial entry, left by a crash in the middle of an append, is cut off.
+func readDictionary(file *os.File) (*StringDictionary, error) {
+
+	info, err := file.Stat()
+
+	if err != nil {
+
+		return nil, fmt.Errorf("failed to stat dictionary: %v", err)
+
+	}
+
+	data := make([]byte, info.Size())
+
+	if _, err := file.ReadAt(data, 0); err != nil && len(data) > 0 {
+
+		return nil, fmt.Errorf("failed to read dictionary: %v", err)
+
+	}
+
+	values, offset := parseDictionary(data)
+
+	dict := &StringDictionary{
+		file:   file,
+		values: values,
+		ids:    make(map[string]uint32, len(values)),
+	}
+
+	for id, value := range values {
+		dict.ids[value] = uint32(id)
+	}
+
+	if offset < len(data) {
+
+		log.Printf("Dictionary %s has a partial trailing entry, truncating", file.Name())
+
+		if err := file.Truncate(int64(offset)); err != nil {
+
+			return nil, fmt.Errorf("failed to truncate dictionary: %v", err)
+
+		}
+
+	}
+
+	dict.size = int64(offset)
+
+	return dict, nil
+}
+
+// parseDictionary decodes the complete entries of a dictionary file and
+// returns them with the length of the data they cover
+func parseDictionary(data []byte) ([]string, int) {
+
+	var values []string
+
+	offset := 0
+
+	for offset+4 <= len(data) {
+
+		length := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
+
+		if offset+4+length > len(data) {
+			break
+		}
+
+		values = append(values, string(data[offset+4:offset+4+length]))
+
+		offset += 4 + length
+	}
+
+	return values, offset
+}
+
+// dictionaryEncode turns a serialized string record, timestamp(4) length(4)
+// value, into the timestamp(4) id(4) record of a dictionary encoded block
+func dictionaryEncode(dict *StringDictionary, data []byte) ([]byte, error) {
+
+	if len(data) < 8 {
+
+		return nil, fmt.Errorf("invalid string record: %d bytes", len(data))
+
+	}
+
+	length := int(binary.LittleEndian.Uint32(data[4:8]))
+
+	if 8+length > len(data) {
+
+		return nil, fmt.Errorf("invalid string record: length %d exceeds record", length)
+
+	}
+
+	id, err := dict.id(string(data[8 : 8+length]))
+
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	record := make([]byte, dictionaryRecordSize)
+
+	copy(record[0:4], data[0:4])
+
+	binary.LittleEndian.PutUint32(record[4:8], id)
+
+	return record, nil
+}
@@ -0,0 +1,117 @@
+package storageEngine
+
+import (
+	"encoding/binary"
+	"os"
+	. "packx/utils"
+	"path/filepath"
+	"testing"
+)
+
+func stringRecord(timestamp uint32, value string) []byte {
+	buf := make([]byte, 8+len(value))
+	binary.LittleEndian.PutUint32(buf[0:4], timestamp)
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(len(value)))
+	copy(buf[8:], value)
+	return buf
+}
+
+func TestStringRecordsAreDictionaryEncoded(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_3")
+	states := []string{"up", "down", "degraded"}
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+
+	var records [][]byte
+	for i := 0; i < 300; i++ {
+		records = append(records, stringRecord(uint32(1000+i), states[i%len(states)]))
+	}
+	if err := engine.PutBatch(counterPath, 5, TypeString, records); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	engine.Close()
+
+	reopened, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	if err := reopened.PutBatch(counterPath, 5, TypeString, [][]byte{stringRecord(1300, "up"), stringRecord(1301, "maintenance")}); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+
+	views, err := reopened.GetRangeByPath(5, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+	if len(views) != 1 {
+		t.Fatalf("expected one block for 302 small records, got %d", len(views))
+	}
+
+	view := views[0]
+	if view.Header.Encoding() != EncodingDictionary || view.Dictionary == nil {
+		t.Fatalf("expected a dictionary encoded block, got type %#x", view.Header.DataType)
+	}
+	if view.Header.RecordCount != 302 {
+		t.Fatalf("expected 302 records, got %d", view.Header.RecordCount)
+	}
+	if got := view.Dictionary.Values(); len(got) != 4 {
+		t.Fatalf("expected 4 distinct values, got %v", got)
+	}
+
+	for i := 0; i < int(view.Header.RecordCount); i++ {
+		record := view.Data[i*dictionaryRecordSize : (i+1)*dictionaryRecordSize]
+		value, found := view.Dictionary.Lookup(binary.LittleEndian.Uint32(record[4:8]))
+		if !found {
+			t.Fatalf("record %d refers to an unknown string", i)
+		}
+
+		expected := "up"
+		switch {
+		case i < 300:
+			expected = states[i%len(states)]
+		case i == 301:
+			expected = "maintenance"
+		}
+		if value != expected {
+			t.Fatalf("record %d: got %q, expected %q", i, value, expected)
+		}
+	}
+}
+
+func TestDictionaryDropsPartialEntry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), dictionaryFileName)
+
+	data := append(stringRecord(0, "up")[4:], 9, 0, 0, 0, 'd', 'o')
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+
+	dict, err := readDictionary(file)
+	if err != nil {
+		t.Fatalf("readDictionary: %v", err)
+	}
+	defer dict.close()
+
+	if values := dict.Values(); len(values) != 1 || values[0] != "up" {
+		t.Fatalf("unexpected values %v", values)
+	}
+
+	id, err := dict.id("down")
+	if err != nil || id != 1 {
+		t.Fatalf("expected the next value to get ID 1, got %d (%v)", id, err)
+	}
+	if info, _ := os.Stat(path); info.Size() != 6+8 {
+		t.Fatalf("expected the torn entry to be replaced, file is %d bytes", info.Size())
+	}
+}
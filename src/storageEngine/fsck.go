@@ -0,0 +1,500 @@
+package storageEngine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	. "packx/utils"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Offline consistency checks for a storage directory. These read the files
+// directly instead of going through a StorageEngine, so they must only be run
+// while the server is stopped.
+
+// FsckIssueKind classifies a problem found by CheckPartition
+type FsckIssueKind string
+
+const (
+	IssueMissingIndex FsckIssueKind = "missing-index" // data.bin without index.bin
+
+	IssueLegacyIndex FsckIssueKind = "legacy-index" // only an index.json, migrated on next start
+
+	IssueTruncatedIndex FsckIssueKind = "truncated-index" // index.bin ends in a partial entry
+
+	IssueIndexOutOfRange FsckIssueKind = "index-out-of-range" // entry points outside the data file
+
+	IssueHeaderMismatch FsckIssueKind = "header-mismatch" // entry and block header disagree
+
+	IssueOverlap FsckIssueKind = "overlap" // block claimed by more than one index entry or chain
+
+	IssueOrphan FsckIssueKind = "orphan" // block holding records that the index does not list
+
+	IssueBadLength FsckIssueKind = "bad-length" // records that cannot fit or cannot be decoded
+
+	IssueChecksum FsckIssueKind = "checksum" // block does not match its checksum
+
+	IssueBrokenChain FsckIssueKind = "broken-chain" // next block offset that leads nowhere valid
+)
+
+// FsckIssue is one problem found in a partition
+type FsckIssue struct {
+	Kind FsckIssueKind
+
+	BlockOffset int64 // -1 when the issue is not about a single block
+
+	DeviceID uint32
+
+	Detail string
+}
+
+// IndexIssue reports whether the issue is fixed by rebuilding the index
+func (i FsckIssue) IndexIssue() bool {
+	switch i.Kind {
+	case IssueMissingIndex, IssueLegacyIndex, IssueTruncatedIndex, IssueIndexOutOfRange, IssueHeaderMismatch, IssueOrphan:
+		return true
+	}
+	return false
+}
+
+func (i FsckIssue) String() string {
+	if i.BlockOffset < 0 {
+		return fmt.Sprintf("%s: %s", i.Kind, i.Detail)
+	}
+	return fmt.Sprintf("%s: block %d (device %d): %s", i.Kind, i.BlockOffset, i.DeviceID, i.Detail)
+}
+
+// PartitionReport is the result of checking one partition directory
+type PartitionReport struct {
+	Path string
+
+	Blocks int // blocks in the data file that hold records
+
+	Issues []FsckIssue
+
+	Rebuilt bool // the index was rebuilt from the data file
+}
+
+// CheckStorage checks every partition below root. With repair set, the index
+// of each partition with index issues is rebuilt from its data file and the
+// partition is checked again.
+func CheckStorage(root string, repair bool) ([]PartitionReport, error) {
+
+	var partitions []string
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == walDirName && filepath.Dir(path) == filepath.Clean(root) {
+			return filepath.SkipDir
+		}
+
+		if info.IsDir() && strings.HasPrefix(info.Name(), "partition_") {
+
+			if _, err := os.Stat(filepath.Join(path, "data.bin")); err == nil {
+				partitions = append(partitions, path)
+			}
+
+			return filepath.SkipDir
+		}
+
+		return nil
+	})
+
+	if err != nil {
+
+		return nil, fmt.Errorf("failed to walk %s: %v", root, err)
+
+	}
+
+	reports := make([]PartitionReport, 0, len(partitions))
+
+	for _, partitionPath := range partitions {
+
+		report, err := CheckPartition(partitionPath)
+
+		if err != nil {
+
+			return reports, err
+
+		}
+
+		if repair && needsIndexRebuild(report.Issues) {
+
+			if _, err := RebuildIndex(partitionPath); err != nil {
+
+				return reports, err
+
+			}
+
+			if report, err = CheckPartition(partitionPath); err != nil {
+
+				return reports, err
+
+			}
+
+			report.Rebuilt = true
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func needsIndexRebuild(issues []FsckIssue) bool {
+
+	for _, issue := range issues {
+
+		if issue.IndexIssue() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CheckPartition validates the data file of a partition against its index:
+// every entry must point at a block of its device, no block may belong to two
+// devices, every block with records must be indexed, and the records of every
+// block must fit and decode.
+func CheckPartition(partitionPath string) (PartitionReport, error) {
+
+	report := PartitionReport{Path: partitionPath}
+
+	issue := func(kind FsckIssueKind, offset int64, deviceID uint32, format string, args ...interface{}) {
+		report.Issues = append(report.Issues, FsckIssue{Kind: kind, BlockOffset: offset, DeviceID: deviceID, Detail: fmt.Sprintf(format, args...)})
+	}
+
+	data, err := os.ReadFile(filepath.Join(partitionPath, "data.bin"))
+
+	if err != nil {
+
+		return report, fmt.Errorf("failed to read data file: %v", err)
+
+	}
+
+	if len(data)%BlockSize != 0 {
+
+		issue(IssueBadLength, -1, 0, "data file size %d is not a multiple of the block size", len(data))
+
+	}
+
+	entries, err := readIndexForCheck(partitionPath)
+
+	switch {
+	case os.IsNotExist(err):
+
+		if _, legacyErr := os.Stat(filepath.Join(partitionPath, legacyIndexFileName)); legacyErr == nil {
+			issue(IssueLegacyIndex, -1, 0, "partition only has %s", legacyIndexFileName)
+		} else {
+			issue(IssueMissingIndex, -1, 0, "partition has no %s", indexFileName)
+		}
+
+	case err == errTruncatedIndex:
+
+		issue(IssueTruncatedIndex, -1, 0, "%s ends in a partial entry", indexFileName)
+
+	case err != nil:
+
+		return report, err
+	}
+
+	var dictionary []string
+
+	if raw, err := os.ReadFile(filepath.Join(partitionPath, dictionaryFileName)); err == nil {
+		dictionary, _ = parseDictionary(raw)
+	}
+
+	blockCount := int64(len(data) / BlockSize)
+
+	owners := make(map[int64]uint32) // block offset -> device of the index entry
+
+	for _, entry := range entries {
+
+		if entry.BlockOffset < 0 || entry.BlockOffset%BlockSize != 0 || entry.BlockOffset/BlockSize >= blockCount {
+
+			issue(IssueIndexOutOfRange, entry.BlockOffset, entry.DeviceID, "index entry points outside the %d blocks of the data file", blockCount)
+
+			continue
+		}
+
+		if owner, exists := owners[entry.BlockOffset]; exists {
+
+			issue(IssueOverlap, entry.BlockOffset, entry.DeviceID, "block is indexed for devices %d and %d", owner, entry.DeviceID)
+
+			continue
+		}
+
+		owners[entry.BlockOffset] = entry.DeviceID
+
+		header := decodeBlockHeader(data[entry.BlockOffset : entry.BlockOffset+BlockHeaderSize])
+
+		if header.DeviceID != entry.DeviceID {
+
+			issue(IssueHeaderMismatch, entry.BlockOffset, entry.DeviceID, "block header belongs to device %d", header.DeviceID)
+
+			continue
+		}
+
+		if header.RecordCount > 0 && (header.StartTimestamp < entry.StartTimestamp || header.EndTimestamp > entry.EndTimestamp) {
+
+			issue(IssueHeaderMismatch, entry.BlockOffset, entry.DeviceID, "header range [%d, %d] is outside the indexed range [%d, %d]",
+				header.StartTimestamp, header.EndTimestamp, entry.StartTimestamp, entry.EndTimestamp)
+
+		}
+	}
+
+	chained := make(map[int64]uint32) // block offset -> device whose chain points at it
+
+	for offset := int64(0); offset/BlockSize < blockCount; offset += BlockSize {
+
+		block := data[offset : offset+BlockSize]
+
+		header := decodeBlockHeader(block[:BlockHeaderSize])
+
+		if header.RecordCount == 0 {
+			continue
+		}
+
+		report.Blocks++
+
+		if _, indexed := owners[offset]; !indexed && entries != nil {
+
+			issue(IssueOrphan, offset, header.DeviceID, "block holds %d records but is not in the index", header.RecordCount)
+
+		}
+
+		if header.Sealed() && !verifyBlock(block) {
+
+			issue(IssueChecksum, offset, header.DeviceID, "block does not match its checksum")
+
+			continue
+		}
+
+		if err := checkBlockRecords(header, block, dictionary); err != nil {
+
+			issue(IssueBadLength, offset, header.DeviceID, "%v", err)
+
+		}
+
+		if next := header.NextBlockOffset; next > offset {
+
+			if next%BlockSize != 0 || next/BlockSize >= blockCount {
+
+				issue(IssueBrokenChain, offset, header.DeviceID, "next block offset %d is outside the data file", next)
+
+			} else if nextDevice := binary.LittleEndian.Uint32(data[next : next+4]); nextDevice != header.DeviceID {
+
+				issue(IssueOverlap, next, nextDevice, "chain of device %d continues into this block", header.DeviceID)
+
+			} else if previous, exists := chained[next]; exists {
+
+				issue(IssueOverlap, next, nextDevice, "block is linked from two blocks of device %d", previous)
+
+			} else {
+
+				chained[next] = header.DeviceID
+
+			}
+		}
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].BlockOffset < report.Issues[j].BlockOffset
+	})
+
+	return report, nil
+}
+
+// RebuildIndex replaces a partition's index with one built from the headers of
+// its data file and returns the number of blocks it lists
+func RebuildIndex(partitionPath string) (int, error) {
+
+	dataFile, err := os.Open(filepath.Join(partitionPath, "data.bin"))
+
+	if err != nil {
+
+		return 0, fmt.Errorf("failed to open data file: %v", err)
+
+	}
+
+	defer dataFile.Close()
+
+	info, err := dataFile.Stat()
+
+	if err != nil {
+
+		return 0, fmt.Errorf("failed to stat data file: %v", err)
+
+	}
+
+	entries := scanBlockHeaders(dataFile, info.Size())
+
+	// write next to the index and rename, so a crash keeps the old index
+	indexPath := filepath.Join(partitionPath, indexFileName)
+
+	file, err := os.OpenFile(indexPath+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+
+	if err != nil {
+
+		return 0, fmt.Errorf("failed to create index: %v", err)
+
+	}
+
+	if err := writeIndexEntries(file, entries); err != nil {
+
+		file.Close()
+
+		return 0, err
+
+	}
+
+	if err := file.Close(); err != nil {
+
+		return 0, fmt.Errorf("failed to close index: %v", err)
+
+	}
+
+	if err := os.Rename(indexPath+".tmp", indexPath); err != nil {
+
+		return 0, fmt.Errorf("failed to replace index: %v", err)
+
+	}
+
+	legacyPath := filepath.Join(partitionPath, legacyIndexFileName)
+
+	if _, err := os.Stat(legacyPath); err == nil {
+
+		if err := os.Rename(legacyPath, legacyPath+".migrated"); err != nil {
+
+			return len(entries), fmt.Errorf("failed to set aside legacy index: %v", err)
+
+		}
+
+	}
+
+	return len(entries), nil
+}
+
+var errTruncatedIndex = fmt.Errorf("index ends in a partial entry")
+
+// readIndexForCheck decodes an index without modifying it. A partial trailing
+// entry is reported through errTruncatedIndex together with the complete ones.
+func readIndexForCheck(partitionPath string) ([]IndexEntry, error) {
+
+	data, err := os.ReadFile(filepath.Join(partitionPath, indexFileName))
+
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	entries := make([]IndexEntry, 0, len(data)/IndexEntrySize)
+
+	for pos := 0; pos+IndexEntrySize <= len(data); pos += IndexEntrySize {
+		entries = append(entries, decodeIndexEntry(data[pos:pos+IndexEntrySize]))
+	}
+
+	if len(data)%IndexEntrySize != 0 {
+
+		return entries, errTruncatedIndex
+
+	}
+
+	return entries, nil
+}
+
+// checkBlockRecords decodes the records counted by a block header and fails if
+// they do not fit in the block, fall outside the header's time range, or
+// refer to strings missing from the dictionary
+func checkBlockRecords(header BlockHeader, block []byte, dictionary []string) error {
+
+	region := block[BlockHeaderSize:]
+
+	if header.Checksummed() {
+		region = block[BlockHeaderSize:blockRecordsEnd]
+	}
+
+	inRange := func(timestamp uint32) error {
+		if timestamp < header.StartTimestamp || timestamp > header.EndTimestamp {
+			return fmt.Errorf("record timestamp %d is outside the header range [%d, %d]", timestamp, header.StartTimestamp, header.EndTimestamp)
+		}
+		return nil
+	}
+
+	switch header.Encoding() {
+
+	case EncodingGorilla:
+
+		decoder := NewGorillaDecoder(region, header.RecordCount)
+
+		for {
+			timestamp, _, err := decoder.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := inRange(timestamp); err != nil {
+				return err
+			}
+		}
+
+	case EncodingDictionary:
+
+		if int(header.RecordCount)*dictionaryRecordSize > len(region) {
+			return fmt.Errorf("%d records of %d bytes do not fit in the block", header.RecordCount, dictionaryRecordSize)
+		}
+
+		for i := 0; i < int(header.RecordCount); i++ {
+			record := region[i*dictionaryRecordSize:]
+			if err := inRange(binary.LittleEndian.Uint32(record[0:4])); err != nil {
+				return err
+			}
+			if id := binary.LittleEndian.Uint32(record[4:8]); int(id) >= len(dictionary) {
+				return fmt.Errorf("record %d refers to string %d, the dictionary has %d", i, id, len(dictionary))
+			}
+		}
+
+		return nil
+
+	case EncodingRaw:
+
+		offset := 0
+
+		for i := 0; i < int(header.RecordCount); i++ {
+
+			size := 12
+
+			if header.ValueType() == TypeString {
+				if offset+8 > len(region) {
+					return fmt.Errorf("record %d runs past the end of the block", i)
+				}
+				size = 8 + int(binary.LittleEndian.Uint32(region[offset+4:offset+8]))
+			}
+
+			if offset+size > len(region) {
+				return fmt.Errorf("record %d has an impossible length of %d bytes", i, size)
+			}
+
+			if err := inRange(binary.LittleEndian.Uint32(region[offset : offset+4])); err != nil {
+				return err
+			}
+
+			offset += size
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown block encoding %d", header.Encoding())
+}
@@ -0,0 +1,124 @@
+package storageEngine
+
+import (
+	"math"
+	"os"
+	. "packx/utils"
+	"path/filepath"
+	"testing"
+)
+
+func writeFsckFixture(t *testing.T) (string, string) {
+	t.Helper()
+
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	for i := 0; i < 600; i++ {
+		for _, device := range []int{1, 4} {
+			if err := engine.PutBatch(counterPath, device, TypeFloat, [][]byte{floatRecord(uint32(1000+i), math.Sin(float64(i*device)))}); err != nil {
+				t.Fatalf("PutBatch: %v", err)
+			}
+		}
+	}
+	engine.Close()
+
+	return root, filepath.Join(counterPath, "partition_1")
+}
+
+func TestCheckStorageCleanTree(t *testing.T) {
+	root, _ := writeFsckFixture(t)
+
+	reports, err := CheckStorage(root, false)
+	if err != nil {
+		t.Fatalf("CheckStorage: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("expected one partition, got %d", len(reports))
+	}
+	if len(reports[0].Issues) != 0 || reports[0].Blocks < 4 {
+		t.Fatalf("expected a clean partition with several blocks, got %+v", reports[0])
+	}
+}
+
+func TestCheckStorageRepairsDamagedIndex(t *testing.T) {
+	root, partitionPath := writeFsckFixture(t)
+	indexPath := filepath.Join(partitionPath, indexFileName)
+
+	// drop the last entry and leave half of it behind
+	data, _ := os.ReadFile(indexPath)
+	os.WriteFile(indexPath, data[:len(data)-IndexEntrySize/2], 0644)
+
+	reports, err := CheckStorage(root, false)
+	if err != nil {
+		t.Fatalf("CheckStorage: %v", err)
+	}
+	kinds := make(map[FsckIssueKind]int)
+	for _, issue := range reports[0].Issues {
+		kinds[issue.Kind]++
+	}
+	if kinds[IssueTruncatedIndex] != 1 || kinds[IssueOrphan] != 1 {
+		t.Fatalf("expected a truncated index and one orphan, got %v", reports[0].Issues)
+	}
+
+	reports, err = CheckStorage(root, true)
+	if err != nil {
+		t.Fatalf("CheckStorage: %v", err)
+	}
+	if !reports[0].Rebuilt || len(reports[0].Issues) != 0 {
+		t.Fatalf("expected the index to be rebuilt cleanly, got %+v", reports[0])
+	}
+	if rebuilt, _ := os.ReadFile(indexPath); len(rebuilt) != len(data) {
+		t.Fatalf("rebuilt index has %d bytes, expected %d", len(rebuilt), len(data))
+	}
+}
+
+func TestCheckPartitionFindsOverlapAndCorruptBlock(t *testing.T) {
+	_, partitionPath := writeFsckFixture(t)
+	indexPath := filepath.Join(partitionPath, indexFileName)
+
+	// point an entry of device 4 at a block of device 1
+	data, _ := os.ReadFile(indexPath)
+	entries := make([]IndexEntry, 0)
+	for pos := 0; pos < len(data); pos += IndexEntrySize {
+		entries = append(entries, decodeIndexEntry(data[pos:pos+IndexEntrySize]))
+	}
+	var first1, first4 int = -1, -1
+	for i, entry := range entries {
+		if entry.DeviceID == 1 && first1 < 0 {
+			first1 = i
+		}
+		if entry.DeviceID == 4 && first4 < 0 {
+			first4 = i
+		}
+	}
+	entries[first4].BlockOffset = entries[first1].BlockOffset
+	file, _ := os.OpenFile(indexPath, os.O_RDWR, 0644)
+	writeIndexEntries(file, entries)
+	file.Close()
+
+	// claim more records than the block of the second entry holds
+	dataPath := filepath.Join(partitionPath, "data.bin")
+	blocks, _ := os.ReadFile(dataPath)
+	second := entries[1].BlockOffset
+	header := decodeBlockHeader(blocks[second : second+BlockHeaderSize])
+	header.RecordCount += 2000
+	copy(blocks[second:], encodeBlockHeader(header))
+	os.WriteFile(dataPath, blocks, 0644)
+
+	report, err := CheckPartition(partitionPath)
+	if err != nil {
+		t.Fatalf("CheckPartition: %v", err)
+	}
+	kinds := make(map[FsckIssueKind]int)
+	for _, issue := range report.Issues {
+		kinds[issue.Kind]++
+	}
+	if kinds[IssueOverlap] != 1 || kinds[IssueOrphan] != 1 || kinds[IssueChecksum] != 1 {
+		t.Fatalf("unexpected issues %v", report.Issues)
+	}
+}
@@ -0,0 +1,413 @@
+package storageEngine
+
+import (
+	"fmt"
+	"io"
+	"math/bits"
+)
+
+// Gorilla block encoding for TypeInt and TypeFloat counters, after
+// "Gorilla: A Fast, Scalable, In-Memory Time Series Database" (VLDB 2015).
+//
+// The first record of a block is stored as a raw 32-bit timestamp and the
+// raw 64 bits of its value. Every following record stores
+//
+//	timestamp: delta-of-delta against the previous two timestamps
+//	  '0'                  dod == 0
+//	  '10'   + 7 bits      dod in [-64, 63]
+//	  '110'  + 9 bits      dod in [-256, 255]
+//	  '1110' + 12 bits     dod in [-2048, 2047]
+//	  '1111' + 64 bits     anything else
+//
+//	value: xor against the previous value's bits
+//	  '0'                  identical value
+//	  '10' + bits          meaningful bits fit the previous leading/trailing window
+//	  '11' + 5 bits leading zeros + 6 bits length + bits
+//
+// Integers are encoded through the same xor path on their two's complement
+// bits. Bits are packed most significant first.
+
+// gorillaEncoder holds the state needed to append to a device's current block.
+// It is rebuilt from the block contents after a restart.
+type gorillaEncoder struct {
+	bitCount int // bits of the block's record region in use
+
+	lastByte byte // contents of the partially filled final byte
+
+	count uint32
+
+	prevTimestamp uint32
+
+	prevDelta int64
+
+	prevValue uint64
+
+	hasWindow bool
+
+	leading int
+
+	trailing int
+}
+
+// append encodes one record and returns where in the block's record region
+// the patch goes. The patch starts with the partially filled byte, if any,
+// so it can be written over the region as is.
+func (e *gorillaEncoder) append(timestamp uint32, value uint64) (int, []byte) {
+
+	patchOffset := e.bitCount / 8
+
+	w := bitAppender{n: e.bitCount % 8}
+
+	if w.n > 0 {
+		w.buf = append(w.buf, e.lastByte)
+	}
+
+	if e.count == 0 {
+
+		w.write(uint64(timestamp), 32)
+
+		w.write(value, 64)
+
+	} else {
+
+		delta := int64(timestamp) - int64(e.prevTimestamp)
+
+		dod := delta - e.prevDelta
+
+		switch {
+		case dod == 0:
+			w.write(0, 1)
+		case dod >= -64 && dod <= 63:
+			w.write(0b10, 2)
+			w.write(uint64(dod), 7)
+		case dod >= -256 && dod <= 255:
+			w.write(0b110, 3)
+			w.write(uint64(dod), 9)
+		case dod >= -2048 && dod <= 2047:
+			w.write(0b1110, 4)
+			w.write(uint64(dod), 12)
+		default:
+			w.write(0b1111, 4)
+			w.write(uint64(dod), 64)
+		}
+
+		e.prevDelta = delta
+
+		xor := value ^ e.prevValue
+
+		if xor == 0 {
+
+			w.write(0, 1)
+
+		} else {
+
+			leading := bits.LeadingZeros64(xor)
+
+			trailing := bits.TrailingZeros64(xor)
+
+			if leading > 31 {
+				leading = 31 // only 5 bits to store it
+			}
+
+			if e.hasWindow && leading >= e.leading && trailing >= e.trailing {
+
+				w.write(0b10, 2)
+
+				w.write(xor>>uint(e.trailing), 64-e.leading-e.trailing)
+
+			} else {
+
+				significant := 64 - leading - trailing
+
+				w.write(0b11, 2)
+
+				w.write(uint64(leading), 5)
+
+				w.write(uint64(significant), 6) // 64 wraps to 0
+
+				w.write(xor>>uint(trailing), significant)
+
+				e.hasWindow = true
+
+				e.leading = leading
+
+				e.trailing = trailing
+			}
+		}
+	}
+
+	e.prevTimestamp = timestamp
+
+	e.prevValue = value
+
+	e.count++
+
+	e.bitCount = patchOffset*8 + w.n
+
+	e.lastByte = 0
+
+	if e.bitCount%8 != 0 {
+		e.lastByte = w.buf[len(w.buf)-1]
+	}
+
+	return patchOffset, w.buf
+}
+
+// size returns the bytes of the record region in use
+func (e *gorillaEncoder) size() int {
+
+	return (e.bitCount + 7) / 8
+}
+
+type bitAppender struct {
+	buf []byte
+
+	n int // bits used in buf
+}
+
+func (w *bitAppender) write(value uint64, nbits int) {
+
+	for i := nbits - 1; i >= 0; i-- {
+
+		if w.n%8 == 0 {
+			w.buf = append(w.buf, 0)
+		}
+
+		if (value>>uint(i))&1 == 1 {
+			w.buf[w.n/8] |= 0x80 >> uint(w.n%8)
+		}
+
+		w.n++
+	}
+}
+
+// GorillaDecoder reads the records of a Gorilla encoded block
+type GorillaDecoder struct {
+	data []byte
+
+	pos int // bit position
+
+	remaining uint32
+
+	count uint32
+
+	prevTimestamp uint32
+
+	prevDelta int64
+
+	prevValue uint64
+
+	hasWindow bool
+
+	leading int
+
+	trailing int
+}
+
+// NewGorillaDecoder decodes the first recordCount records of a block's record region
+func NewGorillaDecoder(data []byte, recordCount uint32) *GorillaDecoder {
+
+	return &GorillaDecoder{
+		data:      data,
+		remaining: recordCount,
+	}
+}
+
+// Next returns the next record's timestamp and raw value bits, or io.EOF once
+// every record has been read
+func (d *GorillaDecoder) Next() (uint32, uint64, error) {
+
+	if d.remaining == 0 {
+
+		return 0, 0, io.EOF
+
+	}
+
+	if d.count == 0 {
+
+		timestamp, err := d.read(32)
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		value, err := d.read(64)
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		d.prevTimestamp = uint32(timestamp)
+
+		d.prevValue = value
+
+		d.count++
+
+		d.remaining--
+
+		return d.prevTimestamp, d.prevValue, nil
+	}
+
+	dod, err := d.readDeltaOfDelta()
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	delta := d.prevDelta + dod
+
+	d.prevDelta = delta
+
+	d.prevTimestamp = uint32(int64(d.prevTimestamp) + delta)
+
+	control, err := d.read(1)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if control == 1 {
+
+		newWindow, err := d.read(1)
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		if newWindow == 1 {
+
+			leading, err := d.read(5)
+
+			if err != nil {
+				return 0, 0, err
+			}
+
+			significant, err := d.read(6)
+
+			if err != nil {
+				return 0, 0, err
+			}
+
+			if significant == 0 {
+				significant = 64
+			}
+
+			d.hasWindow = true
+
+			d.leading = int(leading)
+
+			d.trailing = 64 - int(leading) - int(significant)
+
+			if d.trailing < 0 {
+				return 0, 0, fmt.Errorf("corrupt gorilla block: invalid xor window")
+			}
+		}
+
+		xor, err := d.read(64 - d.leading - d.trailing)
+
+		if err != nil {
+			return 0, 0, err
+		}
+
+		d.prevValue ^= xor << uint(d.trailing)
+	}
+
+	d.count++
+
+	d.remaining--
+
+	return d.prevTimestamp, d.prevValue, nil
+}
+
+func (d *GorillaDecoder) readDeltaOfDelta() (int64, error) {
+
+	// count the leading ones of the control prefix, at most four
+	prefix := 0
+
+	for prefix < 4 {
+
+		bit, err := d.read(1)
+
+		if err != nil {
+			return 0, err
+		}
+
+		if bit == 0 {
+			break
+		}
+
+		prefix++
+	}
+
+	var width int
+
+	switch prefix {
+	case 0:
+		return 0, nil
+	case 1:
+		width = 7
+	case 2:
+		width = 9
+	case 3:
+		width = 12
+	default:
+		width = 64
+	}
+
+	raw, err := d.read(width)
+
+	if err != nil {
+		return 0, err
+	}
+
+	// sign-extend the two's complement field
+	if width < 64 && raw&(1<<uint(width-1)) != 0 {
+		raw |= ^uint64(0) << uint(width)
+	}
+
+	return int64(raw), nil
+}
+
+func (d *GorillaDecoder) read(nbits int) (uint64, error) {
+
+	if d.pos+nbits > len(d.data)*8 {
+
+		return 0, fmt.Errorf("corrupt gorilla block: record runs past the end of the block")
+
+	}
+
+	var value uint64
+
+	for i := 0; i < nbits; i++ {
+
+		bit := (d.data[d.pos/8] >> uint(7-d.pos%8)) & 1
+
+		value = value<<1 | uint64(bit)
+
+		d.pos++
+	}
+
+	return value, nil
+}
+
+// encoder returns an encoder positioned after the records decoded so far, so
+// that appending continues the block seamlessly
+func (d *GorillaDecoder) encoder() *gorillaEncoder {
+
+	e := &gorillaEncoder{
+		bitCount:      d.pos,
+		count:         d.count,
+		prevTimestamp: d.prevTimestamp,
+		prevDelta:     d.prevDelta,
+		prevValue:     d.prevValue,
+		hasWindow:     d.hasWindow,
+		leading:       d.leading,
+		trailing:      d.trailing,
+	}
+
+	if d.pos%8 != 0 {
+		e.lastByte = d.data[d.pos/8] & (0xff << uint(8-d.pos%8))
+	}
+
+	return e
+}
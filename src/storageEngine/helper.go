@@ -2,8 +2,10 @@ package storageEngine
 
 import (
 	"encoding/binary"
-	"encoding/json"
 	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
 	"os"
 	. "packx/utils"
 	"path/filepath"
@@ -20,101 +22,6 @@ func (bs *StorageEngine) getStoragePath() string {
 
 }
 
-func (bs *StorageEngine) updateIndex(indexPath string, deviceID int, offset int64) error {
-	var index []IndexEntry
-
-	// Check if file exists
-	if _, err := os.Stat(indexPath); !os.IsNotExist(err) {
-		// Read existing index if it exists
-		data, err := os.ReadFile(indexPath)
-		if err != nil {
-			return fmt.Errorf("failed to read index file: %v", err)
-		}
-
-		// Skip empty files
-		if len(data) > 0 {
-			if err := json.Unmarshal(data, &index); err != nil {
-				// If cannot parse, start with empty index
-				index = []IndexEntry{}
-			}
-		}
-	}
-
-	bs.blockManager.mu.Lock()
-	currentOffset, hasCurrent := bs.blockManager.currentBlock[deviceID]
-	bs.blockManager.mu.Unlock()
-
-	// Add or update entry
-	entry := IndexEntry{
-		DeviceID:      deviceID,
-		BlockOffset:   offset,
-		CurrentOffset: currentOffset,
-	}
-
-	found := false
-	for i, e := range index {
-		if e.DeviceID == deviceID {
-			// Preserve the block offset if it's not a new block allocation
-			if hasCurrent && offset == currentOffset {
-				entry.BlockOffset = e.BlockOffset
-			}
-			index[i] = entry
-			found = true
-			break
-		}
-	}
-	if !found {
-		index = append(index, entry)
-	}
-
-	// Write updated index
-	data, err := json.MarshalIndent(index, "", "    ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal index: %v", err)
-	}
-
-	if err := os.WriteFile(indexPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write index: %v", err)
-	}
-
-	return nil
-}
-
-func (bs *StorageEngine) readIndex(indexPath string) ([]IndexEntry, error) {
-
-	// Check if file exists
-	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
-
-		return []IndexEntry{}, nil
-
-	}
-
-	data, err := os.ReadFile(indexPath)
-
-	if err != nil {
-
-		return nil, fmt.Errorf("failed to read index: %v", err)
-
-	}
-
-	// Skip empty files
-	if len(data) == 0 {
-
-		return []IndexEntry{}, nil
-
-	}
-
-	var index []IndexEntry
-
-	if err := json.Unmarshal(data, &index); err != nil {
-
-		return nil, fmt.Errorf("failed to parse index: %v", err)
-
-	}
-
-	return index, nil
-}
-
 func extractTimestampFromData(data []byte) (uint32, error) {
 	if len(data) == 0 {
 		return 0, fmt.Errorf("empty data")
@@ -124,7 +31,7 @@ func extractTimestampFromData(data []byte) (uint32, error) {
 
 func getIndexFilePath(baseDir string, partition int) string {
 
-	return filepath.Join(baseDir, fmt.Sprintf("partition_%d", partition), "index.json")
+	return filepath.Join(baseDir, fmt.Sprintf("partition_%d", partition), indexFileName)
 
 }
 
@@ -134,84 +41,6 @@ func getDataFilePath(baseDir string, partition int) string {
 
 }
 
-func readIndex(baseDir string, partition int) ([]IndexEntry, error) {
-
-	indexFile := getIndexFilePath(baseDir, partition)
-
-	data, err := os.ReadFile(indexFile)
-
-	if err != nil {
-
-		if os.IsNotExist(err) {
-			return []IndexEntry{}, nil
-		}
-
-		return nil, fmt.Errorf("failed to read unified index file: %v", err)
-
-	}
-
-	var index []IndexEntry
-
-	if err := json.Unmarshal(data, &index); err != nil {
-
-		return nil, fmt.Errorf("failed to unmarshal unified index file: %v", err)
-
-	}
-
-	return index, nil
-}
-
-func writeIndex(baseDir string, partition int, index []IndexEntry) error {
-
-	indexFile := getIndexFilePath(baseDir, partition)
-
-	// Marshal with indentation for readability
-	data, err := json.MarshalIndent(index, "", "    ")
-
-	if err != nil {
-		return fmt.Errorf("failed to marshal unified index file: %v", err)
-	}
-
-	// newline at the end of the file
-	data = append(data, '\n')
-
-	if err := os.WriteFile(indexFile, data, 0644); err != nil {
-
-		return fmt.Errorf("failed to write unified index file: %v", err)
-
-	}
-
-	return nil
-}
-
-func findDeviceIndex(index []IndexEntry, deviceID int) (int, bool) {
-	for i, entry := range index {
-		if entry.DeviceID == deviceID {
-			return i, true
-		}
-	}
-	return -1, false
-}
-
-func getCurrentBlockOffset(index []IndexEntry, deviceID int) (int64, bool) {
-	for _, entry := range index {
-		if entry.DeviceID == deviceID && entry.CurrentOffset != 0 {
-			return entry.CurrentOffset, true
-		}
-	}
-	return 0, false
-}
-
-func updateCurrentBlockOffset(index []IndexEntry, deviceID int, offset int64) []IndexEntry {
-	for i, entry := range index {
-		if entry.DeviceID == deviceID {
-			index[i].CurrentOffset = offset
-			return index
-		}
-	}
-	return index
-}
-
 func (bs *StorageEngine) getMappedDataFile(path string) (*MappedFile, error) {
 
 	bs.mmapFilesLock.Lock()
@@ -332,30 +161,171 @@ func decodeOffsetTableEntry(data []byte) OffsetTableEntry {
 //	return mmap, nil
 //}
 
-// Update getBlockUsage to use proper BlockManager fields
-func (bs *StorageEngine) getBlockUsage(deviceID int, blockOffset int64) (int, error) {
-	bs.blockManager.mu.Lock()
-	defer bs.blockManager.mu.Unlock()
-	
-	if currentOffset, exists := bs.blockManager.currentBlock[deviceID]; exists && blockOffset == currentOffset {
-		if usage, exists := bs.blockManager.blockUsage[deviceID]; exists {
-			return usage, nil
+// loadChainTail reads a device's current block to learn how much of it is
+// used, and for Gorilla blocks rebuilds the encoder so appends continue where
+// the block left off. Only checksummed blocks of the device that are open or
+// still match their checksum are appended to; anything else is left as it is
+// and the next write moves on to a fresh block.
+func (bs *StorageEngine) loadChainTail(mmapFile *MappedFile, chain *deviceChain, deviceID int, dataType byte) {
+	chain.usage = blockCapacity
+	chain.encoding = EncodingRaw
+	chain.checksummed = false
+	chain.open = false
+	chain.encoder = nil
+
+	block := make([]byte, BlockSize)
+	if _, err := mmapFile.ReadAt(block, chain.lastBlock); err != nil {
+		return
+	}
+
+	header := decodeBlockHeader(block[:BlockHeaderSize])
+	if header.DeviceID != uint32(deviceID) || !header.Checksummed() {
+		return
+	}
+
+	if header.Sealed() && !verifyBlock(block) {
+		log.Printf("Checksum mismatch in current block %d of device %d, starting a new block", chain.lastBlock, deviceID)
+		return
+	}
+
+	chain.encoding = header.Encoding()
+
+	switch chain.encoding {
+	case EncodingDictionary:
+		chain.usage = int(header.RecordCount) * dictionaryRecordSize
+
+	case EncodingGorilla:
+		decoder := NewGorillaDecoder(block[BlockHeaderSize:blockRecordsEnd], header.RecordCount)
+		for {
+			_, _, err := decoder.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return
+			}
 		}
+
+		chain.encoder = decoder.encoder()
+		chain.usage = chain.encoder.size()
+
+	default:
+		chain.usage = recordsLength(block[BlockHeaderSize:blockRecordsEnd], dataType, header.RecordCount)
+	}
+
+	chain.checksummed = true
+	chain.open = !header.Sealed()
+}
+
+// sealBlock writes the checksum of a device's open block and clears its
+// open flag, so that reads check it from then on
+func (bs *StorageEngine) sealBlock(mmapFile *MappedFile, blockOffset int64) error {
+	block := make([]byte, BlockSize)
+	if _, err := mmapFile.ReadAt(block, blockOffset); err != nil {
+		return fmt.Errorf("failed to read block for checksum: %v", err)
+	}
+
+	return writeBlockHeader(mmapFile, blockOffset, block, decodeBlockHeader(block[:BlockHeaderSize]))
+}
+
+// reopenBlock sets the open flag of a sealed block before records are
+// appended to it
+func (bs *StorageEngine) reopenBlock(mmapFile *MappedFile, blockOffset int64) error {
+	headerData := make([]byte, BlockHeaderSize)
+	if _, err := mmapFile.ReadAt(headerData, blockOffset); err != nil {
+		return fmt.Errorf("failed to read header: %v", err)
+	}
+
+	header := decodeBlockHeader(headerData)
+	header.DataType |= BlockFlagOpen
+
+	if _, err := mmapFile.WriteAt(encodeBlockHeader(header), blockOffset); err != nil {
+		return fmt.Errorf("failed to write header: %v", err)
 	}
-	return BlockSize - BlockHeaderSize, nil // Assume historical blocks are full
+
+	return nil
 }
 
-// Update hasSpaceInBlock to use proper BlockManager fields
-func (bs *StorageEngine) hasSpaceInBlock(deviceID int, dataSize int) bool {
-	bs.blockManager.mu.Lock()
-	defer bs.blockManager.mu.Unlock()
-	
-	if _, exists := bs.blockManager.currentBlock[deviceID]; exists {
-		if usage, exists := bs.blockManager.blockUsage[deviceID]; exists {
-			return usage+dataSize <= BlockSize-BlockHeaderSize
+// writeBlockHeader writes header over the block at blockOffset, whose
+// current contents are block. A checksummed block is sealed on the way: its
+// checksum is written before the header, so a crash in between leaves the
+// block open and readable instead of failing its checksum.
+func writeBlockHeader(mmapFile *MappedFile, blockOffset int64, block []byte, header BlockHeader) error {
+	if header.Checksummed() {
+		header.DataType &^= BlockFlagOpen
+		copy(block[:BlockHeaderSize], encodeBlockHeader(header))
+
+		checksum := make([]byte, BlockChecksumSize)
+		binary.LittleEndian.PutUint32(checksum, crc32.ChecksumIEEE(block[:BlockSize-BlockChecksumSize]))
+
+		if _, err := mmapFile.WriteAt(checksum, blockOffset+BlockSize-BlockChecksumSize); err != nil {
+			return fmt.Errorf("failed to write block checksum: %v", err)
 		}
 	}
-	return false
+
+	if _, err := mmapFile.WriteAt(encodeBlockHeader(header), blockOffset); err != nil {
+		return fmt.Errorf("failed to write header: %v", err)
+	}
+
+	return nil
+}
+
+// verifyBlock reports whether a checksummed block still matches its checksum
+func verifyBlock(block []byte) bool {
+	stored := binary.LittleEndian.Uint32(block[BlockSize-BlockChecksumSize:])
+	return crc32.ChecksumIEEE(block[:BlockSize-BlockChecksumSize]) == stored
+}
+
+// linkBlock points the header of a device's previous block at its new block.
+// A checksummed block is sealed with the same header write, as nothing is
+// appended to it any more.
+func (bs *StorageEngine) linkBlock(mmapFile *MappedFile, prevOffset int64, nextOffset int64) error {
+	block := make([]byte, BlockSize)
+	if _, err := mmapFile.ReadAt(block, prevOffset); err != nil {
+		return fmt.Errorf("failed to read block: %v", err)
+	}
+
+	header := decodeBlockHeader(block[:BlockHeaderSize])
+	header.NextBlockOffset = nextOffset
+
+	return writeBlockHeader(mmapFile, prevOffset, block, header)
+}
+
+// chainOffsets walks a device's blocks through their NextBlockOffset links,
+// starting at the first block in the index. A chain cut short by a crash
+// between allocating a block and linking it is resumed from the next block
+// the index knows about, so no block of the device is skipped.
+func (bs *StorageEngine) chainOffsets(mmapFile *MappedFile, index *blockIndex, deviceID uint32) []int64 {
+	var offsets []int64
+
+	visited := make(map[int64]bool)
+	headerData := make([]byte, BlockHeaderSize)
+
+	for _, entry := range index.deviceBlocks(deviceID) {
+		offset := entry.BlockOffset
+
+		for !visited[offset] {
+			if _, err := mmapFile.ReadAt(headerData, offset); err != nil {
+				break
+			}
+
+			header := decodeBlockHeader(headerData)
+			if header.DeviceID != deviceID {
+				break
+			}
+
+			visited[offset] = true
+			offsets = append(offsets, offset)
+
+			// blocks are allocated in increasing order, anything else ends the chain
+			if header.NextBlockOffset <= offset {
+				break
+			}
+			offset = header.NextBlockOffset
+		}
+	}
+
+	return offsets
 }
 
 // Update updateBlockHeader to use proper BlockManager fields
@@ -374,7 +344,7 @@ func (bs *StorageEngine) updateBlockHeader(mmapFile *MappedFile, offset int64, n
 		if timestamp > header.EndTimestamp {
 			header.EndTimestamp = timestamp
 		}
-		if header.StartTimestamp == 0 {
+		if header.StartTimestamp == 0 || timestamp < header.StartTimestamp {
 			header.StartTimestamp = timestamp
 		}
 	}
@@ -390,10 +360,113 @@ func (bs *StorageEngine) updateBlockHeader(mmapFile *MappedFile, offset int64, n
 // Update initializeBlockHeader to use proper BlockManager fields
 func (bs *StorageEngine) initializeBlockHeader(deviceID int, dataType byte, timestamp uint32) BlockHeader {
 	return BlockHeader{
-		DeviceID:       uint32(deviceID),
-		StartTimestamp: timestamp,
-		EndTimestamp:   timestamp,
-		RecordCount:    1,
-		DataType:       dataType,
+		DeviceID:        uint32(deviceID),
+		StartTimestamp:  timestamp,
+		EndTimestamp:    timestamp,
+		NextBlockOffset: -1, // end of the device's chain until a newer block is linked
+		RecordCount:     1,
+		DataType:        dataType,
+	}
+}
+
+// appliedWalPosition returns the write-ahead log position stored with the
+// device's current block in the partition under basePath, the zero position
+// when no logged record of the device was stored
+func (bs *StorageEngine) appliedWalPosition(basePath string, deviceID int) walPosition {
+	partition := deviceID % NumPartitions
+	partitionPath := filepath.Join(basePath, fmt.Sprintf("partition_%d", partition))
+
+	bs.partitionLocks[partition].RLock()
+	defer bs.partitionLocks[partition].RUnlock()
+
+	dataFile := filepath.Join(partitionPath, "data.bin")
+	if _, err := os.Stat(dataFile); err != nil {
+		return walPosition{}
+	}
+
+	index, err := bs.getBlockIndex(partitionPath)
+	if err != nil {
+		return walPosition{}
+	}
+
+	mmapFile, err := bs.getMappedDataFile(dataFile)
+	if err != nil {
+		return walPosition{}
+	}
+
+	// the newest intact block decides, open blocks counting as intact; records
+	// of a torn block are lost with it and are applied again
+	blocks := index.deviceBlocks(uint32(deviceID))
+	block := make([]byte, BlockSize)
+
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if _, err := mmapFile.ReadAt(block, blocks[i].BlockOffset); err != nil {
+			continue
+		}
+
+		header := decodeBlockHeader(block[:BlockHeaderSize])
+		if header.DeviceID != uint32(deviceID) || !header.Checksummed() || (header.Sealed() && !verifyBlock(block)) {
+			continue
+		}
+
+		return decodeWalPosition(block[blockRecordsEnd:])
+	}
+
+	return walPosition{}
+}
+
+// readWalPosition returns the write-ahead log position stored with a block
+func readWalPosition(mmapFile *MappedFile, blockOffset int64) walPosition {
+	trailer := make([]byte, BlockWalPositionSize)
+	if _, err := mmapFile.ReadAt(trailer, blockOffset+blockRecordsEnd); err != nil {
+		return walPosition{}
+	}
+
+	return decodeWalPosition(trailer)
+}
+
+// writeWalPosition stores the write-ahead log position of a block's last
+// record; the block must be open
+func writeWalPosition(mmapFile *MappedFile, blockOffset int64, position walPosition) error {
+	trailer := make([]byte, BlockWalPositionSize)
+	binary.LittleEndian.PutUint64(trailer[0:8], position.lsn)
+	binary.LittleEndian.PutUint32(trailer[8:12], position.seq)
+
+	if _, err := mmapFile.WriteAt(trailer, blockOffset+blockRecordsEnd); err != nil {
+		return fmt.Errorf("failed to write wal position: %v", err)
 	}
+
+	return nil
+}
+
+func decodeWalPosition(trailer []byte) walPosition {
+	return walPosition{
+		lsn: binary.LittleEndian.Uint64(trailer[0:8]),
+		seq: binary.LittleEndian.Uint32(trailer[8:12]),
+	}
+}
+
+// recordsLength returns the number of bytes taken by the first count records
+// of a block's data region
+func recordsLength(data []byte, dataType byte, count uint32) int {
+	offset := 0
+
+	for i := uint32(0); i < count && offset+4 <= len(data); i++ {
+		offset += 4 // timestamp
+
+		if dataType == TypeString {
+			if offset+4 > len(data) {
+				return len(data)
+			}
+			offset += 4 + int(binary.LittleEndian.Uint32(data[offset:offset+4]))
+		} else {
+			offset += 8
+		}
+	}
+
+	if offset > len(data) {
+		return len(data)
+	}
+
+	return offset
 }
@@ -0,0 +1,489 @@
+package storageEngine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	. "packx/utils"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Binary block index. Every partition keeps an index.bin next to its data.bin
+// holding one fixed-size entry per allocated block, in allocation order:
+//
+//	entry := deviceID(4) startTimestamp(4) endTimestamp(4) blockOffset(8)
+//
+// New blocks are appended and the timestamps of a device's current block are
+// rewritten in place, so both operations cost a single small write. The file
+// is loaded once per partition and kept in memory afterwards.
+
+const (
+	indexFileName = "index.bin"
+
+	// legacyIndexFileName is the JSON index written by earlier versions
+	legacyIndexFileName = "index.json"
+)
+
+type blockIndex struct {
+	mu sync.RWMutex
+
+	file *os.File
+
+	entries []IndexEntry
+
+	byDevice map[uint32][]int // positions in entries, oldest block first
+}
+
+func newBlockIndex(file *os.File, entries []IndexEntry) *blockIndex {
+
+	idx := &blockIndex{
+		file:     file,
+		entries:  entries,
+		byDevice: make(map[uint32][]int),
+	}
+
+	for pos, entry := range entries {
+		idx.byDevice[entry.DeviceID] = append(idx.byDevice[entry.DeviceID], pos)
+	}
+
+	return idx
+}
+
+// append records a newly allocated block
+func (idx *blockIndex) append(entry IndexEntry) error {
+
+	idx.mu.Lock()
+
+	defer idx.mu.Unlock()
+
+	pos := len(idx.entries)
+
+	if _, err := idx.file.WriteAt(encodeIndexEntry(entry), int64(pos)*IndexEntrySize); err != nil {
+
+		return fmt.Errorf("failed to append index entry: %v", err)
+
+	}
+
+	idx.entries = append(idx.entries, entry)
+
+	idx.byDevice[entry.DeviceID] = append(idx.byDevice[entry.DeviceID], pos)
+
+	return nil
+}
+
+// extend widens the time range of the device's current block to cover timestamp
+func (idx *blockIndex) extend(deviceID uint32, blockOffset int64, timestamp uint32) error {
+
+	idx.mu.Lock()
+
+	defer idx.mu.Unlock()
+
+	positions := idx.byDevice[deviceID]
+
+	if len(positions) == 0 {
+
+		return fmt.Errorf("device %d has no blocks in the index", deviceID)
+
+	}
+
+	pos := positions[len(positions)-1]
+
+	entry := &idx.entries[pos]
+
+	if entry.BlockOffset != blockOffset {
+
+		return fmt.Errorf("block at offset %d is not the current block of device %d", blockOffset, deviceID)
+
+	}
+
+	if timestamp >= entry.StartTimestamp && timestamp <= entry.EndTimestamp {
+
+		return nil
+
+	}
+
+	if timestamp < entry.StartTimestamp {
+		entry.StartTimestamp = timestamp
+	}
+
+	if timestamp > entry.EndTimestamp {
+		entry.EndTimestamp = timestamp
+	}
+
+	buf := make([]byte, 8)
+
+	binary.LittleEndian.PutUint32(buf[0:4], entry.StartTimestamp)
+
+	binary.LittleEndian.PutUint32(buf[4:8], entry.EndTimestamp)
+
+	if _, err := idx.file.WriteAt(buf, int64(pos)*IndexEntrySize+4); err != nil {
+
+		return fmt.Errorf("failed to update index entry: %v", err)
+
+	}
+
+	return nil
+}
+
+// deviceBlocks returns the index entries of a device, oldest block first
+func (idx *blockIndex) deviceBlocks(deviceID uint32) []IndexEntry {
+
+	return idx.deviceBlocksFrom(deviceID, 0)
+}
+
+// deviceBlocksFrom returns the index entries of a device from the block at
+// startOffset on. Blocks are allocated in file order, so a device's entries
+// are ordered by offset as well.
+func (idx *blockIndex) deviceBlocksFrom(deviceID uint32, startOffset int64) []IndexEntry {
+
+	idx.mu.RLock()
+
+	defer idx.mu.RUnlock()
+
+	positions := idx.byDevice[deviceID]
+
+	first := sort.Search(len(positions), func(i int) bool { return idx.entries[positions[i]].BlockOffset >= startOffset })
+
+	positions = positions[first:]
+
+	blocks := make([]IndexEntry, 0, len(positions))
+
+	for _, pos := range positions {
+		blocks = append(blocks, idx.entries[pos])
+	}
+
+	return blocks
+}
+
+// allEntries returns a copy of every entry, in allocation order
+func (idx *blockIndex) allEntries() []IndexEntry {
+
+	idx.mu.RLock()
+
+	defer idx.mu.RUnlock()
+
+	entries := make([]IndexEntry, len(idx.entries))
+
+	copy(entries, idx.entries)
+
+	return entries
+}
+
+func (idx *blockIndex) sync() error {
+
+	return idx.file.Sync()
+}
+
+func (idx *blockIndex) close() error {
+
+	idx.mu.Lock()
+
+	defer idx.mu.Unlock()
+
+	if err := idx.file.Sync(); err != nil {
+
+		return fmt.Errorf("failed to sync index: %v", err)
+
+	}
+
+	return idx.file.Close()
+}
+
+// getBlockIndex returns the in-memory index of a partition, loading it from
+// index.bin on first use. A partition that only has a legacy index.json is
+// migrated by scanning the block headers of its data file.
+func (bs *StorageEngine) getBlockIndex(partitionPath string) (*blockIndex, error) {
+
+	bs.indexesLock.Lock()
+
+	defer bs.indexesLock.Unlock()
+
+	if idx, exists := bs.indexes[partitionPath]; exists {
+
+		return idx, nil
+
+	}
+
+	if err := os.MkdirAll(partitionPath, 0755); err != nil {
+
+		return nil, fmt.Errorf("failed to create partition directory: %v", err)
+
+	}
+
+	indexPath := filepath.Join(partitionPath, indexFileName)
+
+	_, statErr := os.Stat(indexPath)
+
+	needsMigration := os.IsNotExist(statErr)
+
+	file, err := os.OpenFile(indexPath, os.O_RDWR|os.O_CREATE, 0644)
+
+	if err != nil {
+
+		return nil, fmt.Errorf("failed to open index: %v", err)
+
+	}
+
+	var entries []IndexEntry
+
+	if needsMigration {
+
+		entries, err = bs.rebuildIndexFromData(partitionPath, file)
+
+	} else if entries, err = readIndexEntries(file); err == nil {
+
+		err = bs.checkIndexEntries(partitionPath, entries)
+
+	}
+
+	if err != nil && !needsMigration {
+
+		// an unreadable index must not cost the partition its data
+		log.Printf("Block index of %s is damaged (%v), rebuilding it from the data file", partitionPath, err)
+
+		entries, err = bs.rebuildIndexFromData(partitionPath, file)
+
+	}
+
+	if err != nil {
+
+		file.Close()
+
+		return nil, err
+
+	}
+
+	idx := newBlockIndex(file, entries)
+
+	bs.indexes[partitionPath] = idx
+
+	return idx, nil
+}
+
+// readIndexEntries decodes every complete entry of an index file. A trailing
+// partial entry, left by a crash in the middle of an append, is cut off.
+func readIndexEntries(file *os.File) ([]IndexEntry, error) {
+
+	info, err := file.Stat()
+
+	if err != nil {
+
+		return nil, fmt.Errorf("failed to stat index: %v", err)
+
+	}
+
+	data := make([]byte, info.Size())
+
+	if _, err := file.ReadAt(data, 0); err != nil && len(data) > 0 {
+
+		return nil, fmt.Errorf("failed to read index: %v", err)
+
+	}
+
+	count := len(data) / IndexEntrySize
+
+	if len(data)%IndexEntrySize != 0 {
+
+		log.Printf("Index %s has a partial trailing entry, truncating", file.Name())
+
+		if err := file.Truncate(int64(count * IndexEntrySize)); err != nil {
+
+			return nil, fmt.Errorf("failed to truncate index: %v", err)
+
+		}
+
+	}
+
+	entries := make([]IndexEntry, 0, count)
+
+	for i := 0; i < count; i++ {
+		entries = append(entries, decodeIndexEntry(data[i*IndexEntrySize:(i+1)*IndexEntrySize]))
+	}
+
+	return entries, nil
+}
+
+// rebuildIndexFromData writes a partition's index from the headers of its
+// data file. This migrates partitions written before index.bin existed (the
+// JSON index only kept one block per device, scanning also recovers older
+// blocks) and replaces indexes that are damaged.
+func (bs *StorageEngine) rebuildIndexFromData(partitionPath string, file *os.File) ([]IndexEntry, error) {
+
+	dataFile := filepath.Join(partitionPath, "data.bin")
+
+	if _, err := os.Stat(dataFile); os.IsNotExist(err) {
+
+		return nil, file.Truncate(0)
+
+	}
+
+	mmapFile, err := bs.getMappedDataFile(dataFile)
+
+	if err != nil {
+
+		return nil, err
+
+	}
+
+	entries := scanBlockHeaders(mmapFile, int64(mmapFile.getSize()))
+
+	if err := writeIndexEntries(file, entries); err != nil {
+
+		return nil, err
+
+	}
+
+	legacyPath := filepath.Join(partitionPath, legacyIndexFileName)
+
+	if _, err := os.Stat(legacyPath); err == nil {
+
+		if err := os.Rename(legacyPath, legacyPath+".migrated"); err != nil {
+
+			log.Printf("Failed to set aside legacy index %s: %v", legacyPath, err)
+
+		}
+
+		log.Printf("Migrated %s to %s with %d blocks", legacyPath, indexFileName, len(entries))
+
+	}
+
+	return entries, nil
+}
+
+// checkIndexEntries verifies that every entry points at a block of the data
+// file that belongs to the entry's device
+func (bs *StorageEngine) checkIndexEntries(partitionPath string, entries []IndexEntry) error {
+
+	if len(entries) == 0 {
+
+		return nil
+
+	}
+
+	mmapFile, err := bs.getMappedDataFile(filepath.Join(partitionPath, "data.bin"))
+
+	if err != nil {
+
+		return err
+
+	}
+
+	headerData := make([]byte, BlockHeaderSize)
+
+	for pos, entry := range entries {
+
+		if entry.BlockOffset < 0 || entry.BlockOffset%BlockSize != 0 || entry.BlockOffset+BlockSize > int64(mmapFile.getSize()) {
+
+			return fmt.Errorf("entry %d points outside the data file (offset %d)", pos, entry.BlockOffset)
+
+		}
+
+		if _, err := mmapFile.ReadAt(headerData, entry.BlockOffset); err != nil {
+
+			return fmt.Errorf("failed to read block header at offset %d: %v", entry.BlockOffset, err)
+
+		}
+
+		if header := decodeBlockHeader(headerData); header.DeviceID != entry.DeviceID {
+
+			return fmt.Errorf("entry %d is for device %d but block %d belongs to device %d", pos, entry.DeviceID, entry.BlockOffset, header.DeviceID)
+
+		}
+	}
+
+	return nil
+}
+
+// writeIndexEntries replaces the contents of an index file
+func writeIndexEntries(file *os.File, entries []IndexEntry) error {
+
+	buf := make([]byte, 0, len(entries)*IndexEntrySize)
+
+	for _, entry := range entries {
+		buf = append(buf, encodeIndexEntry(entry)...)
+	}
+
+	if err := file.Truncate(0); err != nil {
+
+		return fmt.Errorf("failed to truncate index: %v", err)
+
+	}
+
+	if _, err := file.WriteAt(buf, 0); err != nil {
+
+		return fmt.Errorf("failed to write index: %v", err)
+
+	}
+
+	if err := file.Sync(); err != nil {
+
+		return fmt.Errorf("failed to sync index: %v", err)
+
+	}
+
+	return nil
+}
+
+// scanBlockHeaders returns an index entry for every block of a data file whose
+// header records at least one record
+func scanBlockHeaders(data io.ReaderAt, size int64) []IndexEntry {
+
+	var entries []IndexEntry
+
+	headerData := make([]byte, BlockHeaderSize)
+
+	for offset := int64(0); offset+BlockSize <= size; offset += BlockSize {
+
+		if _, err := data.ReadAt(headerData, offset); err != nil {
+			break
+		}
+
+		header := decodeBlockHeader(headerData)
+
+		if header.RecordCount == 0 {
+			continue
+		}
+
+		entries = append(entries, IndexEntry{
+			DeviceID:       header.DeviceID,
+			StartTimestamp: header.StartTimestamp,
+			EndTimestamp:   header.EndTimestamp,
+			BlockOffset:    offset,
+		})
+	}
+
+	return entries
+}
+
+func encodeIndexEntry(entry IndexEntry) []byte {
+
+	buf := make([]byte, IndexEntrySize)
+
+	binary.LittleEndian.PutUint32(buf[0:4], entry.DeviceID)
+
+	binary.LittleEndian.PutUint32(buf[4:8], entry.StartTimestamp)
+
+	binary.LittleEndian.PutUint32(buf[8:12], entry.EndTimestamp)
+
+	binary.LittleEndian.PutUint64(buf[12:20], uint64(entry.BlockOffset))
+
+	return buf
+}
+
+func decodeIndexEntry(data []byte) IndexEntry {
+
+	return IndexEntry{
+
+		DeviceID: binary.LittleEndian.Uint32(data[0:4]),
+
+		StartTimestamp: binary.LittleEndian.Uint32(data[4:8]),
+
+		EndTimestamp: binary.LittleEndian.Uint32(data[8:12]),
+
+		BlockOffset: int64(binary.LittleEndian.Uint64(data[12:20])),
+	}
+}
@@ -0,0 +1,177 @@
+package storageEngine
+
+import (
+	"math"
+	"os"
+	. "packx/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndexKeepsEveryBlockAcrossRestart(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+
+	var records [][]byte
+	for i := 0; i < 1000; i++ {
+		records = append(records, floatRecord(uint32(1000+i), math.Sin(float64(i))))
+	}
+
+	if err := engine.PutBatch(counterPath, 4, TypeFloat, records); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	engine.Close()
+
+	reopened, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	partitionPath := filepath.Join(counterPath, "partition_1")
+	index, err := reopened.getBlockIndex(partitionPath)
+	if err != nil {
+		t.Fatalf("getBlockIndex: %v", err)
+	}
+
+	blocks := index.deviceBlocks(4)
+	if len(blocks) < 3 {
+		t.Fatalf("expected at least 3 blocks for 1000 records, got %d", len(blocks))
+	}
+	if blocks[0].StartTimestamp != 1000 || blocks[len(blocks)-1].EndTimestamp != 1999 {
+		t.Fatalf("unexpected index time range: first %+v last %+v", blocks[0], blocks[len(blocks)-1])
+	}
+
+	if got := countRecords(t, reopened, counterPath, 4); got != 1000 {
+		t.Fatalf("expected 1000 records, got %d", got)
+	}
+}
+
+func TestLegacyIndexIsMigrated(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+	partitionPath := filepath.Join(counterPath, "partition_1")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	if err := engine.PutBatch(counterPath, 1, TypeFloat, [][]byte{floatRecord(10, 1), floatRecord(11, 2)}); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	engine.Close()
+
+	// pretend the partition was written by a version with index.json
+	os.Remove(filepath.Join(partitionPath, indexFileName))
+	os.WriteFile(filepath.Join(partitionPath, legacyIndexFileName), []byte(`[{"device_id":1,"block_offset":0,"current_offset":0}]`), 0644)
+
+	reopened, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	if got := countRecords(t, reopened, counterPath, 1); got != 2 {
+		t.Fatalf("expected 2 records after migration, got %d", got)
+	}
+	if _, err := os.Stat(filepath.Join(partitionPath, legacyIndexFileName+".migrated")); err != nil {
+		t.Fatalf("expected legacy index to be set aside: %v", err)
+	}
+}
+
+func TestDevicesInPartitionDoNotOverlap(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	defer engine.Close()
+
+	// devices 1 and 4 share partition_1; interleave them across several blocks
+	for i := 0; i < 800; i++ {
+		for _, device := range []int{1, 4} {
+			if err := engine.PutBatch(counterPath, device, TypeFloat, [][]byte{floatRecord(uint32(1000+i), math.Sin(float64(i*device)))}); err != nil {
+				t.Fatalf("PutBatch: %v", err)
+			}
+		}
+	}
+
+	for _, device := range []int{1, 4} {
+		if got := countRecords(t, engine, counterPath, device); got != 800 {
+			t.Fatalf("device %d: expected 800 records, got %d", device, got)
+		}
+	}
+
+	index, _ := engine.getBlockIndex(filepath.Join(counterPath, "partition_1"))
+	seen := make(map[int64]uint32)
+	for _, entry := range index.allEntries() {
+		if owner, exists := seen[entry.BlockOffset]; exists {
+			t.Fatalf("block %d allocated to both device %d and %d", entry.BlockOffset, owner, entry.DeviceID)
+		}
+		seen[entry.BlockOffset] = entry.DeviceID
+	}
+
+	// the chain alone must reach every block of the device
+	mmapFile, _ := engine.getMappedDataFile(filepath.Join(counterPath, "partition_1", "data.bin"))
+	blocks := index.deviceBlocks(4)
+	headerData := make([]byte, BlockHeaderSize)
+	walked := 0
+	for offset := blocks[0].BlockOffset; ; {
+		mmapFile.ReadAt(headerData, offset)
+		header := decodeBlockHeader(headerData)
+		if header.DeviceID != 4 {
+			t.Fatalf("chain of device 4 reached a block of device %d", header.DeviceID)
+		}
+		walked++
+		if header.NextBlockOffset <= offset {
+			break
+		}
+		offset = header.NextBlockOffset
+	}
+	if walked != len(blocks) {
+		t.Fatalf("chain has %d blocks, index has %d", walked, len(blocks))
+	}
+}
+
+func TestDamagedIndexIsRebuiltOnLoad(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+	indexPath := filepath.Join(counterPath, "partition_1", indexFileName)
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	var records [][]byte
+	for i := 0; i < 1000; i++ {
+		records = append(records, floatRecord(uint32(1000+i), math.Sin(float64(i))))
+	}
+	if err := engine.PutBatch(counterPath, 1, TypeFloat, records); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	engine.Close()
+
+	// an entry pointing far past the end of the data file
+	data, _ := os.ReadFile(indexPath)
+	entry := decodeIndexEntry(data[:IndexEntrySize])
+	entry.BlockOffset = 1 << 40
+	copy(data, encodeIndexEntry(entry))
+	os.WriteFile(indexPath, data, 0644)
+
+	reopened, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	if got := countRecords(t, reopened, counterPath, 1); got != 1000 {
+		t.Fatalf("expected 1000 records after the rebuild, got %d", got)
+	}
+}
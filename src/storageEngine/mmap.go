@@ -3,21 +3,112 @@ package storageEngine
 import (
 	"fmt"
 	"golang.org/x/sys/unix"
+	"log"
 	"os"
+	. "packx/utils"
 	"sync"
 	"syscall"
 )
 
+// Growth of mapped files: each grow at least doubles the file, by no less
+// than mmapGrowMin and no more than mmapGrowMax at a time, so that a file of
+// N blocks is remapped O(log N) times
+const (
+	mmapGrowMin = 256 * BlockSize
+
+	mmapGrowMax = 16384 * BlockSize
+)
+
 // MappedFile represents a memory-mapped file
 type MappedFile struct {
 	file          *os.File
-	data          []byte
+	current       *mapping
 	size          int
 	currentOffset int64
 	mu            sync.RWMutex
 	isClosed      bool
 }
 
+// mapping is one mmap of a file. Views pin the mapping they alias; a mapping
+// replaced by grow, or released by unmap, is unmapped once no view pins it.
+// Both old and new mappings share the file's pages, so they stay coherent.
+type mapping struct {
+	mu sync.Mutex
+
+	data []byte
+
+	pins int
+
+	retired bool
+}
+
+// pin keeps the mapping alive until a matching unpin
+func (r *mapping) pin() {
+
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	r.pins++
+}
+
+// unpin releases a pin, unmapping a retired mapping with the last one
+func (r *mapping) unpin() {
+
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	r.pins--
+
+	if r.pins == 0 && r.retired {
+
+		if err := r.unmapLocked(); err != nil {
+
+			log.Printf("Failed to release mapping: %v", err)
+
+		}
+
+	}
+}
+
+// retire unmaps the mapping now if no view pins it, or else with the last unpin
+func (r *mapping) retire() error {
+
+	r.mu.Lock()
+
+	defer r.mu.Unlock()
+
+	r.retired = true
+
+	if r.pins > 0 {
+
+		return nil
+
+	}
+
+	return r.unmapLocked()
+}
+
+func (r *mapping) unmapLocked() error {
+
+	if r.data == nil {
+
+		return nil
+
+	}
+
+	if err := syscall.Munmap(r.data); err != nil {
+
+		return fmt.Errorf("failed to unmap file: %v", err)
+
+	}
+
+	r.data = nil
+
+	return nil
+}
+
 // openMappedFile opens a file and maps it into memory
 func openMappedFile(path string, initialSize int) (*MappedFile, error) {
 
@@ -61,7 +152,7 @@ func openMappedFile(path string, initialSize int) (*MappedFile, error) {
 
 	return &MappedFile{
 		file:          file,
-		data:          data,
+		current:       &mapping{data: data},
 		size:          size,
 		currentOffset: int64(size),
 		isClosed:      false,
@@ -86,12 +177,41 @@ func (m *MappedFile) ReadAt(b []byte, offset int64) (int, error) {
 
 	}
 
-	copy(b, m.data[offset:offset+int64(len(b))])
+	copy(b, m.current.data[offset:offset+int64(len(b))])
 
 	return len(b), nil
 
 }
 
+// pinnedView returns a read-only slice aliasing the mapped file at offset,
+// together with the mapping it aliases, pinned. The slice stays valid when the
+// file grows or is closed until the mapping is unpinned.
+func (m *MappedFile) pinnedView(offset int64, length int) ([]byte, *mapping, error) {
+
+	m.mu.RLock()
+
+	defer m.mu.RUnlock()
+
+	if m.isClosed {
+
+		return nil, nil, fmt.Errorf("file already closed")
+
+	}
+
+	end := offset + int64(length)
+
+	if offset < 0 || end > int64(m.size) {
+
+		return nil, nil, fmt.Errorf("view would exceed mapped region size")
+
+	}
+
+	m.current.pin()
+
+	return m.current.data[offset:end:end], m.current, nil
+
+}
+
 // WriteAt writes data to the mapped file at the specified offset
 func (m *MappedFile) WriteAt(b []byte, offset int64) (int, error) {
 
@@ -111,7 +231,7 @@ func (m *MappedFile) WriteAt(b []byte, offset int64) (int, error) {
 
 	}
 
-	copy(m.data[offset:], b)
+	copy(m.current.data[offset:], b)
 
 	// Update current offset if this write extends it
 	if offset+int64(len(b)) > m.currentOffset {
@@ -123,8 +243,9 @@ func (m *MappedFile) WriteAt(b []byte, offset int64) (int, error) {
 	return len(b), nil
 }
 
-// grow increases the size of the mapped region
-func (m *MappedFile) grow(newSize int) error {
+// grow makes the mapped region hold at least requiredSize bytes, growing it
+// geometrically
+func (m *MappedFile) grow(requiredSize int) error {
 
 	m.mu.Lock()
 
@@ -136,16 +257,31 @@ func (m *MappedFile) grow(newSize int) error {
 
 	}
 
-	if newSize <= m.size {
+	if requiredSize <= m.size {
 
 		return nil // Already large enough
 
 	}
 
-	// Unmap current region
-	if err := syscall.Munmap(m.data); err != nil {
+	step := m.size
 
-		return fmt.Errorf("failed to unmap file: %v", err)
+	if step < mmapGrowMin {
+
+		step = mmapGrowMin
+
+	}
+
+	if step > mmapGrowMax {
+
+		step = mmapGrowMax
+
+	}
+
+	newSize := m.size + step
+
+	if newSize < requiredSize {
+
+		newSize = (requiredSize + BlockSize - 1) / BlockSize * BlockSize
 
 	}
 
@@ -176,7 +312,16 @@ func (m *MappedFile) grow(newSize int) error {
 
 	}
 
-	m.data = data
+	// views handed out earlier keep the old mapping until they are released
+	previous := m.current
+
+	m.current = &mapping{data: data}
+
+	if err := previous.retire(); err != nil {
+
+		return err
+
+	}
 
 	m.size = newSize
 
@@ -184,6 +329,17 @@ func (m *MappedFile) grow(newSize int) error {
 
 }
 
+// getSize returns the length of the mapped region
+func (m *MappedFile) getSize() int {
+
+	m.mu.RLock()
+
+	defer m.mu.RUnlock()
+
+	return m.size
+
+}
+
 // sync flushes changes to disk
 func (m *MappedFile) sync() error {
 
@@ -197,13 +353,27 @@ func (m *MappedFile) sync() error {
 
 	}
 
-	return unix.Msync(m.data, unix.MS_SYNC)
+	return unix.Msync(m.current.data, unix.MS_SYNC)
 
 }
 
 // syncAndClose syncs data to disk and closes the file
 func (m *MappedFile) syncAndClose() error {
 
+	if err := m.detach(); err != nil {
+
+		return err
+
+	}
+
+	return m.unmap()
+
+}
+
+// detach syncs data to disk and closes the file but keeps the mapping, so
+// that views handed out earlier stay readable until unmap is called
+func (m *MappedFile) detach() error {
+
 	m.mu.Lock()
 
 	defer m.mu.Unlock()
@@ -215,19 +385,12 @@ func (m *MappedFile) syncAndClose() error {
 	}
 
 	// Sync changes to disk
-	if err := unix.Msync(m.data, unix.MS_SYNC); err != nil {
+	if err := unix.Msync(m.current.data, unix.MS_SYNC); err != nil {
 
 		return fmt.Errorf("failed to sync file: %v", err)
 
 	}
 
-	// Unmap memory
-	if err := syscall.Munmap(m.data); err != nil {
-
-		return fmt.Errorf("failed to unmap file: %v", err)
-
-	}
-
 	// Close file
 	if err := m.file.Close(); err != nil {
 
@@ -241,6 +404,18 @@ func (m *MappedFile) syncAndClose() error {
 
 }
 
+// unmap releases the mapping of a detached file; views still pinning it
+// keep it until they are released
+func (m *MappedFile) unmap() error {
+
+	m.mu.Lock()
+
+	defer m.mu.Unlock()
+
+	return m.current.retire()
+
+}
+
 // min returns the minimum of two int64 values
 func min(a, b int64) int64 {
 
@@ -0,0 +1,75 @@
+package storageEngine
+
+import (
+	. "packx/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestMappedFileGrowsGeometrically(t *testing.T) {
+	mmap, err := openMappedFile(filepath.Join(t.TempDir(), "data.bin"), BlockSize)
+	if err != nil {
+		t.Fatalf("openMappedFile: %v", err)
+	}
+	defer mmap.syncAndClose()
+
+	remaps := 0
+	for blocks := 2; blocks <= 100000; blocks++ {
+		previous := mmap.current
+		if err := mmap.grow(blocks * BlockSize); err != nil {
+			t.Fatalf("grow: %v", err)
+		}
+		if mmap.current != previous {
+			remaps++
+		}
+	}
+
+	if mmap.getSize() < 100000*BlockSize {
+		t.Fatalf("expected room for 100000 blocks, got %d bytes", mmap.getSize())
+	}
+	if remaps > 20 {
+		t.Fatalf("expected a handful of remaps for 100000 blocks, got %d", remaps)
+	}
+}
+
+func TestRetiredMappingIsUnmappedWhenReleased(t *testing.T) {
+	mmap, err := openMappedFile(filepath.Join(t.TempDir(), "data.bin"), BlockSize)
+	if err != nil {
+		t.Fatalf("openMappedFile: %v", err)
+	}
+	defer mmap.syncAndClose()
+
+	if _, err := mmap.WriteAt([]byte{42}, 0); err != nil {
+		t.Fatalf("WriteAt: %v", err)
+	}
+
+	view, pinned, err := mmap.pinnedView(0, BlockSize)
+	if err != nil {
+		t.Fatalf("pinnedView: %v", err)
+	}
+
+	// a mapping nobody pins goes right away, a pinned one when released
+	unpinned := mmap.current
+	if err := mmap.grow(2 * BlockSize); err != nil {
+		t.Fatalf("grow: %v", err)
+	}
+	if pinned.data == nil {
+		t.Fatalf("expected the pinned mapping to outlive grow")
+	}
+	if view[0] != 42 {
+		t.Fatalf("expected the view to stay readable, got %d", view[0])
+	}
+
+	second := mmap.current
+	if err := mmap.grow(mmap.getSize() + BlockSize); err != nil {
+		t.Fatalf("grow: %v", err)
+	}
+	if second.data != nil {
+		t.Fatalf("expected the unpinned mapping to be unmapped by grow")
+	}
+
+	pinned.unpin()
+	if unpinned.data != nil {
+		t.Fatalf("expected the retired mapping to be unmapped with its last pin")
+	}
+}
@@ -0,0 +1,142 @@
+package storageEngine
+
+import (
+	"fmt"
+	"os"
+	. "packx/utils"
+	"path/filepath"
+	"sort"
+)
+
+// DeviceRange is the time span a device has records in, as far as its blocks tell
+type DeviceRange struct {
+	DeviceID uint32
+
+	FirstTimestamp uint32
+
+	LastTimestamp uint32
+}
+
+// DeviceIDs returns every device with blocks in a counter day, in ascending order
+func (bs *StorageEngine) DeviceIDs(counterPath string) ([]uint32, error) {
+
+	var ids []uint32
+
+	for partition := 0; partition < NumPartitions; partition++ {
+
+		partitionPath := filepath.Join(counterPath, fmt.Sprintf("partition_%d", partition))
+
+		if _, err := os.Stat(filepath.Join(partitionPath, "data.bin")); err != nil {
+			continue
+		}
+
+		bs.partitionLocks[partition].RLock()
+
+		index, err := bs.getBlockIndex(partitionPath)
+
+		if err == nil {
+
+			index.mu.RLock()
+
+			for deviceID := range index.byDevice {
+				ids = append(ids, deviceID)
+			}
+
+			index.mu.RUnlock()
+
+		}
+
+		bs.partitionLocks[partition].RUnlock()
+
+		if err != nil {
+
+			return nil, err
+
+		}
+	}
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	return ids, nil
+}
+
+// DeviceRanges returns every device of a counter day with blocks overlapping
+// [from, to], in ascending order. The timestamps come from the block index and
+// are clamped to the window, so a block straddling a bound widens the range
+// up to that bound.
+func (bs *StorageEngine) DeviceRanges(counterPath string, from uint32, to uint32) ([]DeviceRange, error) {
+
+	ranges := make(map[uint32]*DeviceRange)
+
+	for partition := 0; partition < NumPartitions; partition++ {
+
+		partitionPath := filepath.Join(counterPath, fmt.Sprintf("partition_%d", partition))
+
+		if _, err := os.Stat(filepath.Join(partitionPath, "data.bin")); err != nil {
+			continue
+		}
+
+		bs.partitionLocks[partition].RLock()
+
+		index, err := bs.getBlockIndex(partitionPath)
+
+		var entries []IndexEntry
+
+		if err == nil {
+			entries = index.allEntries()
+		}
+
+		bs.partitionLocks[partition].RUnlock()
+
+		if err != nil {
+
+			return nil, err
+
+		}
+
+		for _, entry := range entries {
+
+			if entry.EndTimestamp < from || entry.StartTimestamp > to {
+				continue
+			}
+
+			first, last := entry.StartTimestamp, entry.EndTimestamp
+
+			if first < from {
+				first = from
+			}
+
+			if last > to {
+				last = to
+			}
+
+			deviceRange, exists := ranges[entry.DeviceID]
+
+			if !exists {
+
+				ranges[entry.DeviceID] = &DeviceRange{DeviceID: entry.DeviceID, FirstTimestamp: first, LastTimestamp: last}
+
+				continue
+
+			}
+
+			if first < deviceRange.FirstTimestamp {
+				deviceRange.FirstTimestamp = first
+			}
+
+			if last > deviceRange.LastTimestamp {
+				deviceRange.LastTimestamp = last
+			}
+		}
+	}
+
+	result := make([]DeviceRange, 0, len(ranges))
+
+	for _, deviceRange := range ranges {
+		result = append(result, *deviceRange)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].DeviceID < result[j].DeviceID })
+
+	return result, nil
+}
@@ -0,0 +1,51 @@
+package storageEngine
+
+import (
+	. "packx/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeviceRangesClampToWindow(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	defer engine.Close()
+
+	// devices 1 and 4 share a partition, device 2 has its own
+	for device, timestamps := range map[int][]uint32{1: {100, 200}, 2: {300, 400}, 4: {150, 500}} {
+		for _, timestamp := range timestamps {
+			if err := engine.PutBatch(counterPath, device, TypeFloat, [][]byte{floatRecord(timestamp, 1)}); err != nil {
+				t.Fatalf("PutBatch: %v", err)
+			}
+		}
+	}
+
+	ranges, err := engine.DeviceRanges(counterPath, 0, 1000)
+	if err != nil {
+		t.Fatalf("DeviceRanges: %v", err)
+	}
+	expected := []DeviceRange{{1, 100, 200}, {2, 300, 400}, {4, 150, 500}}
+	if len(ranges) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, ranges)
+	}
+	for i := range expected {
+		if ranges[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, ranges)
+		}
+	}
+
+	ranges, _ = engine.DeviceRanges(counterPath, 250, 450)
+	if len(ranges) != 2 || ranges[0] != (DeviceRange{2, 300, 400}) || ranges[1] != (DeviceRange{4, 250, 450}) {
+		t.Fatalf("unexpected ranges in [250, 450]: %v", ranges)
+	}
+
+	ids, _ := engine.DeviceIDs(counterPath)
+	if len(ids) != 3 {
+		t.Fatalf("expected 3 devices, got %v", ids)
+	}
+}
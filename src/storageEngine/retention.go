@@ -0,0 +1,140 @@
+package storageEngine
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DropCounterDay deletes one day of a counter, counterPath being
+// <root>/YYYY/MM/DD/counter_N. Writes are held off while the day's mapped
+// files, indexes and dictionaries are released and its directory and rollups
+// are removed. Empty parent day, month and year directories are removed as well.
+func (bs *StorageEngine) DropCounterDay(counterPath string) error {
+
+	counterPath = filepath.Clean(counterPath)
+
+	// nothing may stay in the log for a directory that is about to vanish
+	bs.walLock.Lock()
+
+	defer bs.walLock.Unlock()
+
+	if err := bs.checkpointLocked(); err != nil {
+
+		return fmt.Errorf("failed to checkpoint before dropping %s: %v", counterPath, err)
+
+	}
+
+	for partition := range bs.partitionLocks {
+
+		bs.partitionLocks[partition].Lock()
+
+		defer bs.partitionLocks[partition].Unlock()
+
+	}
+
+	inDay := func(path string) bool {
+		return strings.HasPrefix(path, counterPath+string(filepath.Separator))
+	}
+
+	bs.mmapFilesLock.Lock()
+
+	for path, mmap := range bs.mmapFiles {
+
+		if !inDay(path) {
+			continue
+		}
+
+		// views of the day still being read keep its mapping until released
+		if err := mmap.syncAndClose(); err != nil {
+
+			log.Printf("Error closing %s: %v", path, err)
+
+		}
+
+		delete(bs.mmapFiles, path)
+
+	}
+
+	bs.mmapFilesLock.Unlock()
+
+	bs.indexesLock.Lock()
+
+	for path, index := range bs.indexes {
+
+		if !inDay(path) {
+			continue
+		}
+
+		if err := index.close(); err != nil {
+
+			log.Printf("Error closing block index %s: %v", path, err)
+
+		}
+
+		delete(bs.indexes, path)
+
+	}
+
+	bs.indexesLock.Unlock()
+
+	bs.dictionariesLock.Lock()
+
+	for path, dict := range bs.dictionaries {
+
+		if !inDay(path) {
+			continue
+		}
+
+		if err := dict.close(); err != nil {
+
+			log.Printf("Error closing string dictionary %s: %v", path, err)
+
+		}
+
+		delete(bs.dictionaries, path)
+
+	}
+
+	bs.dictionariesLock.Unlock()
+
+	bs.blockManager.forget(inDay)
+
+	// the day's rollups go with it
+	for _, path := range []string{bs.rollupDayPath(counterPath), counterPath} {
+
+		if err := os.RemoveAll(path); err != nil {
+
+			return fmt.Errorf("failed to remove %s: %v", path, err)
+
+		}
+
+		bs.removeEmptyParents(path)
+
+	}
+
+	return nil
+}
+
+// removeEmptyParents drops the day, month and year directories above path
+// once they are empty
+func (bs *StorageEngine) removeEmptyParents(path string) {
+
+	for dir := filepath.Dir(path); bs.rootPath != "" && inRoot(bs.rootPath, dir); dir = filepath.Dir(dir) {
+
+		if os.Remove(dir) != nil {
+			break
+		}
+
+	}
+}
+
+// inRoot reports whether dir lies strictly below root
+func inRoot(root string, dir string) bool {
+
+	rel, err := filepath.Rel(root, dir)
+
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,60 @@
+package storageEngine
+
+import (
+	"os"
+	. "packx/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestDropCounterDay(t *testing.T) {
+	root := t.TempDir()
+	oldDay := filepath.Join(root, "2025/03/01/counter_2")
+	newDay := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	defer engine.Close()
+
+	for _, counterPath := range []string{oldDay, newDay} {
+		if err := engine.PutBatch(counterPath, 1, TypeFloat, [][]byte{floatRecord(10, 1), floatRecord(11, 2)}); err != nil {
+			t.Fatalf("PutBatch: %v", err)
+		}
+	}
+
+	views, err := engine.GetRangeByPath(1, oldDay, 0, ^uint32(0))
+	if err != nil || len(views) != 1 {
+		t.Fatalf("GetRangeByPath: %d views, %v", len(views), err)
+	}
+
+	if err := engine.DropCounterDay(oldDay); err != nil {
+		t.Fatalf("DropCounterDay: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "2025/03")); !os.IsNotExist(err) {
+		t.Fatalf("expected the empty month directory to be removed, got %v", err)
+	}
+
+	// views taken before the drop stay readable until they are released
+	if views[0].Header.RecordCount != 2 || len(views[0].Data) == 0 || views[0].Data[3] != 10 {
+		t.Fatalf("unexpected view after drop: %+v", views[0].Header)
+	}
+	ReleaseViews(views)
+
+	if got := countRecords(t, engine, oldDay, 1); got != 0 {
+		t.Fatalf("expected the dropped day to be empty, got %d records", got)
+	}
+	if got := countRecords(t, engine, newDay, 1); got != 2 {
+		t.Fatalf("expected the other day to be untouched, got %d records", got)
+	}
+
+	// late data for the dropped day starts a fresh directory
+	if err := engine.PutBatch(oldDay, 1, TypeFloat, [][]byte{floatRecord(12, 3)}); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	if got := countRecords(t, engine, oldDay, 1); got != 1 {
+		t.Fatalf("expected 1 record in the recreated day, got %d", got)
+	}
+}
@@ -0,0 +1,358 @@
+package storageEngine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	. "packx/utils"
+	"path/filepath"
+	"sort"
+)
+
+// Rollup files. The downsampled form of one day of a counter lives in a tree
+// beside the raw data, one file per resolution:
+//
+//	<root>/rollups/YYYY/MM/DD/counter_N/rollup_<seconds>.bin
+//
+//	file   := magic(4) format(1) resolution(4) sourceVersion(8) objectCount(4)
+//	          { objectID(4) recordOffset(8) recordCount(4) } records
+//	record := start(4) last(4) count(4) min(8) max(8) sum(8)
+//
+// sourceVersion is the CounterDayVersion of the raw data the rollup was built
+// from; a rollup whose version no longer matches is stale. Files are written
+// to a temporary name and renamed, so readers never see a partial rollup.
+
+const (
+	rollupDirName = "rollups"
+
+	rollupMagic = "RDBR"
+
+	rollupFormat = 1
+
+	rollupHeaderSize = 21
+
+	rollupTableEntrySize = 16
+
+	rollupRecordSize = 36
+)
+
+// RollupPoint summarizes the records of one object in one interval
+type RollupPoint struct {
+	Start uint32 // start of the interval, a multiple of the resolution
+
+	Last uint32 // timestamp of the latest record in the interval
+
+	Count uint32
+
+	Min float64
+
+	Max float64
+
+	Sum float64
+}
+
+// CounterDayVersion fingerprints the raw data of a counter day. It changes
+// whenever a record is added to any of its blocks.
+func (bs *StorageEngine) CounterDayVersion(counterPath string) (uint64, error) {
+
+	hash := fnv.New64a()
+
+	buf := make([]byte, 4+8+4+4+4)
+
+	headerData := make([]byte, BlockHeaderSize)
+
+	for partition := 0; partition < NumPartitions; partition++ {
+
+		partitionPath := filepath.Join(counterPath, fmt.Sprintf("partition_%d", partition))
+
+		dataFile := filepath.Join(partitionPath, "data.bin")
+
+		if _, err := os.Stat(dataFile); err != nil {
+			continue
+		}
+
+		if err := func() error {
+
+			bs.partitionLocks[partition].RLock()
+
+			defer bs.partitionLocks[partition].RUnlock()
+
+			index, err := bs.getBlockIndex(partitionPath)
+
+			if err != nil {
+
+				return err
+
+			}
+
+			mmapFile, err := bs.getMappedDataFile(dataFile)
+
+			if err != nil {
+
+				return err
+
+			}
+
+			for _, entry := range index.allEntries() {
+
+				if _, err := mmapFile.ReadAt(headerData, entry.BlockOffset); err != nil {
+
+					return fmt.Errorf("failed to read block header at offset %d: %v", entry.BlockOffset, err)
+
+				}
+
+				header := decodeBlockHeader(headerData)
+
+				binary.LittleEndian.PutUint32(buf[0:4], entry.DeviceID)
+
+				binary.LittleEndian.PutUint64(buf[4:12], uint64(entry.BlockOffset))
+
+				binary.LittleEndian.PutUint32(buf[12:16], header.RecordCount)
+
+				binary.LittleEndian.PutUint32(buf[16:20], header.StartTimestamp)
+
+				binary.LittleEndian.PutUint32(buf[20:24], header.EndTimestamp)
+
+				hash.Write(buf)
+			}
+
+			return nil
+
+		}(); err != nil {
+
+			return 0, err
+
+		}
+	}
+
+	return hash.Sum64(), nil
+}
+
+// WriteRollup replaces the rollup of a counter day at one resolution
+func (bs *StorageEngine) WriteRollup(counterPath string, resolution uint32, sourceVersion uint64, points map[uint32][]RollupPoint) error {
+
+	path := bs.rollupPath(counterPath, resolution)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+
+		return fmt.Errorf("failed to create rollup directory: %v", err)
+
+	}
+
+	objectIDs := make([]uint32, 0, len(points))
+
+	total := 0
+
+	for objectID, objectPoints := range points {
+
+		objectIDs = append(objectIDs, objectID)
+
+		total += len(objectPoints)
+
+	}
+
+	sort.Slice(objectIDs, func(i, j int) bool { return objectIDs[i] < objectIDs[j] })
+
+	tableSize := len(objectIDs) * rollupTableEntrySize
+
+	buf := make([]byte, rollupHeaderSize+tableSize+total*rollupRecordSize)
+
+	copy(buf[0:4], rollupMagic)
+
+	buf[4] = rollupFormat
+
+	binary.LittleEndian.PutUint32(buf[5:9], resolution)
+
+	binary.LittleEndian.PutUint64(buf[9:17], sourceVersion)
+
+	binary.LittleEndian.PutUint32(buf[17:21], uint32(len(objectIDs)))
+
+	recordOffset := rollupHeaderSize + tableSize
+
+	for i, objectID := range objectIDs {
+
+		entry := buf[rollupHeaderSize+i*rollupTableEntrySize:]
+
+		binary.LittleEndian.PutUint32(entry[0:4], objectID)
+
+		binary.LittleEndian.PutUint64(entry[4:12], uint64(recordOffset))
+
+		binary.LittleEndian.PutUint32(entry[12:16], uint32(len(points[objectID])))
+
+		for _, point := range points[objectID] {
+
+			record := buf[recordOffset : recordOffset+rollupRecordSize]
+
+			binary.LittleEndian.PutUint32(record[0:4], point.Start)
+
+			binary.LittleEndian.PutUint32(record[4:8], point.Last)
+
+			binary.LittleEndian.PutUint32(record[8:12], point.Count)
+
+			binary.LittleEndian.PutUint64(record[12:20], math.Float64bits(point.Min))
+
+			binary.LittleEndian.PutUint64(record[20:28], math.Float64bits(point.Max))
+
+			binary.LittleEndian.PutUint64(record[28:36], math.Float64bits(point.Sum))
+
+			recordOffset += rollupRecordSize
+		}
+	}
+
+	if err := os.WriteFile(path+".tmp", buf, 0644); err != nil {
+
+		return fmt.Errorf("failed to write rollup: %v", err)
+
+	}
+
+	if err := os.Rename(path+".tmp", path); err != nil {
+
+		return fmt.Errorf("failed to replace rollup: %v", err)
+
+	}
+
+	return nil
+}
+
+// RollupSourceVersion returns the source version a rollup was built from. The
+// error satisfies os.IsNotExist when the rollup has not been built.
+func (bs *StorageEngine) RollupSourceVersion(counterPath string, resolution uint32) (uint64, error) {
+
+	file, err := os.Open(bs.rollupPath(counterPath, resolution))
+
+	if err != nil {
+
+		return 0, err
+
+	}
+
+	defer file.Close()
+
+	_, sourceVersion, _, err := readRollupHeader(file, resolution)
+
+	return sourceVersion, err
+}
+
+// ReadRollup returns the rollup points of one object, ordered by interval,
+// together with the source version of the rollup. The error satisfies
+// os.IsNotExist when the rollup has not been built.
+func (bs *StorageEngine) ReadRollup(counterPath string, resolution uint32, objectID uint32) ([]RollupPoint, uint64, error) {
+
+	file, err := os.Open(bs.rollupPath(counterPath, resolution))
+
+	if err != nil {
+
+		return nil, 0, err
+
+	}
+
+	defer file.Close()
+
+	objectCount, sourceVersion, table, err := readRollupHeader(file, resolution)
+
+	if err != nil {
+
+		return nil, 0, err
+
+	}
+
+	// the table is sorted by object ID
+	pos := sort.Search(int(objectCount), func(i int) bool {
+		return binary.LittleEndian.Uint32(table[i*rollupTableEntrySize:]) >= objectID
+	})
+
+	if pos == int(objectCount) || binary.LittleEndian.Uint32(table[pos*rollupTableEntrySize:]) != objectID {
+
+		return nil, sourceVersion, nil
+
+	}
+
+	entry := table[pos*rollupTableEntrySize:]
+
+	recordOffset := int64(binary.LittleEndian.Uint64(entry[4:12]))
+
+	records := make([]byte, int(binary.LittleEndian.Uint32(entry[12:16]))*rollupRecordSize)
+
+	if _, err := file.ReadAt(records, recordOffset); err != nil {
+
+		return nil, 0, fmt.Errorf("failed to read rollup records: %v", err)
+
+	}
+
+	points := make([]RollupPoint, 0, len(records)/rollupRecordSize)
+
+	for offset := 0; offset < len(records); offset += rollupRecordSize {
+
+		record := records[offset : offset+rollupRecordSize]
+
+		points = append(points, RollupPoint{
+			Start: binary.LittleEndian.Uint32(record[0:4]),
+			Last:  binary.LittleEndian.Uint32(record[4:8]),
+			Count: binary.LittleEndian.Uint32(record[8:12]),
+			Min:   math.Float64frombits(binary.LittleEndian.Uint64(record[12:20])),
+			Max:   math.Float64frombits(binary.LittleEndian.Uint64(record[20:28])),
+			Sum:   math.Float64frombits(binary.LittleEndian.Uint64(record[28:36])),
+		})
+	}
+
+	return points, sourceVersion, nil
+}
+
+// readRollupHeader validates a rollup file and returns its object count,
+// source version and object table
+func readRollupHeader(file *os.File, resolution uint32) (uint32, uint64, []byte, error) {
+
+	header := make([]byte, rollupHeaderSize)
+
+	if _, err := file.ReadAt(header, 0); err != nil {
+
+		return 0, 0, nil, fmt.Errorf("failed to read rollup header: %v", err)
+
+	}
+
+	if string(header[0:4]) != rollupMagic || header[4] != rollupFormat {
+
+		return 0, 0, nil, fmt.Errorf("%s is not a rollup file", file.Name())
+
+	}
+
+	if stored := binary.LittleEndian.Uint32(header[5:9]); stored != resolution {
+
+		return 0, 0, nil, fmt.Errorf("%s holds %ds rollups, expected %ds", file.Name(), stored, resolution)
+
+	}
+
+	objectCount := binary.LittleEndian.Uint32(header[17:21])
+
+	table := make([]byte, int(objectCount)*rollupTableEntrySize)
+
+	if _, err := file.ReadAt(table, rollupHeaderSize); err != nil && len(table) > 0 {
+
+		return 0, 0, nil, fmt.Errorf("failed to read rollup table: %v", err)
+
+	}
+
+	return objectCount, binary.LittleEndian.Uint64(header[9:17]), table, nil
+}
+
+// rollupDayPath returns the directory holding the rollups of a counter day
+func (bs *StorageEngine) rollupDayPath(counterPath string) string {
+
+	rel := bs.relativeToRoot(counterPath)
+
+	if filepath.IsAbs(rel) {
+
+		// outside the engine root, keep the rollups next to the raw data
+		return filepath.Join(counterPath, rollupDirName)
+
+	}
+
+	return filepath.Join(bs.rootPath, rollupDirName, rel)
+}
+
+func (bs *StorageEngine) rollupPath(counterPath string, resolution uint32) string {
+
+	return filepath.Join(bs.rollupDayPath(counterPath), fmt.Sprintf("rollup_%d.bin", resolution))
+}
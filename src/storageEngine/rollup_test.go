@@ -0,0 +1,74 @@
+package storageEngine
+
+import (
+	"os"
+	. "packx/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestRollupRoundTripAndVersion(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+	defer engine.Close()
+
+	if _, _, err := engine.ReadRollup(counterPath, 60, 1); !os.IsNotExist(err) {
+		t.Fatalf("expected a missing rollup, got %v", err)
+	}
+
+	for _, device := range []int{1, 2, 4} {
+		if err := engine.PutBatch(counterPath, device, TypeFloat, [][]byte{floatRecord(60, 1), floatRecord(90, 3)}); err != nil {
+			t.Fatalf("PutBatch: %v", err)
+		}
+	}
+
+	ids, err := engine.DeviceIDs(counterPath)
+	if err != nil || len(ids) != 3 || ids[0] != 1 || ids[2] != 4 {
+		t.Fatalf("DeviceIDs: %v %v", ids, err)
+	}
+
+	version, err := engine.CounterDayVersion(counterPath)
+	if err != nil {
+		t.Fatalf("CounterDayVersion: %v", err)
+	}
+
+	points := map[uint32][]RollupPoint{
+		1: {{Start: 60, Last: 90, Count: 2, Min: 1, Max: 3, Sum: 4}},
+		4: {{Start: 60, Last: 60, Count: 1, Min: 5, Max: 5, Sum: 5}, {Start: 120, Last: 130, Count: 1, Min: 6, Max: 6, Sum: 6}},
+	}
+	if err := engine.WriteRollup(counterPath, 60, version, points); err != nil {
+		t.Fatalf("WriteRollup: %v", err)
+	}
+
+	got, sourceVersion, err := engine.ReadRollup(counterPath, 60, 4)
+	if err != nil || sourceVersion != version {
+		t.Fatalf("ReadRollup: version %d, expected %d, %v", sourceVersion, version, err)
+	}
+	if len(got) != 2 || got[1] != points[4][1] {
+		t.Fatalf("unexpected rollup points %+v", got)
+	}
+	if got, _, _ := engine.ReadRollup(counterPath, 60, 2); got != nil {
+		t.Fatalf("expected no points for an object without a rollup, got %+v", got)
+	}
+
+	// any new record changes the version the rollup is checked against
+	if err := engine.PutBatch(counterPath, 2, TypeFloat, [][]byte{floatRecord(95, 2)}); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+	if changed, _ := engine.CounterDayVersion(counterPath); changed == version {
+		t.Fatalf("expected the version to change after a write")
+	}
+
+	// rollups go with their day
+	if err := engine.DropCounterDay(counterPath); err != nil {
+		t.Fatalf("DropCounterDay: %v", err)
+	}
+	if _, err := engine.RollupSourceVersion(counterPath, 60); !os.IsNotExist(err) {
+		t.Fatalf("expected the rollup to be dropped, got %v", err)
+	}
+}
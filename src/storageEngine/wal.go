@@ -0,0 +1,586 @@
+package storageEngine
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Write-ahead log layout. Every batch handed to PutBatch is appended as one
+// record, and synced, before any byte of it reaches a mapped data file:
+//
+//	record  := length(4) crc32(4) payload(length)
+//	payload := lsn(8) pathLen(2) path key(4) dataType(1) count(4) { recLen(4) rec }
+//
+// The crc covers the payload only. A record whose length runs past the end of
+// the file or whose crc does not match is treated as a torn write: replay
+// stops there and the log is truncated to the last good record.
+//
+// Every checksummed block stores the walPosition of the last record written
+// to it, so replay applies exactly the records after the position of each
+// device's newest block. LSNs keep increasing across checkpoints and
+// restarts: the next one is saved in the checkpoint file before the log is
+// truncated.
+
+const (
+	walDirName = "wal"
+
+	walFileName = "wal.log"
+
+	// walCheckpointFileName holds the next LSN while the log is empty
+	walCheckpointFileName = "checkpoint"
+
+	// walRecordHeaderSize is the length + crc prefix of every record
+	walRecordHeaderSize = 8
+
+	// walCheckpointInterval is how often synced blocks are released from the log
+	walCheckpointInterval = 30 * time.Second
+
+	// walMaxSize forces an early checkpoint when the log grows past it
+	walMaxSize = 64 * 1024 * 1024
+)
+
+// walEntry is a single logged batch of serialized records for one device
+type walEntry struct {
+	lsn uint64
+
+	path string // counter path, relative to the engine root when possible
+
+	key int
+
+	dataType byte
+
+	records [][]byte
+}
+
+// walPosition is the place of a record in the log: the LSN of its entry and
+// its index among the entry's records
+type walPosition struct {
+	lsn uint64
+
+	seq uint32
+}
+
+// before reports whether p comes earlier in the log than other
+func (p walPosition) before(other walPosition) bool {
+	return p.lsn < other.lsn || (p.lsn == other.lsn && p.seq < other.seq)
+}
+
+type writeAheadLog struct {
+	mu sync.Mutex
+
+	file *os.File
+
+	checkpointPath string
+
+	size int64
+
+	nextLSN uint64
+}
+
+// openWriteAheadLog opens (or creates) the log in dir and returns every intact
+// entry that is still waiting to be checkpointed.
+func openWriteAheadLog(dir string) (*writeAheadLog, []walEntry, error) {
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+
+		return nil, nil, fmt.Errorf("failed to create wal directory: %v", err)
+
+	}
+
+	path := filepath.Join(dir, walFileName)
+
+	data, err := os.ReadFile(path)
+
+	if err != nil && !os.IsNotExist(err) {
+
+		return nil, nil, fmt.Errorf("failed to read wal: %v", err)
+
+	}
+
+	entries, validSize := decodeWalRecords(data)
+
+	if validSize < int64(len(data)) {
+
+		log.Printf("Write-ahead log %s has a torn record at offset %d, truncating %d bytes", path, validSize, int64(len(data))-validSize)
+
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+
+	if err != nil {
+
+		return nil, nil, fmt.Errorf("failed to open wal: %v", err)
+
+	}
+
+	if err := file.Truncate(validSize); err != nil {
+
+		file.Close()
+
+		return nil, nil, fmt.Errorf("failed to truncate wal: %v", err)
+
+	}
+
+	if _, err := file.Seek(validSize, 0); err != nil {
+
+		file.Close()
+
+		return nil, nil, fmt.Errorf("failed to seek wal: %v", err)
+
+	}
+
+	wal := &writeAheadLog{
+		file:           file,
+		checkpointPath: filepath.Join(dir, walCheckpointFileName),
+		size:           validSize,
+		nextLSN:        1,
+	}
+
+	if checkpoint, err := os.ReadFile(wal.checkpointPath); err == nil && len(checkpoint) == 8 {
+		wal.nextLSN = binary.LittleEndian.Uint64(checkpoint)
+	}
+
+	if len(entries) > 0 && entries[len(entries)-1].lsn >= wal.nextLSN {
+		wal.nextLSN = entries[len(entries)-1].lsn + 1
+	}
+
+	return wal, entries, nil
+}
+
+// append writes the entry to the log and syncs it to disk
+func (w *writeAheadLog) append(entry *walEntry) error {
+
+	w.mu.Lock()
+
+	defer w.mu.Unlock()
+
+	entry.lsn = w.nextLSN
+
+	record := encodeWalRecord(entry)
+
+	if _, err := w.file.Write(record); err != nil {
+
+		// drop whatever part of the record made it to the file
+		w.file.Truncate(w.size)
+
+		w.file.Seek(w.size, 0)
+
+		return fmt.Errorf("failed to write wal record: %v", err)
+
+	}
+
+	if err := w.file.Sync(); err != nil {
+
+		return fmt.Errorf("failed to sync wal: %v", err)
+
+	}
+
+	w.size += int64(len(record))
+
+	w.nextLSN++
+
+	return nil
+}
+
+// truncate discards every logged entry; callers must have synced the data first
+func (w *writeAheadLog) truncate() error {
+
+	w.mu.Lock()
+
+	defer w.mu.Unlock()
+
+	// blocks keep the positions of the discarded entries, so the LSNs that
+	// follow must not start over
+	if err := w.saveNextLSN(); err != nil {
+
+		return err
+
+	}
+
+	if err := w.file.Truncate(0); err != nil {
+
+		return fmt.Errorf("failed to truncate wal: %v", err)
+
+	}
+
+	if _, err := w.file.Seek(0, 0); err != nil {
+
+		return fmt.Errorf("failed to seek wal: %v", err)
+
+	}
+
+	w.size = 0
+
+	return w.file.Sync()
+}
+
+// saveNextLSN durably records the next LSN in the checkpoint file
+func (w *writeAheadLog) saveNextLSN() error {
+
+	buf := make([]byte, 8)
+
+	binary.LittleEndian.PutUint64(buf, w.nextLSN)
+
+	tmpPath := w.checkpointPath + ".tmp"
+
+	file, err := os.Create(tmpPath)
+
+	if err != nil {
+
+		return fmt.Errorf("failed to create wal checkpoint: %v", err)
+
+	}
+
+	if _, err := file.Write(buf); err != nil {
+
+		file.Close()
+
+		return fmt.Errorf("failed to write wal checkpoint: %v", err)
+
+	}
+
+	if err := file.Sync(); err != nil {
+
+		file.Close()
+
+		return fmt.Errorf("failed to sync wal checkpoint: %v", err)
+
+	}
+
+	file.Close()
+
+	if err := os.Rename(tmpPath, w.checkpointPath); err != nil {
+
+		return fmt.Errorf("failed to replace wal checkpoint: %v", err)
+
+	}
+
+	return nil
+}
+
+func (w *writeAheadLog) getSize() int64 {
+
+	w.mu.Lock()
+
+	defer w.mu.Unlock()
+
+	return w.size
+}
+
+func (w *writeAheadLog) close() error {
+
+	w.mu.Lock()
+
+	defer w.mu.Unlock()
+
+	if err := w.file.Sync(); err != nil {
+
+		return fmt.Errorf("failed to sync wal: %v", err)
+
+	}
+
+	return w.file.Close()
+}
+
+func encodeWalRecord(entry *walEntry) []byte {
+
+	payloadSize := 8 + 2 + len(entry.path) + 4 + 1 + 4
+
+	for _, rec := range entry.records {
+		payloadSize += 4 + len(rec)
+	}
+
+	buf := make([]byte, walRecordHeaderSize+payloadSize)
+
+	payload := buf[walRecordHeaderSize:]
+
+	pos := 0
+
+	binary.LittleEndian.PutUint64(payload[pos:], entry.lsn)
+	pos += 8
+
+	binary.LittleEndian.PutUint16(payload[pos:], uint16(len(entry.path)))
+	pos += 2
+
+	pos += copy(payload[pos:], entry.path)
+
+	binary.LittleEndian.PutUint32(payload[pos:], uint32(entry.key))
+	pos += 4
+
+	payload[pos] = entry.dataType
+	pos++
+
+	binary.LittleEndian.PutUint32(payload[pos:], uint32(len(entry.records)))
+	pos += 4
+
+	for _, rec := range entry.records {
+
+		binary.LittleEndian.PutUint32(payload[pos:], uint32(len(rec)))
+		pos += 4
+
+		pos += copy(payload[pos:], rec)
+	}
+
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(payloadSize))
+
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+
+	return buf
+}
+
+// decodeWalRecords parses records until the first torn or corrupt one and
+// returns the entries together with the length of the intact prefix.
+func decodeWalRecords(data []byte) ([]walEntry, int64) {
+
+	var entries []walEntry
+
+	offset := 0
+
+	for offset+walRecordHeaderSize <= len(data) {
+
+		length := int(binary.LittleEndian.Uint32(data[offset : offset+4]))
+
+		checksum := binary.LittleEndian.Uint32(data[offset+4 : offset+8])
+
+		end := offset + walRecordHeaderSize + length
+
+		if length == 0 || end > len(data) {
+			break
+		}
+
+		payload := data[offset+walRecordHeaderSize : end]
+
+		if crc32.ChecksumIEEE(payload) != checksum {
+			break
+		}
+
+		entry, err := decodeWalPayload(payload)
+
+		if err != nil {
+			break
+		}
+
+		entries = append(entries, entry)
+
+		offset = end
+	}
+
+	return entries, int64(offset)
+}
+
+func decodeWalPayload(payload []byte) (walEntry, error) {
+
+	var entry walEntry
+
+	if len(payload) < 8+2 {
+		return entry, fmt.Errorf("wal payload too short")
+	}
+
+	pos := 0
+
+	entry.lsn = binary.LittleEndian.Uint64(payload[pos:])
+	pos += 8
+
+	pathLen := int(binary.LittleEndian.Uint16(payload[pos:]))
+	pos += 2
+
+	if pos+pathLen+4+1+4 > len(payload) {
+		return entry, fmt.Errorf("wal payload too short for path")
+	}
+
+	entry.path = string(payload[pos : pos+pathLen])
+	pos += pathLen
+
+	entry.key = int(binary.LittleEndian.Uint32(payload[pos:]))
+	pos += 4
+
+	entry.dataType = payload[pos]
+	pos++
+
+	count := int(binary.LittleEndian.Uint32(payload[pos:]))
+	pos += 4
+
+	entry.records = make([][]byte, 0, count)
+
+	for i := 0; i < count; i++ {
+
+		if pos+4 > len(payload) {
+			return entry, fmt.Errorf("wal payload too short for record length")
+		}
+
+		recLen := int(binary.LittleEndian.Uint32(payload[pos:]))
+		pos += 4
+
+		if pos+recLen > len(payload) {
+			return entry, fmt.Errorf("wal record length %d exceeds payload", recLen)
+		}
+
+		rec := make([]byte, recLen)
+
+		copy(rec, payload[pos:pos+recLen])
+
+		entry.records = append(entry.records, rec)
+
+		pos += recLen
+	}
+
+	return entry, nil
+}
+
+// Checkpoint syncs every mapped data file and block index to disk and then
+// truncates the write-ahead log, since all logged batches are now durable.
+func (bs *StorageEngine) Checkpoint() error {
+
+	if bs.wal == nil {
+
+		return nil
+
+	}
+
+	// wait for in-flight batches to reach their mapped files
+	bs.walLock.Lock()
+
+	defer bs.walLock.Unlock()
+
+	return bs.checkpointLocked()
+}
+
+// checkpointLocked is Checkpoint for callers already holding walLock exclusively
+func (bs *StorageEngine) checkpointLocked() error {
+
+	if bs.wal == nil {
+
+		return nil
+
+	}
+
+	if err := bs.sealOpenBlocks(); err != nil {
+
+		return err
+
+	}
+
+	bs.mmapFilesLock.Lock()
+
+	for path, mmap := range bs.mmapFiles {
+
+		if err := mmap.sync(); err != nil {
+
+			bs.mmapFilesLock.Unlock()
+
+			return fmt.Errorf("failed to sync %s: %v", path, err)
+
+		}
+
+	}
+
+	bs.mmapFilesLock.Unlock()
+
+	bs.indexesLock.Lock()
+
+	for path, index := range bs.indexes {
+
+		if err := index.sync(); err != nil {
+
+			bs.indexesLock.Unlock()
+
+			return fmt.Errorf("failed to sync index %s: %v", path, err)
+
+		}
+
+	}
+
+	bs.indexesLock.Unlock()
+
+	return bs.wal.truncate()
+}
+
+func (bs *StorageEngine) checkpointLoop() {
+
+	defer close(bs.checkpointDone)
+
+	ticker := time.NewTicker(walCheckpointInterval)
+
+	defer ticker.Stop()
+
+	for {
+
+		select {
+
+		case <-bs.stopCheckpoint:
+
+			return
+
+		case <-ticker.C:
+
+		case <-bs.checkpointNow:
+
+		}
+
+		if err := bs.Checkpoint(); err != nil {
+
+			log.Printf("Write-ahead log checkpoint failed: %v", err)
+
+		}
+	}
+}
+
+// replayWal re-applies logged batches that were not checkpointed before the
+// last shutdown and then checkpoints them.
+func (bs *StorageEngine) replayWal(entries []walEntry) error {
+
+	if len(entries) == 0 {
+
+		return nil
+
+	}
+
+	log.Printf("Replaying %d batches from the write-ahead log", len(entries))
+
+	for _, entry := range entries {
+
+		counterPath := entry.path
+
+		if !filepath.IsAbs(counterPath) {
+
+			counterPath = filepath.Join(bs.rootPath, counterPath)
+
+		}
+
+		if err := bs.applyBatch(counterPath, entry.key, entry.dataType, entry.lsn, entry.records, true); err != nil {
+
+			return fmt.Errorf("failed to replay wal entry %d: %v", entry.lsn, err)
+
+		}
+	}
+
+	return bs.Checkpoint()
+}
+
+// relativeToRoot shortens a counter path for the log, keeping it absolute
+// when it lives outside the engine root
+func (bs *StorageEngine) relativeToRoot(path string) string {
+
+	if bs.rootPath == "" {
+
+		return path
+
+	}
+
+	rel, err := filepath.Rel(bs.rootPath, path)
+
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+
+		return path
+
+	}
+
+	return rel
+}
@@ -0,0 +1,211 @@
+package storageEngine
+
+import (
+	"encoding/binary"
+	"math"
+	"os"
+	. "packx/utils"
+	"path/filepath"
+	"testing"
+)
+
+func floatRecord(timestamp uint32, value float64) []byte {
+	buf := make([]byte, 12)
+	binary.LittleEndian.PutUint32(buf[0:4], timestamp)
+	binary.LittleEndian.PutUint64(buf[4:12], math.Float64bits(value))
+	return buf
+}
+
+func countRecords(t *testing.T, engine *StorageEngine, counterPath string, deviceID int) int {
+	t.Helper()
+
+	views, err := engine.GetRangeByPath(deviceID, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+
+	count := 0
+	for _, view := range views {
+		count += int(view.Header.RecordCount)
+	}
+	return count
+}
+
+func TestWalReplayAfterCrash(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+
+	if err := engine.PutBatch(counterPath, 1, TypeFloat, [][]byte{floatRecord(100, 1), floatRecord(101, 2)}); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+
+	// log a batch without applying it, as if the process died right after the wal sync
+	if err := engine.wal.append(&walEntry{
+		path:     engine.relativeToRoot(counterPath),
+		key:      1,
+		dataType: TypeFloat,
+		records:  [][]byte{floatRecord(101, 2), floatRecord(102, 3), floatRecord(103, 4)},
+	}); err != nil {
+		t.Fatalf("wal append: %v", err)
+	}
+
+	reopened, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	// the first batch is not applied again, all of the second is
+	if got := countRecords(t, reopened, counterPath, 1); got != 5 {
+		t.Fatalf("expected 5 records after replay, got %d", got)
+	}
+
+	if size := reopened.wal.getSize(); size != 0 {
+		t.Fatalf("expected wal to be truncated after replay, size %d", size)
+	}
+}
+
+func TestWalReplayAppliesLateAndSameSecondRecords(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+
+	if err := engine.PutBatch(counterPath, 1, TypeFloat, [][]byte{floatRecord(100, 1), floatRecord(200, 2)}); err != nil {
+		t.Fatalf("PutBatch: %v", err)
+	}
+
+	// a record older than the stored ones and a second one in the same
+	// second, logged but not applied before the crash
+	if err := engine.wal.append(&walEntry{
+		path:     engine.relativeToRoot(counterPath),
+		key:      1,
+		dataType: TypeFloat,
+		records:  [][]byte{floatRecord(150, 3), floatRecord(200, 4)},
+	}); err != nil {
+		t.Fatalf("wal append: %v", err)
+	}
+
+	reopened, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+
+	views, err := reopened.GetRangeByPath(1, counterPath, 0, ^uint32(0))
+	if err != nil {
+		t.Fatalf("GetRangeByPath: %v", err)
+	}
+
+	values := make(map[float64]uint32)
+	for _, view := range views {
+		for _, rec := range decodeGorillaBlock(t, view.Data, view.Header.RecordCount) {
+			values[math.Float64frombits(rec.value)] = rec.timestamp
+		}
+	}
+
+	expected := map[float64]uint32{1: 100, 2: 200, 3: 150, 4: 200}
+	if len(values) != len(expected) {
+		t.Fatalf("expected %d records after replay, got %v", len(expected), values)
+	}
+	for value, timestamp := range expected {
+		if got, ok := values[value]; !ok || got != timestamp {
+			t.Fatalf("expected value %v at %d, got %v", value, timestamp, values)
+		}
+	}
+}
+
+func TestWalLSNSurvivesCheckpointAndRestart(t *testing.T) {
+	root := t.TempDir()
+	counterPath := filepath.Join(root, "2025/04/22/counter_2")
+
+	engine, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("openStorageEngine: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := engine.PutBatch(counterPath, 1, TypeFloat, [][]byte{floatRecord(uint32(100+i), 1)}); err != nil {
+			t.Fatalf("PutBatch: %v", err)
+		}
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+
+	// the blocks hold LSN 3, so an entry logged after the restart must come later
+	if reopened.wal.nextLSN != 4 {
+		t.Fatalf("expected next lsn 4 after the restart, got %d", reopened.wal.nextLSN)
+	}
+
+	if err := reopened.wal.append(&walEntry{
+		path:     reopened.relativeToRoot(counterPath),
+		key:      1,
+		dataType: TypeFloat,
+		records:  [][]byte{floatRecord(90, 2)},
+	}); err != nil {
+		t.Fatalf("wal append: %v", err)
+	}
+
+	again, err := openStorageEngine(root)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer again.Close()
+
+	if got := countRecords(t, again, counterPath, 1); got != 4 {
+		t.Fatalf("expected 4 records after replay, got %d", got)
+	}
+}
+
+func TestWalTornRecordIsDiscarded(t *testing.T) {
+	dir := t.TempDir()
+
+	wal, entries, err := openWriteAheadLog(dir)
+	if err != nil {
+		t.Fatalf("openWriteAheadLog: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected empty log, got %d entries", len(entries))
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := wal.append(&walEntry{path: "counter_1", key: 1, dataType: TypeInt, records: [][]byte{floatRecord(uint32(i+1), 0)}}); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+	size := wal.getSize()
+	wal.close()
+
+	// chop the last record in half
+	path := filepath.Join(dir, walFileName)
+	if err := os.Truncate(path, size-10); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	wal, entries, err = openWriteAheadLog(dir)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer wal.close()
+
+	if len(entries) != 1 || entries[0].lsn != 1 {
+		t.Fatalf("expected only the first entry to survive, got %+v", entries)
+	}
+	if wal.nextLSN != 2 {
+		t.Fatalf("expected next lsn 2, got %d", wal.nextLSN)
+	}
+}
@@ -14,22 +14,43 @@ import (
 
 type Config struct {
 	Writers            int    `json:"writers"`
-	Readers           int    `json:"readers"`
-	NumOfPartitions   int    `json:"num_of_partitions"`
-	BlockSize         int    `json:"block_size"`
-	MaxDevices        int    `json:"max_devices"`
-	IntialMmap        int    `json:"initial_mmap"`
-	MaxBlocksPerDevice int   `json:"max_blocks_per_device"`
-	BuffredChanSize   int    `json:"buffred_chan_size"`
-	StoragePath       string `json:"storage_path"`
+	Readers            int    `json:"readers"`
+	NumOfPartitions    int    `json:"num_of_partitions"`
+	BlockSize          int    `json:"block_size"`
+	MaxDevices         int    `json:"max_devices"`
+	IntialMmap         int    `json:"initial_mmap"`
+	MaxBlocksPerDevice int    `json:"max_blocks_per_device"`
+	BuffredChanSize    int    `json:"buffred_chan_size"`
+	StoragePath        string `json:"storage_path"`
+	RetentionDays      int    `json:"retention_days"`    // default for counters without their own, 0 keeps data forever
+	PullAddress        string `json:"pull_address"`      // endpoint the PULL server binds for pushed metrics, DefaultPullAddress when empty
+	QueryAddress       string `json:"query_address"`     // endpoint the query server binds, DefaultQueryAddress when empty
+	HTTPAddress        string `json:"http_address"`      // listen address of the HTTP API, DefaultHTTPAddress when empty
+	GRPCAddress        string `json:"grpc_address"`      // listen address of the gRPC API, DefaultGRPCAddress when empty
+	LiveFeedAddress    string `json:"live_feed_address"` // endpoint the live feed publishes on, DefaultLiveFeedAddress when empty
 }
 
+// Listen addresses used unless configured otherwise
+const (
+	DefaultPullAddress = "tcp://*:5555"
+
+	DefaultQueryAddress = "tcp://*:8008"
+
+	DefaultHTTPAddress = ":8080"
+
+	DefaultGRPCAddress = ":9090"
+
+	DefaultLiveFeedAddress = "tcp://*:5556"
+)
+
 // Counter Config
 
 type CounterConfig struct {
 	Name string `json:"name"`
 
 	Type string `json:"type"`
+
+	RetentionDays int `json:"retention_days,omitempty"` // days of data to keep, overrides the global default
 }
 
 const (
@@ -70,8 +91,7 @@ func LoadConfig() error {
 }
 
 func loadConfig() error {
-	// Get the absolute path to config directory
-	configPath := filepath.Join("/home/maulikpuri/Desktop/v1/config", "config.json")
+	configPath := filepath.Join(ConfigDir(), "config.json")
 
 	data, err := os.ReadFile(configPath)
 	if err != nil {
@@ -86,6 +106,10 @@ func loadConfig() error {
 		return err
 	}
 
+	if err = applyAddressEnv(config); err != nil {
+		return err
+	}
+
 	// Create storage directory if it doesn't exist
 	err = os.MkdirAll(config.StoragePath, 0755)
 	if err != nil {
@@ -97,8 +121,7 @@ func loadConfig() error {
 }
 
 func loadCounterConfig() error {
-	// Get the absolute path to config directory
-	countersPath := filepath.Join("/home/maulikpuri/Desktop/v1/config", "counters.json")
+	countersPath := filepath.Join(ConfigDir(), "counters.json")
 
 	data, err := os.ReadFile(countersPath)
 	if err != nil {
@@ -206,7 +229,100 @@ func GetCounterType(counterID uint16) (byte, error) {
 
 }
 
+// GetCounterRetentionDays returns how many days of a counter's data are kept,
+// 0 meaning forever
+func GetCounterRetentionDays(counterID uint16) int {
+
+	if counter, exists := counters[int(counterID)]; exists && counter.RetentionDays > 0 {
+
+		return counter.RetentionDays
+
+	}
+
+	if config == nil {
+
+		return 0
+
+	}
+
+	return config.RetentionDays
+}
+
 // Add this function to get storage path
 func GetStoragePath() string {
 	return config.StoragePath
 }
+
+// GetPullAddress returns the endpoint the PULL server binds
+func GetPullAddress() string {
+
+	if config == nil || config.PullAddress == "" {
+
+		return DefaultPullAddress
+
+	}
+
+	return config.PullAddress
+}
+
+// GetQueryAddress returns the endpoint the query server binds
+func GetQueryAddress() string {
+
+	if config == nil || config.QueryAddress == "" {
+
+		return DefaultQueryAddress
+
+	}
+
+	return config.QueryAddress
+}
+
+// GetHTTPAddress returns the listen address of the HTTP API
+func GetHTTPAddress() string {
+
+	if config == nil || config.HTTPAddress == "" {
+
+		return DefaultHTTPAddress
+
+	}
+
+	return config.HTTPAddress
+}
+
+// GetGRPCAddress returns the listen address of the gRPC API
+func GetGRPCAddress() string {
+
+	if config == nil || config.GRPCAddress == "" {
+
+		return DefaultGRPCAddress
+
+	}
+
+	return config.GRPCAddress
+}
+
+// GetCounters returns the configured counters by ID
+func GetCounters() map[uint16]CounterConfig {
+
+	result := make(map[uint16]CounterConfig, len(counters))
+
+	for id, counter := range counters {
+
+		result[uint16(id)] = *counter
+
+	}
+
+	return result
+}
+
+// GetLiveFeedAddress returns the endpoint the live feed publishes on
+func GetLiveFeedAddress() string {
+
+	if config == nil || config.LiveFeedAddress == "" {
+
+		return DefaultLiveFeedAddress
+
+	}
+
+	return config.LiveFeedAddress
+}
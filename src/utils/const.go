@@ -8,6 +8,9 @@ const (
 	// BlockHeaderSize is the size of block header
 	BlockHeaderSize = 25
 
+	// IndexEntrySize is the size of each entry in a partition's binary block index
+	IndexEntrySize = 20
+
 	// OffsetTableEntrySize is the size of each offset table entry
 	OffsetTableEntrySize = 16
 
@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultConfigDir holds config.json and counters.json unless SetConfigDir or
+// the EnvConfigDir environment variable name another directory. It is looked
+// for in the working directory, then beside the executable.
+const DefaultConfigDir = "config"
+
+// Environment variables overriding the config directory and the addresses
+// of config.json. Command line flags, applied with OverrideAddresses, take
+// precedence over them.
+const (
+	EnvConfigDir = "REPORTDB_CONFIG_DIR"
+
+	EnvPullAddress = "REPORTDB_PULL_ADDRESS"
+
+	EnvQueryAddress = "REPORTDB_QUERY_ADDRESS"
+
+	EnvHTTPAddress = "REPORTDB_HTTP_ADDRESS"
+
+	EnvGRPCAddress = "REPORTDB_GRPC_ADDRESS"
+
+	EnvLiveFeedAddress = "REPORTDB_LIVE_FEED_ADDRESS"
+)
+
+// zmqTransports are the transports the ZMQ endpoints may use. An inproc
+// endpoint is reachable only from within the server process.
+var zmqTransports = []string{"tcp://", "ipc://", "inproc://"}
+
+var configDir string // set by SetConfigDir
+
+// addressField is an address of Config with its environment variable
+type addressField struct {
+	name string // its key in config.json
+
+	env string
+
+	value *string
+
+	zmq bool // a ZMQ endpoint rather than a host:port address
+}
+
+func addressFields(c *Config) []addressField {
+
+	return []addressField{
+		{name: "pull_address", env: EnvPullAddress, value: &c.PullAddress, zmq: true},
+		{name: "query_address", env: EnvQueryAddress, value: &c.QueryAddress, zmq: true},
+		{name: "http_address", env: EnvHTTPAddress, value: &c.HTTPAddress},
+		{name: "grpc_address", env: EnvGRPCAddress, value: &c.GRPCAddress},
+		{name: "live_feed_address", env: EnvLiveFeedAddress, value: &c.LiveFeedAddress, zmq: true},
+	}
+}
+
+// SetConfigDir sets the directory LoadConfig reads from. It has no effect
+// once the config is loaded.
+func SetConfigDir(dir string) {
+
+	configDir = dir
+}
+
+// ConfigDir returns the directory the config is read from
+func ConfigDir() string {
+
+	if configDir != "" {
+
+		return configDir
+
+	}
+
+	if dir := os.Getenv(EnvConfigDir); dir != "" {
+
+		return dir
+
+	}
+
+	if _, err := os.Stat(DefaultConfigDir); err == nil {
+
+		return DefaultConfigDir
+
+	}
+
+	if executable, err := os.Executable(); err == nil {
+
+		besideExecutable := filepath.Join(filepath.Dir(executable), DefaultConfigDir)
+
+		if _, err := os.Stat(besideExecutable); err == nil {
+
+			return besideExecutable
+
+		}
+	}
+
+	return DefaultConfigDir
+}
+
+// applyAddressEnv overrides the addresses of c with those set in the
+// environment
+func applyAddressEnv(c *Config) error {
+
+	for _, field := range addressFields(c) {
+
+		if value := os.Getenv(field.env); value != "" {
+
+			*field.value = value
+
+		}
+	}
+
+	return checkAddresses(c)
+}
+
+// OverrideAddresses replaces the loaded addresses with the non-empty
+// addresses of overrides, e.g. those given as command line flags. It must be
+// called after LoadConfig and before the servers start.
+func OverrideAddresses(overrides Config) error {
+
+	if config == nil {
+
+		return fmt.Errorf("config not loaded")
+
+	}
+
+	loaded := addressFields(config)
+
+	for i, field := range addressFields(&overrides) {
+
+		if *field.value != "" {
+
+			*loaded[i].value = *field.value
+
+		}
+	}
+
+	return checkAddresses(config)
+}
+
+// checkAddresses makes sure every ZMQ endpoint names a transport and every
+// other address is a host:port
+func checkAddresses(c *Config) error {
+
+	for _, field := range addressFields(c) {
+
+		if *field.value == "" {
+			continue
+		}
+
+		if !field.zmq {
+
+			if _, port, err := net.SplitHostPort(*field.value); err != nil || port == "" {
+
+				return fmt.Errorf("%s %q must be a host:port address, e.g. %q", field.name, *field.value, ":8080")
+
+			}
+
+			continue
+		}
+
+		valid := false
+
+		for _, transport := range zmqTransports {
+
+			if strings.HasPrefix(*field.value, transport) {
+
+				valid = true
+
+			}
+		}
+
+		if !valid {
+
+			return fmt.Errorf("%s %q must start with one of %s", field.name, *field.value, strings.Join(zmqTransports, ", "))
+
+		}
+	}
+
+	return nil
+}
+
+// ConnectEndpoint returns the endpoint to connect to, from the same host, a
+// socket bound to endpoint. A wildcard tcp host becomes localhost; other
+// endpoints are connected to as they are bound.
+func ConnectEndpoint(endpoint string) string {
+
+	for _, wildcard := range []string{"tcp://*:", "tcp://0.0.0.0:"} {
+
+		if strings.HasPrefix(endpoint, wildcard) {
+
+			return "tcp://localhost:" + strings.TrimPrefix(endpoint, wildcard)
+
+		}
+	}
+
+	return endpoint
+}
@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withConfig runs the test against c as the loaded config
+func withConfig(t *testing.T, c *Config) {
+	t.Helper()
+
+	saved := config
+	config = c
+	t.Cleanup(func() { config = saved })
+}
+
+func TestCheckAddresses(t *testing.T) {
+	testCases := []struct {
+		name        string
+		config      Config
+		shouldError bool
+	}{
+		{
+			name:   "Defaults",
+			config: Config{},
+		},
+		{
+			name:   "Wildcard TCP",
+			config: Config{PullAddress: "tcp://*:5555", QueryAddress: "tcp://0.0.0.0:8008"},
+		},
+		{
+			name:   "IPC And Inproc",
+			config: Config{QueryAddress: "ipc:///run/reportdb/query.sock", LiveFeedAddress: "inproc://live"},
+		},
+		{
+			name:   "HTTP And gRPC Host And Port",
+			config: Config{HTTPAddress: ":8080", GRPCAddress: "[::1]:9090"},
+		},
+		{
+			name:        "Host And Port Only",
+			config:      Config{QueryAddress: "localhost:8008"},
+			shouldError: true,
+		},
+		{
+			name:        "Unknown Transport",
+			config:      Config{PullAddress: "udp://*:5555"},
+			shouldError: true,
+		},
+		{
+			name:        "Transport In Capitals",
+			config:      Config{LiveFeedAddress: "TCP://*:5556"},
+			shouldError: true,
+		},
+		{
+			name:        "HTTP Port Only",
+			config:      Config{HTTPAddress: "8080"},
+			shouldError: true,
+		},
+		{
+			name:        "gRPC With Transport",
+			config:      Config{GRPCAddress: "tcp://*:9090"},
+			shouldError: true,
+		},
+		{
+			name:        "gRPC Without Port",
+			config:      Config{GRPCAddress: "localhost:"},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		if err := checkAddresses(&tc.config); (err != nil) != tc.shouldError {
+			t.Fatalf("%s: checkAddresses returned %v", tc.name, err)
+		}
+	}
+}
+
+func TestConnectEndpoint(t *testing.T) {
+	// a wildcard host is connected to on localhost
+	for _, bound := range []string{"tcp://*:8008", "tcp://0.0.0.0:8008"} {
+		if got := ConnectEndpoint(bound); got != "tcp://localhost:8008" {
+			t.Fatalf("ConnectEndpoint(%q) = %q", bound, got)
+		}
+	}
+
+	// anything else as it is bound
+	for _, bound := range []string{"tcp://10.0.0.5:8008", "ipc:///run/reportdb/query.sock", "inproc://query"} {
+		if got := ConnectEndpoint(bound); got != bound {
+			t.Fatalf("ConnectEndpoint(%q) = %q", bound, got)
+		}
+	}
+}
+
+func TestAddressPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	configJSON := `{"storage_path": "` + filepath.Join(dir, "storage") + `", "pull_address": "tcp://*:1", "query_address": "tcp://*:2", "live_feed_address": "tcp://*:3"}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(configJSON), 0644); err != nil {
+		t.Fatalf("writing config.json: %v", err)
+	}
+
+	savedDir := configDir
+	t.Cleanup(func() { configDir = savedDir })
+	withConfig(t, nil)
+
+	// the environment overrides the file
+	t.Setenv(EnvQueryAddress, "ipc:///tmp/query.sock")
+	t.Setenv(EnvLiveFeedAddress, "tcp://*:13")
+
+	SetConfigDir(dir)
+	if err := loadConfig(); err != nil {
+		t.Fatalf("loadConfig: %v", err)
+	}
+
+	// and flags override both
+	if err := OverrideAddresses(Config{LiveFeedAddress: "inproc://live"}); err != nil {
+		t.Fatalf("OverrideAddresses: %v", err)
+	}
+
+	if address := GetPullAddress(); address != "tcp://*:1" {
+		t.Fatalf("expected the pull address of the file, got %q", address)
+	}
+	if address := GetQueryAddress(); address != "ipc:///tmp/query.sock" {
+		t.Fatalf("expected the query address of the environment, got %q", address)
+	}
+	if address := GetLiveFeedAddress(); address != "inproc://live" {
+		t.Fatalf("expected the live feed address of the flag, got %q", address)
+	}
+	if address := GetHTTPAddress(); address != DefaultHTTPAddress {
+		t.Fatalf("expected the default HTTP address, got %q", address)
+	}
+
+	// an invalid override is refused
+	if err := OverrideAddresses(Config{PullAddress: "localhost:5555"}); err == nil {
+		t.Fatalf("expected an override without a transport to be refused")
+	}
+
+	// as is an invalid address from the environment
+	t.Setenv(EnvPullAddress, "localhost:5555")
+	if err := loadConfig(); err == nil {
+		t.Fatalf("expected a pull address without a transport to be refused")
+	}
+}
+
+func TestDefaultConfigDir(t *testing.T) {
+	savedDir := configDir
+	t.Cleanup(func() { configDir = savedDir })
+	SetConfigDir("")
+	t.Setenv(EnvConfigDir, "")
+
+	// relative to the working directory, wherever the server is started
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Getwd: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Chdir: %v", err)
+	}
+	if err := os.Mkdir(DefaultConfigDir, 0755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if got := ConfigDir(); got != DefaultConfigDir || filepath.IsAbs(got) {
+		t.Fatalf("expected %q in the working directory, got %q", DefaultConfigDir, got)
+	}
+
+	// the environment takes precedence over it
+	t.Setenv(EnvConfigDir, "/etc/reportdb")
+	if got := ConfigDir(); got != "/etc/reportdb" {
+		t.Fatalf("expected the directory of the environment, got %q", got)
+	}
+}
+
+func TestOverrideAddressesBeforeLoad(t *testing.T) {
+	withConfig(t, nil)
+
+	if err := OverrideAddresses(Config{QueryAddress: "tcp://*:1"}); err == nil {
+		t.Fatalf("expected an error overriding addresses before the config is loaded")
+	}
+}
@@ -162,7 +162,10 @@ func batchBufferFlushRoutine(batchBuffer *BufferBatch, writersChannel chan<- Wri
 	}
 }
 
-func StartWriteHandler(shutdownWaitGroup *sync.WaitGroup, dataWriteChannel <-chan []models.Metric) error {
+// StartWriteHandler stores the metrics of dataWriteChannel until it is
+// closed. Stored metrics are passed to publish, which may be nil, and must
+// not block the writer.
+func StartWriteHandler(shutdownWaitGroup *sync.WaitGroup, dataWriteChannel <-chan []models.Metric, storageEn *storageEngine.StorageEngine, publish func(metrics []models.Metric)) error {
 
 	defer shutdownWaitGroup.Done()
 
@@ -174,11 +177,9 @@ func StartWriteHandler(shutdownWaitGroup *sync.WaitGroup, dataWriteChannel <-cha
 
 	writersWaitGroup.Add(getBufferSize())
 
-	storageEn, _ := storageEngine.NewStorageEngine()
-
 	for i := 0; i < getBufferSize(); i++ {
 
-		go writer(writersChannel, storageEn, &writersWaitGroup)
+		go writer(writersChannel, storageEn, publish, &writersWaitGroup)
 
 	}
 
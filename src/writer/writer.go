@@ -14,8 +14,9 @@ import (
 	"time"
 )
 
-// writer function to process batched data
-func writer(writersChannel <-chan WriteObjectWiseBatch, storageEn *storageEngine.StorageEngine, writerWaitGroup *sync.WaitGroup) {
+// writer function to process batched data. The metrics of each day of a
+// batch are passed to publish, when set, once the storage engine applied them.
+func writer(writersChannel <-chan WriteObjectWiseBatch, storageEn *storageEngine.StorageEngine, publish func(metrics []models.Metric), writerWaitGroup *sync.WaitGroup) {
 
 	defer writerWaitGroup.Done()
 
@@ -25,6 +26,21 @@ func writer(writersChannel <-chan WriteObjectWiseBatch, storageEn *storageEngine
 
 		log.Printf("Writer received batch for ObjectId: %d, CounterId: %d, Count: %d\n", dataBatch.ObjectId, dataBatch.CounterId, len(dataBatch.Values))
 
+		dataType, err := utils.GetCounterType(dataBatch.CounterId)
+
+		if err != nil {
+
+			log.Printf("Error resolving counter type for ObjectId %d: %v", dataBatch.ObjectId, err)
+
+			continue
+
+		}
+
+		// a batch can straddle midnight, so group records by their day directory
+		dayBatches := make(map[string][][]byte)
+
+		dayMetrics := make(map[string][]models.Metric)
+
 		for _, dp := range dataBatch.Values {
 
 			metric := &models.Metric{
@@ -52,16 +68,8 @@ func writer(writersChannel <-chan WriteObjectWiseBatch, storageEn *storageEngine
 				fmt.Sprintf("counter_%d", metric.CounterId),
 			)
 
-			// Set storage path for this write
-			if err := storageEn.SetStoragePath(counterPath); err != nil {
-
-				log.Printf("Error setting storage path for ObjectId %d: %v", dataBatch.ObjectId, err)
-
-				continue
-			}
-
 			// Serialize the metric data
-			data, err := serializeMetric(*metric)
+			data, err := serializeMetric(metric)
 
 			if err != nil {
 
@@ -71,15 +79,29 @@ func writer(writersChannel <-chan WriteObjectWiseBatch, storageEn *storageEngine
 
 			}
 
-			// Write to storage engine
-			if err := storageEn.Put(int(metric.ObjectID), data); err != nil {
+			dayBatches[counterPath] = append(dayBatches[counterPath], data)
+
+			dayMetrics[counterPath] = append(dayMetrics[counterPath], *metric)
+
+		}
+
+		for counterPath, records := range dayBatches {
+
+			// Write to storage engine, through the write-ahead log
+			if err := storageEn.PutBatch(counterPath, int(dataBatch.ObjectId), dataType, records); err != nil {
 
 				log.Printf("Storage engine error for ObjectId %d: %v", dataBatch.ObjectId, err)
 
 				continue
 			}
 
-			log.Printf("Successfully stored metric for ObjectId: %d, Timestamp: %d, Value: %v\n", dataBatch.ObjectId, dp.Timestamp, dp.Value)
+			log.Printf("Successfully stored %d metrics for ObjectId: %d in %s\n", len(records), dataBatch.ObjectId, counterPath)
+
+			if publish != nil {
+
+				publish(dayMetrics[counterPath])
+
+			}
 
 		}
 
@@ -90,11 +112,11 @@ func writer(writersChannel <-chan WriteObjectWiseBatch, storageEn *storageEngine
 
 }
 
-func serializeMetric(metric models.Metric) ([]byte, error) {
-	// Validate the metric value type
-	log.Println(metric)
+func serializeMetric(metric *models.Metric) ([]byte, error) {
+	// Validate the metric value type, converting it to the counter's type
+	log.Println(*metric)
 
-	if err := ValidateMetricValueType(&metric); err != nil {
+	if err := ValidateMetricValueType(metric); err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,93 @@
+package writer
+
+import (
+	"log"
+	"os"
+	"packx/models"
+	"packx/storageEngine"
+	"packx/utils"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMain loads a config storing under a temporary directory
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "writer-test")
+	if err != nil {
+		log.Fatalf("creating config directory: %v", err)
+	}
+
+	configJSON := `{"storage_path": "` + filepath.Join(dir, "storage") + `"}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(configJSON), 0644); err != nil {
+		log.Fatalf("writing config.json: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "counters.json"), []byte(`{}`), 0644); err != nil {
+		log.Fatalf("writing counters.json: %v", err)
+	}
+
+	utils.SetConfigDir(dir)
+	if err := utils.LoadConfig(); err != nil {
+		log.Fatalf("LoadConfig: %v", err)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestWriterPublishesStoredMetrics(t *testing.T) {
+	storage, err := storageEngine.NewStorageEngine()
+	if err != nil {
+		t.Fatalf("NewStorageEngine: %v", err)
+	}
+	defer storage.Close()
+
+	day := time.Date(2025, 5, 1, 0, 0, 0, 0, time.Local)
+	timestamp := uint32(day.Unix())
+
+	var publishedLock sync.Mutex
+	var published []models.Metric
+	publish := func(metrics []models.Metric) {
+		publishedLock.Lock()
+		defer publishedLock.Unlock()
+
+		// what is published can already be read back
+		views, err := storage.GetRangeByPath(int(metrics[0].ObjectID), filepath.Join(utils.GetStoragePath(), day.Format("2006/01/02"), "counter_1"), timestamp, timestamp+10)
+		if err != nil || len(views) == 0 {
+			t.Errorf("metrics of object %d published before they were stored: %v", metrics[0].ObjectID, err)
+		}
+		storageEngine.ReleaseViews(views)
+
+		published = append(published, metrics...)
+	}
+
+	writersChannel := make(chan WriteObjectWiseBatch, 3)
+	writersChannel <- WriteObjectWiseBatch{ObjectId: 1, CounterId: 1, Values: []models.DataPoint{
+		{Timestamp: timestamp, Value: float64(10)}, // an int counter's value as decoded from JSON
+		{Timestamp: timestamp + 1, Value: "not a number"},
+		{Timestamp: timestamp + 2, Value: int64(12)},
+	}}
+	writersChannel <- WriteObjectWiseBatch{ObjectId: 2, CounterId: 999, Values: []models.DataPoint{{Timestamp: timestamp, Value: 1.5}}}
+	close(writersChannel)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	writer(writersChannel, storage, publish, &wg)
+	wg.Wait()
+
+	// the invalid value and the unknown counter are neither stored nor published
+	want := []models.Metric{
+		{ObjectID: 1, CounterId: 1, Timestamp: timestamp, Value: int64(10)},
+		{ObjectID: 1, CounterId: 1, Timestamp: timestamp + 2, Value: int64(12)},
+	}
+	if len(published) != len(want) {
+		t.Fatalf("expected %d metrics published, got %+v", len(want), published)
+	}
+	for i := range want {
+		if published[i] != want[i] {
+			t.Fatalf("metric %d: published %+v, want %+v", i, published[i], want[i])
+		}
+	}
+}